@@ -0,0 +1,45 @@
+package gorpc
+
+import (
+	"reflect"
+)
+
+// CacheVary lists values of the request which the handler's response depends on besides its parameters, they are
+// parts of the transport cache key. It's returned by V<N>CacheVary() marker method:
+//
+//	func (*Handler) V1CacheVary() gorpc.CacheVary {
+//		return gorpc.CacheVary{Headers: []string{"Accept-Language"}}
+//	}
+type CacheVary struct {
+	// Headers are names of request headers, e.g. "Accept-Language" or "Authorization"
+	Headers []string
+	// ContextKeys are keys of context values, e.g. the user's id set by OnInitCtx callback. Values which aren't
+	// strings or fmt.Stringer are encoded into JSON, the response isn't cached if it fails. Generated clients don't
+	// know them, so their caches vary on headers only.
+	ContextKeys []interface{}
+}
+
+// CacheKeyParams returns parameters of the handler's call which are a part of the transport cache key, fields with
+// `cache:"-"` tag are zero in them. Only fields of the parameters structure itself can be excluded.
+func CacheKeyParams(handler HandlerVersion, params reflect.Value) interface{} {
+	if !handler.Request.hasNoCacheFields {
+		return params.Interface()
+	}
+
+	v := params
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	res := reflect.New(v.Type())
+	res.Elem().Set(v)
+	for i, field := range handler.Request.Fields {
+		if field.NoCache {
+			f := res.Elem().Field(i)
+			f.Set(reflect.Zero(f.Type()))
+		}
+	}
+	if params.Kind() == reflect.Ptr {
+		return res.Interface()
+	}
+	return res.Elem().Interface()
+}
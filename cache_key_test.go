@@ -0,0 +1,51 @@
+package gorpc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cacheKeyTestArgs struct {
+	ID     int                    `key:"id" description:"ID"`
+	Source string                 `key:"source" description:"Tracking source" cache:"-"`
+	Nested *cacheKeyTestNestedArg `key:"nested" description:"Nested"`
+}
+
+type cacheKeyTestNestedArg struct {
+	Source string `key:"source" description:"Nested tracking source" cache:"-"`
+}
+
+func TestCacheKeyParams(t *testing.T) {
+	request, err := processRequestType(reflect.TypeOf(&cacheKeyTestArgs{}))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []bool{false, true, false}, []bool{request.Fields[0].NoCache, request.Fields[1].NoCache, request.Fields[2].NoCache})
+	handler := &handlerVersion{Request: request}
+
+	params := &cacheKeyTestArgs{ID: 1, Source: "email", Nested: &cacheKeyTestNestedArg{Source: "push"}}
+	// tags of nested fields are ignored
+	assert.Equal(t, &cacheKeyTestArgs{ID: 1, Nested: &cacheKeyTestNestedArg{Source: "push"}}, CacheKeyParams(handler, reflect.ValueOf(params)))
+	assert.Equal(t, "email", params.Source, "Parameters must not be modified")
+	assert.Equal(t, cacheKeyTestArgs{ID: 1}, CacheKeyParams(handler, reflect.ValueOf(cacheKeyTestArgs{ID: 1, Source: "email"})))
+
+	request, err = processRequestType(reflect.TypeOf(cacheKeyTestNestedArg{}))
+	assert.NoError(t, err)
+	handler = &handlerVersion{Request: request}
+	assert.Equal(t, cacheKeyTestNestedArg{}, CacheKeyParams(handler, reflect.ValueOf(cacheKeyTestNestedArg{Source: "push"})))
+
+	_, err = processRequestType(reflect.TypeOf(struct {
+		ID int `key:"id" description:"ID" cache:"+"`
+	}{}))
+	assert.EqualError(t, err, `Invalid tag "cache" of field ID, only "-" is allowed`)
+
+	// tags of nested fields are checked too, even though they are ignored
+	_, err = processRequestType(reflect.TypeOf(struct {
+		Items []struct {
+			ID int `key:"id" description:"ID" cache:"x"`
+		} `key:"items" description:"Items"`
+	}{}))
+	assert.EqualError(t, err, `Invalid tag "cache" of field ID, only "-" is allowed`)
+}
@@ -0,0 +1,75 @@
+package gorpc
+
+import (
+	"errors"
+	"io"
+	"reflect"
+)
+
+// File is a file uploaded in multipart form. Handlers declare parameters of types File, *File, []File or []*File,
+// the slices receive all files uploaded with the same key:
+//
+//	type V1Args struct {
+//		Avatar      gorpc.File    `key:"avatar" description:"User's avatar"`
+//		Attachments []*gorpc.File `key:"attachments" description:"Attached documents"`
+//	}
+//
+// The content is read from memory for small files or from a temporary file which is removed after the request.
+// Maximum size of the upload is set by V<N>MaxUploadSize() marker method returning int64.
+type File struct {
+	io.ReadSeeker `json:"-"`
+
+	Name        string `json:"name"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+}
+
+var fileType = reflect.TypeOf(File{})
+
+// isFileType returns true for File, *File and slices of them
+func isFileType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+	return t == fileType
+}
+
+// unmarshalFiles sets the files uploaded with the key of param into v which is File or slice of files
+func (u *parametersUnmarshaler) unmarshalFiles(v reflect.Value, param *HandlerParameter) error {
+	getter, ok := u.handlerParameters.(IHandlerParametersFiles)
+	if !ok {
+		return errors.New("Files can be uploaded in multipart form only")
+	}
+	files, err := getter.GetFiles(param.Path, param.GetKey())
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return errors.New("It must be file")
+	}
+
+	if v.Type() == fileType {
+		if len(files) > 1 {
+			return errors.New("Only one file is expected")
+		}
+		v.Set(reflect.ValueOf(*files[0]))
+		return nil
+	}
+
+	slice := reflect.MakeSlice(v.Type(), 0, len(files))
+	for _, file := range files {
+		fileValue := reflect.ValueOf(file)
+		if v.Type().Elem() == fileType {
+			fileValue = fileValue.Elem()
+		}
+		slice = reflect.Append(slice, fileValue)
+	}
+	v.Set(slice)
+	return nil
+}
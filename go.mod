@@ -1,9 +1,20 @@
 module github.com/sergei-svistunov/gorpc
 
-go 1.15
+go 1.18
 
 require (
+	github.com/fxamacker/cbor/v2 v2.5.0
 	github.com/mailru/easyjson v0.7.6
 	github.com/stretchr/testify v1.7.0
+	github.com/vmihailenco/msgpack/v5 v5.3.5
 	golang.org/x/net v0.0.0-20210119194325-5f4716e94777
 )
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/josharian/intern v1.0.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+)
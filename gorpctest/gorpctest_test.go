@@ -0,0 +1,75 @@
+package gorpctest
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/sergei-svistunov/gorpc"
+	test_handler1 "github.com/sergei-svistunov/gorpc/test/handler1"
+	test_handler_validation "github.com/sergei-svistunov/gorpc/test/handler_validation"
+)
+
+// fakeTB records failures instead of failing the test
+type fakeTB struct {
+	testing.TB
+	errors []string
+}
+
+func (tb *fakeTB) Helper() {}
+
+func (tb *fakeTB) Errorf(format string, args ...interface{}) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func TestHarness_Call(t *testing.T) {
+	h := New(t, test_handler1.NewHandler(), test_handler_validation.NewHandler())
+
+	h.CallQuery("/handler1/v1/", url.Values{"req_int": {"5"}}).
+		AssertOK().
+		AssertResult(&test_handler1.V1Res{String: "Test", Int: 5})
+
+	h.CallJSON("/handler1/v2/", `{"req_int": 1, "error_id": 2}`).AssertErrorCode("ERROR_TYPE2")
+
+	h.CallMap("/handler_validation/v2/", map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"id": 1},
+			map[string]interface{}{"id": 0},
+		},
+	}).AssertParameterError("items[1].id", gorpc.ParameterErrorMin)
+
+	h.CallMap("/handler_validation/v3/", nil).AssertOK()
+
+	calls := h.Recorder().Calls()
+	if len(calls) != 3 {
+		t.Fatalf("Expected 3 recorded calls, got %d", len(calls))
+	}
+	if calls[1].Route != "/handler1/v2/" || calls[1].Err.ErrorCode() != "ERROR_TYPE2" {
+		t.Errorf("Unexpected recorded call %+v", calls[1])
+	}
+	if last := h.Recorder().Last(); last.Params.(*test_handler_validation.V3Args).Limit != 20 {
+		t.Errorf("Default value wasn't passed to the handler: %+v", last.Params)
+	}
+
+	h.Recorder().Reset()
+	if h.Recorder().Last() != nil {
+		t.Error("Recorder wasn't reset")
+	}
+}
+
+func TestResponse_AssertionsFailures(t *testing.T) {
+	tb := &fakeTB{TB: t}
+	h := New(t, test_handler1.NewHandler())
+	h.tb = tb
+
+	h.CallQuery("/handler1/v1/", url.Values{"req_int": {"5"}}).
+		AssertResult(&test_handler1.V1Res{String: "Test", Int: 6}).
+		AssertErrorCode("ERROR_TYPE1")
+	h.CallQuery("/handler1/v1/", url.Values{}).
+		AssertOK().
+		AssertParameterError("req_int", gorpc.ParameterErrorMin)
+
+	if len(tb.errors) != 4 {
+		t.Errorf("Expected 4 failures, got %d: %v", len(tb.errors), tb.errors)
+	}
+}
@@ -0,0 +1,118 @@
+package gorpctest
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sergei-svistunov/gorpc"
+)
+
+// Harness registers handlers in a new HandlersManager and calls them by routes:
+//
+//	h := gorpctest.New(t, users.NewHandler())
+//	h.CallJSON("/users/v1/", `{"id": 1}`).AssertOK().AssertResult(&users.V1Res{ID: 1})
+//
+// Handlers path of the manager is the common parent directory of the handlers' packages, so routes start with
+// the name of the handler's package.
+type Harness struct {
+	tb       testing.TB
+	hm       *gorpc.HandlersManager
+	recorder *Recorder
+}
+
+// New creates the harness, registration errors fail the test
+func New(tb testing.TB, handlers ...gorpc.IHandler) *Harness {
+	tb.Helper()
+
+	hm := gorpc.NewHandlersManager(handlersPath(handlers), gorpc.HandlersManagerCallbacks{})
+	recorder := &Recorder{}
+	hm.Use(recorder.Interceptor())
+	if err := hm.RegisterHandlers(handlers...); err != nil {
+		tb.Fatalf("Can't register handlers: %v", err)
+	}
+
+	return &Harness{
+		tb:       tb,
+		hm:       hm,
+		recorder: recorder,
+	}
+}
+
+// HM returns the handlers manager, e.g. to add interceptors or to enable the strict mode
+func (h *Harness) HM() *gorpc.HandlersManager {
+	return h.hm
+}
+
+// Recorder returns the recorder of all calls made through the harness
+func (h *Harness) Recorder() *Recorder {
+	return h.recorder
+}
+
+// Call unmarshals parameters and calls the handler version by route, e.g. "/users/v1/"
+func (h *Harness) Call(ctx context.Context, route string, params gorpc.IHandlerParameters) *Response {
+	h.tb.Helper()
+
+	resp := &Response{tb: h.tb}
+
+	handler := h.hm.FindHandlerByRoute(route)
+	if handler == nil {
+		h.tb.Fatalf("Handler with route %q is not found", route)
+		return resp
+	}
+
+	args, err := h.hm.UnmarshalParameters(ctx, handler, params)
+	if err != nil {
+		if callErr, ok := err.(*gorpc.CallHandlerError); ok {
+			resp.Err = callErr
+		} else {
+			resp.Err = &gorpc.CallHandlerError{Type: gorpc.ErrorInParameters, Err: err}
+		}
+		return resp
+	}
+
+	resp.Result, resp.Err = h.hm.CallHandler(ctx, handler, args)
+	return resp
+}
+
+// CallJSON calls the handler with JSON body
+func (h *Harness) CallJSON(route string, body string) *Response {
+	h.tb.Helper()
+	return h.Call(context.Background(), route, NewJSONParameters(body))
+}
+
+// CallMap calls the handler with values passed as JSON body
+func (h *Harness) CallMap(route string, values map[string]interface{}) *Response {
+	h.tb.Helper()
+	return h.Call(context.Background(), route, NewMapParameters(values))
+}
+
+// CallQuery calls the handler with query parameters
+func (h *Harness) CallQuery(route string, values url.Values) *Response {
+	h.tb.Helper()
+	return h.Call(context.Background(), route, NewQueryParameters(values))
+}
+
+func handlersPath(handlers []gorpc.IHandler) string {
+	var res []string
+	for _, handler := range handlers {
+		t := reflect.TypeOf(handler)
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		dir := strings.Split(path.Dir(t.PkgPath()), "/")
+		if res == nil {
+			res = dir
+			continue
+		}
+		i := 0
+		for i < len(res) && i < len(dir) && res[i] == dir[i] {
+			i++
+		}
+		res = res[:i]
+	}
+	return strings.Join(res, "/")
+}
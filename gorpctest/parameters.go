@@ -0,0 +1,55 @@
+// Package gorpctest contains helpers for unit testing of gorpc handlers without running a transport.
+package gorpctest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/sergei-svistunov/gorpc/transport/http_json"
+)
+
+// NewJSONParameters returns parameters getter reading the JSON body like http_json.APIHandler does for
+// "application/json" requests
+func NewJSONParameters(body string) gorpc.IHandlerParameters {
+	return &http_json.JsonParametersGetter{
+		Req: ioutil.NopCloser(strings.NewReader(body)),
+	}
+}
+
+// NewMapParameters returns parameters getter for the values which are passed as a JSON body.
+// Nested structures can be written as maps and slices:
+//
+//	gorpctest.NewMapParameters(map[string]interface{}{
+//	    "items": []interface{}{map[string]interface{}{"id": 1}},
+//	})
+func NewMapParameters(values map[string]interface{}) gorpc.IHandlerParameters {
+	body, err := json.Marshal(values)
+	if err != nil {
+		return &invalidParameters{
+			IHandlerParameters: NewJSONParameters("{}"),
+			err:                err,
+		}
+	}
+	return NewJSONParameters(string(body))
+}
+
+// NewQueryParameters returns parameters getter for query string like http_json.APIHandler does for GET requests
+func NewQueryParameters(values url.Values) gorpc.IHandlerParameters {
+	return &http_json.ParametersGetter{
+		Req: httptest.NewRequest("GET", "/?"+values.Encode(), nil),
+	}
+}
+
+// invalidParameters fails on parsing, it's used when the parameters can't be prepared
+type invalidParameters struct {
+	gorpc.IHandlerParameters
+	err error
+}
+
+func (p *invalidParameters) Parse() error {
+	return p.err
+}
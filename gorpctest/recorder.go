@@ -0,0 +1,73 @@
+package gorpctest
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/sergei-svistunov/gorpc"
+)
+
+// RecordedCall describes one handler call
+type RecordedCall struct {
+	Route  string
+	Params interface{}
+	Result interface{}
+	Err    *gorpc.CallHandlerError
+}
+
+// Recorder remembers all handler calls passed through its interceptor:
+//
+//	recorder := &gorpctest.Recorder{}
+//	hm.Use(recorder.Interceptor())
+type Recorder struct {
+	mu    sync.Mutex
+	calls []RecordedCall
+}
+
+// Interceptor returns the interceptor for HandlersManager.Use()
+func (r *Recorder) Interceptor() gorpc.Interceptor {
+	return func(ctx context.Context, handler gorpc.HandlerVersion, params reflect.Value, next gorpc.InterceptorNext) (interface{}, *gorpc.CallHandlerError) {
+		res, err := next(ctx, params)
+
+		r.mu.Lock()
+		r.calls = append(r.calls, RecordedCall{
+			Route:  handler.Route,
+			Params: params.Interface(),
+			Result: res,
+			Err:    err,
+		})
+		r.mu.Unlock()
+
+		return res, err
+	}
+}
+
+// Calls returns all recorded calls in order of their completion
+func (r *Recorder) Calls() []RecordedCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	calls := make([]RecordedCall, len(r.calls))
+	copy(calls, r.calls)
+	return calls
+}
+
+// Last returns the last recorded call or nil
+func (r *Recorder) Last() *RecordedCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.calls) == 0 {
+		return nil
+	}
+	call := r.calls[len(r.calls)-1]
+	return &call
+}
+
+// Reset forgets all recorded calls
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.calls = nil
+	r.mu.Unlock()
+}
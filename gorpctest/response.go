@@ -0,0 +1,69 @@
+package gorpctest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sergei-svistunov/gorpc"
+)
+
+// Response is the result of the handler call. Assertions report problems with testing.TB and return the response,
+// so they can be chained.
+type Response struct {
+	Result interface{}
+	Err    *gorpc.CallHandlerError
+
+	tb testing.TB
+}
+
+// AssertOK checks that the call was successful
+func (r *Response) AssertOK() *Response {
+	r.tb.Helper()
+	if r.Err != nil {
+		r.tb.Errorf("Unexpected error (type %d): %v", r.Err.Type, r.Err)
+	}
+	return r
+}
+
+// AssertResult checks that the call was successful and the result is deeply equal to expected
+func (r *Response) AssertResult(expected interface{}) *Response {
+	r.tb.Helper()
+	if r.Err != nil {
+		r.tb.Errorf("Unexpected error (type %d): %v", r.Err.Type, r.Err)
+		return r
+	}
+	if !reflect.DeepEqual(expected, r.Result) {
+		r.tb.Errorf("Unexpected result.\nExpected: %#v\nActual:   %#v", expected, r.Result)
+	}
+	return r
+}
+
+// AssertErrorCode checks that the handler returned HandlerError with the code (name of the field in V<N>ErrorsVar)
+func (r *Response) AssertErrorCode(code string) *Response {
+	r.tb.Helper()
+	if r.Err == nil {
+		r.tb.Errorf("Expected error %q, got result %#v", code, r.Result)
+		return r
+	}
+	if r.Err.Type != gorpc.ErrorReturnedFromCall || r.Err.ErrorCode() != code {
+		r.tb.Errorf("Expected error %q, got %q (type %d): %v", code, r.Err.ErrorCode(), r.Err.Type, r.Err)
+	}
+	return r
+}
+
+// AssertParameterError checks that the call failed because of the parameter with the path (e.g. "items[1].id")
+// and the reason code (e.g. gorpc.ParameterErrorRequired), empty code matches any reason
+func (r *Response) AssertParameterError(path string, code string) *Response {
+	r.tb.Helper()
+	if r.Err == nil || r.Err.Type != gorpc.ErrorInParameters {
+		r.tb.Errorf("Expected error in parameter %q, got %v", path, r.Err)
+		return r
+	}
+	for _, err := range r.Err.ParameterErrors() {
+		if err.Path == path && (code == "" || err.Code == code) {
+			return r
+		}
+	}
+	r.tb.Errorf("Expected error %q in parameter %q, got: %v", code, path, r.Err)
+	return r
+}
@@ -5,21 +5,38 @@ import (
 )
 
 type handlerVersion struct {
-	Route         string
-	Errors        []HandlerError
-	Request       *handlerRequest
-	Response      reflect.Type
+	Route    string
+	Errors   []HandlerError
+	Request  *handlerRequest
+	Response reflect.Type
+	// StreamItem is the type of items if the handler streams its response, see ReadStream()
+	StreamItem reflect.Type
+	// Raw is true if the handler returns RawResponse, Produces are its content types set by V<N>Produces()
+	// marker method
+	Raw           bool
+	Produces      []string
 	Version       string
 	ExtraData     interface{}
 	handlerStruct IHandler
 	method        reflect.Method
 	path          string
+	// strictParameters is set by V<N>StrictParameters() marker method and overrides HandlersManager setting
+	strictParameters *bool
+	// MaxUploadSize is set by V<N>MaxUploadSize() marker method, it limits the size of the request with uploaded
+	// files. Zero means the transport's default.
+	MaxUploadSize int64
+	// CacheVary is set by V<N>CacheVary() marker method, it's nil if the cached response depends on parameters only
+	CacheVary *CacheVary
 }
 
 type handlerRequest struct {
-	Type   reflect.Type
-	Flat   bool
-	Fields []HandlerParameter
+	Type reflect.Type
+	Flat bool
+	// Multipart is true if the request has parameters of File type, so it can be passed in multipart form only
+	Multipart bool
+	Fields    []HandlerParameter
+	// hasNoCacheFields is true if some fields are excluded from the transport cache key, see CacheKeyParams()
+	hasNoCacheFields bool
 }
 
 type HandlerParameter struct {
@@ -29,9 +46,25 @@ type HandlerParameter struct {
 	Path        []string
 	RawType     reflect.Type
 	IsRequired  bool
-	getMethod   reflect.Method
-	structField reflect.StructField
-	Fields      []HandlerParameter
+	Constraints ParameterConstraints
+	// Default is the raw value of "default" tag
+	Default string
+	// NoCache is set by `cache:"-"` tag, the parameter isn't a part of the transport cache key, e.g. it's used for
+	// tracking only. Tags of nested structures' fields are ignored.
+	NoCache      bool
+	defaultValue reflect.Value
+	valueKind    valueKind
+	getMethod    reflect.Method
+	structField  reflect.StructField
+	Fields       []HandlerParameter
+}
+
+// DefaultValue returns the parsed value of "default" tag
+func (p *HandlerParameter) DefaultValue() (interface{}, bool) {
+	if !p.defaultValue.IsValid() {
+		return nil, false
+	}
+	return p.defaultValue.Interface(), true
 }
 
 func (p *HandlerParameter) GetKey() string {
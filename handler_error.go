@@ -7,12 +7,24 @@ const (
 	ErrorWriteResponse
 	ErrorUnknown
 	ErrorPanic
+	ErrorNotFound
+)
+
+// Codes of errors which aren't returned by handlers, transports send them instead of codes of business errors
+const (
+	ErrorCodeInvalidParameters = "INVALID_PARAMETERS"
+	ErrorCodeNotFound          = "NOT_FOUND"
+	ErrorCodeTimeout           = "TIMEOUT"
+	ErrorCodeInternal          = "INTERNAL_ERROR"
 )
 
 type HandlerError struct {
 	UserMessage string
 	Err         error
 	Code        string
+	// HTTPStatus is set by "status" tag of the field of ErrorsVar, e.g. `text:"User not found" status:"404"`.
+	// Zero means the transport's default.
+	HTTPStatus int
 }
 
 func (e *HandlerError) Error() string {
@@ -44,3 +56,49 @@ func (e *CallHandlerError) ErrorCode() string {
 	}
 	return ""
 }
+
+// HTTPStatus returns the HTTP status of the business error or 0 if it isn't set
+func (e *CallHandlerError) HTTPStatus() int {
+	if userErr, ok := e.Err.(*HandlerError); ok {
+		return userErr.HTTPStatus
+	}
+	return 0
+}
+
+func (e *CallHandlerError) Unwrap() error {
+	return e.Err
+}
+
+// CodeAndData returns the error code and the data of the failed call for the client: the code and the message of
+// the business error, ErrorCodeInvalidParameters with the list of invalid fields or ErrorCodeInternal. The text
+// of internal errors is added to the data only if debug is true.
+func (e *CallHandlerError) CodeAndData(debug bool) (string, interface{}) {
+	switch e.Type {
+	case ErrorInParameters:
+		if paramErrs := e.ParameterErrors(); paramErrs != nil {
+			return ErrorCodeInvalidParameters, paramErrs
+		}
+		return ErrorCodeInvalidParameters, e.UserMessage()
+	case ErrorReturnedFromCall:
+		return e.ErrorCode(), e.UserMessage()
+	case ErrorNotFound:
+		return ErrorCodeNotFound, "Not Found"
+	default:
+		message := "Internal Server Error"
+		if debug {
+			message += ":\n" + e.Error()
+		}
+		return ErrorCodeInternal, message
+	}
+}
+
+// ParameterErrors returns the list of invalid fields if the error is caused by invalid parameters
+func (e *CallHandlerError) ParameterErrors() ParameterErrors {
+	switch err := e.Err.(type) {
+	case ParameterErrors:
+		return err
+	case *CallHandlerError:
+		return err.ParameterErrors()
+	}
+	return nil
+}
@@ -21,6 +21,9 @@ func (hi *handlerInfo) String() string {
 		for _, parameter := range version.Request.Fields {
 			res += fmt.Sprintf("\t\t\t\t%s:\n\t\t\t\t\tType: %s\n\t\t\t\t\tDescription: %s\n\t\t\t\t\tIs required: %t\n",
 				parameter.Name, parameter.RawType.Kind().String(), parameter.Description, parameter.IsRequired)
+			if parameter.Default != "" {
+				res += fmt.Sprintf("\t\t\t\t\tDefault: %s\n", parameter.Default)
+			}
 		}
 	}
 
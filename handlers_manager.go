@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
@@ -44,11 +45,21 @@ type HandlersManagerCallbacks struct {
 	AppendInParams func(ctx context.Context, preparedParams []reflect.Value, extraData interface{}) (context.Context, []reflect.Value, error)
 }
 
+// InterceptorNext continues the interceptors chain. The last element of the chain calls the handler itself.
+type InterceptorNext func(ctx context.Context, params reflect.Value) (interface{}, *CallHandlerError)
+
+// Interceptor wraps every CallHandler() invocation. It can inspect or modify the context and the decoded
+// arguments before calling next, rewrite the result after it or short-circuit the call by not calling next at all.
+type Interceptor func(ctx context.Context, handler HandlerVersion, params reflect.Value, next InterceptorNext) (interface{}, *CallHandlerError)
+
 type HandlersManager struct {
 	handlers        map[string]*handlerEntity
 	handlerVersions map[string]*handlerVersion
 	handlersPath    string
 	callbacks       HandlersManagerCallbacks
+	interceptors    []Interceptor
+	// strictParameters rejects unknown parameters, see SetStrictParameters()
+	strictParameters bool
 }
 
 func NewHandlersManager(handlersPath string, callbacks HandlersManagerCallbacks) *HandlersManager {
@@ -64,6 +75,13 @@ func (hm *HandlersManager) Pkg() string {
 	return hm.handlersPath
 }
 
+// Use appends interceptors to the chain. Interceptors are called in the order they were added,
+// so the first one is the outermost.
+func (hm *HandlersManager) Use(interceptors ...Interceptor) *HandlersManager {
+	hm.interceptors = append(hm.interceptors, interceptors...)
+	return hm
+}
+
 func (hm *HandlersManager) MustRegisterHandlers(handlers ...IHandler) {
 	if err := hm.RegisterHandlers(handlers...); err != nil {
 		panic(err)
@@ -183,10 +201,33 @@ func (hm *HandlersManager) RegisterHandler(h IHandler) error {
 
 		// TODO: check response object for unexported fields here. Move that code out of docs.go
 		version.Response = vMethodType.Type.Out(0)
+		responseType := version.Response
+		if version.Response.Kind() == reflect.Chan || version.Response.Kind() == reflect.Func {
+			version.StreamItem = streamItemType(version.Response)
+			if version.StreamItem == nil {
+				return fmt.Errorf("First output parameter of handler %s version number %d must be a receive channel or an iterator func(yield func(T) bool) error", handlerPath, handlerVersion)
+			}
+			responseType = version.StreamItem
+		}
 
-		err = validateStructure(handlerPtrType.PkgPath(), version.Response, handlerPathWithVersion, "ResponseStructure", typesUsageInHandlers)
-		if err != nil {
-			return fmt.Errorf("Handler '%s' version '%s' return value: %s", handlerPath, vMethodType.Name, err)
+		if version.Response == rawResponseType || version.Response == reflect.PtrTo(rawResponseType) {
+			version.Raw = true
+		} else {
+			err = validateStructure(handlerPtrType.PkgPath(), responseType, handlerPathWithVersion, "ResponseStructure", typesUsageInHandlers)
+			if err != nil {
+				return fmt.Errorf("Handler '%s' version '%s' return value: %s", handlerPath, vMethodType.Name, err)
+			}
+		}
+
+		if producesMethod, found := handlerType.MethodByName(handlerMethodPrefix + "Produces"); found {
+			producesMethodType := producesMethod.Type
+			if producesMethodType.NumIn() != 1 || producesMethodType.NumOut() != 1 || producesMethodType.Out(0) != reflect.TypeOf([]string(nil)) {
+				return fmt.Errorf("V%dProduces() method of handler %s should return []string", handlerVersion, handlerPath)
+			}
+			if !version.Raw {
+				return fmt.Errorf("V%dProduces() method of handler %s is allowed for RawResponse only", handlerVersion, handlerPath)
+			}
+			version.Produces = producesMethod.Func.Call([]reflect.Value{reflect.ValueOf(h)})[0].Interface().([]string)
 		}
 
 		version.Request, err = processRequestType(paramsType)
@@ -194,6 +235,32 @@ func (hm *HandlersManager) RegisterHandler(h IHandler) error {
 			return fmt.Errorf("%s (handler %s, version number %d)", err.Error(), handlerPath, handlerVersion)
 		}
 
+		if strictMethod, found := handlerType.MethodByName(handlerMethodPrefix + "StrictParameters"); found {
+			strictMethodType := strictMethod.Type
+			if strictMethodType.NumIn() != 1 || strictMethodType.NumOut() != 1 || strictMethodType.Out(0).Kind() != reflect.Bool {
+				return fmt.Errorf("V%dStrictParameters() method of handler %s should return bool", handlerVersion, handlerPath)
+			}
+			strict := strictMethod.Func.Call([]reflect.Value{reflect.ValueOf(h)})[0].Bool()
+			version.strictParameters = &strict
+		}
+
+		if sizeMethod, found := handlerType.MethodByName(handlerMethodPrefix + "MaxUploadSize"); found {
+			sizeMethodType := sizeMethod.Type
+			if sizeMethodType.NumIn() != 1 || sizeMethodType.NumOut() != 1 || sizeMethodType.Out(0).Kind() != reflect.Int64 {
+				return fmt.Errorf("V%dMaxUploadSize() method of handler %s should return int64", handlerVersion, handlerPath)
+			}
+			version.MaxUploadSize = sizeMethod.Func.Call([]reflect.Value{reflect.ValueOf(h)})[0].Int()
+		}
+
+		if varyMethod, found := handlerType.MethodByName(handlerMethodPrefix + "CacheVary"); found {
+			varyMethodType := varyMethod.Type
+			if varyMethodType.NumIn() != 1 || varyMethodType.NumOut() != 1 || varyMethodType.Out(0) != reflect.TypeOf(CacheVary{}) {
+				return fmt.Errorf("V%dCacheVary() method of handler %s should return gorpc.CacheVary", handlerVersion, handlerPath)
+			}
+			vary := varyMethod.Func.Call([]reflect.Value{reflect.ValueOf(h)})[0].Interface().(CacheVary)
+			version.CacheVary = &vary
+		}
+
 		// check and prepare errors types for handler
 		errMethod, found := handlerType.MethodByName(handlerMethodPrefix + "ErrorsVar")
 		if found {
@@ -221,6 +288,12 @@ func (hm *HandlersManager) RegisterHandler(h IHandler) error {
 					Err:         errors.New(errText),
 					Code:        fieldStruct.Name,
 				}
+				if status := fieldStruct.Tag.Get("status"); status != "" {
+					handlerError.HTTPStatus, err = strconv.Atoi(status)
+					if err != nil || handlerError.HTTPStatus < 400 || handlerError.HTTPStatus > 599 {
+						return fmt.Errorf("ErrorTypes struct is invalid: field '%s' has invalid status %q, it must be 4xx or 5xx. Handler %s", fieldStruct.Name, status, handlerPath)
+					}
+				}
 				version.Errors = append(version.Errors, handlerError)
 				fieldVal.Set(
 					reflect.ValueOf(&handlerError),
@@ -290,7 +363,7 @@ func validateStructure(packagePath string, basicType reflect.Type, handlerPathWi
 		return nil
 	} else if basicType.Kind() == reflect.Ptr {
 		return validateStructure(packagePath, basicType.Elem(), handlerPathWithVersion, role, typesUsageInHandlers)
-	} else if len(pkgPath) == 0 || isPrimitiveType(basicType) {
+	} else if len(pkgPath) == 0 || isPrimitiveType(basicType) || valueKindOf(basicType) != valueKindPlain {
 		return nil
 	} else if _, exception := isSamePackagePathException[pkgPath]; exception {
 		return nil
@@ -346,6 +419,14 @@ func processRequestType(requestType reflect.Type) (*handlerRequest, error) {
 		return nil, err
 	}
 
+	// only top-level fields can be excluded from the cache key, tags of nested ones are checked by processParamFields
+	for i := range request.Fields {
+		if _, ok := request.Type.Field(i).Tag.Lookup("cache"); ok {
+			request.Fields[i].NoCache = true
+			request.hasNoCacheFields = true
+		}
+	}
+
 	return request, nil
 }
 
@@ -382,6 +463,10 @@ func processParamFields(request *handlerRequest, fieldType, handlerParametersTyp
 			return nil, fmt.Errorf("tag \"key\" must be specified for parameter %q", fieldType.Name)
 		}
 
+		if tag, ok := fieldType.Tag.Lookup("cache"); ok && tag != "-" {
+			return nil, fmt.Errorf("Invalid tag \"cache\" of field %s, only \"-\" is allowed", fieldType.Name)
+		}
+
 		if unicode.IsLower(rune(fieldType.Name[0])) {
 			return nil, fmt.Errorf("Parameters field %s is private", parameter.Name)
 		}
@@ -390,11 +475,36 @@ func processParamFields(request *handlerRequest, fieldType, handlerParametersTyp
 			return nil, fmt.Errorf("Opt %s does not have description", fieldType.Name)
 		}
 
+		var err error
+		parameter.Constraints, err = ParseParameterConstraints(fieldType)
+		if err != nil {
+			return nil, err
+		}
+
+		if defaultValue, ok := fieldType.Tag.Lookup("default"); ok {
+			parameter.Default = defaultValue
+			parameter.defaultValue, err = ParseParameterDefault(fieldType.Type, defaultValue)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid default value of field %s: %v", fieldType.Name, err)
+			}
+			if err := parameter.Constraints.Validate(parameter.defaultValue); err != nil {
+				return nil, fmt.Errorf("Invalid default value of field %s: %v", fieldType.Name, err)
+			}
+			parameter.IsRequired = false
+		}
+
 		t := fieldType.Type
 		if t.Kind() == reflect.Ptr {
 			t = t.Elem()
 		}
-		if t.Kind() != reflect.Struct && t.Kind() != reflect.Map && t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+		if isFileType(t) {
+			parameter.valueKind = valueKindFile
+			request.Multipart = true
+
+		} else if parameter.valueKind = valueKindOf(t); parameter.valueKind != valueKindPlain {
+			// the value is got by valueKind
+
+		} else if t.Kind() != reflect.Struct && t.Kind() != reflect.Map && t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
 			paramGetMethod, exist := findParameterGetMethod(handlerParametersType, fieldType.Type)
 			if !exist {
 				return nil, fmt.Errorf("Type %s does not supported", fieldType.Type.Kind().String())
@@ -408,6 +518,10 @@ func processParamFields(request *handlerRequest, fieldType, handlerParametersTyp
 			}
 			path = append(path, parameter.Key)
 			var err error
+			if t.Kind() == reflect.Map {
+				// maps can't be passed in query parameters
+				request.Flat = false
+			}
 			if t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
 				t = t.Elem()
 				path = nil
@@ -415,7 +529,7 @@ func processParamFields(request *handlerRequest, fieldType, handlerParametersTyp
 			if t.Kind() == reflect.Ptr {
 				t = t.Elem()
 			}
-			if t.Kind() == reflect.Struct {
+			if t.Kind() == reflect.Struct && valueKindOf(t) == valueKindPlain {
 				parameter.Fields, err = processParamFields(request, t, handlerParametersType, path, copyEncounteredMap(encountered))
 				request.Flat = false
 				if err != nil {
@@ -461,25 +575,114 @@ func (hm *HandlersManager) FindHandlerByRoute(route string) HandlerVersion {
 	return hm.handlerVersions[route]
 }
 
-func (*HandlersManager) UnmarshalParameters(ctx context.Context, handler HandlerVersion,
+func (hm *HandlersManager) UnmarshalParameters(ctx context.Context, handler HandlerVersion,
 	handlerParameters IHandlerParameters) (reflect.Value, error) {
-	return unmarshalRequest(handler.Request, handlerParameters)
+	return unmarshalRequest(handler.Request, handlerParameters, hm.IsStrictParameters(handler))
+}
+
+// SetStrictParameters enables or disables the strict mode. In the strict mode keys which are not declared in the
+// arguments structure produce ErrorInParameters. Handler version can override it by V<N>StrictParameters() method.
+func (hm *HandlersManager) SetStrictParameters(strict bool) *HandlersManager {
+	hm.strictParameters = strict
+	return hm
 }
 
-func unmarshalRequest(request *handlerRequest, handlerParameters IHandlerParameters) (reflect.Value, error) {
+// IsStrictParameters returns true if unknown parameters are rejected for the handler version
+func (hm *HandlersManager) IsStrictParameters(handler HandlerVersion) bool {
+	if handler.strictParameters != nil {
+		return *handler.strictParameters
+	}
+	return hm.strictParameters
+}
+
+func unmarshalRequest(request *handlerRequest, handlerParameters IHandlerParameters, strict bool) (reflect.Value, error) {
 	if err := handlerParameters.Parse(); err != nil {
 		return reflect.ValueOf(nil), &CallHandlerError{ErrorInParameters, err}
 	}
 	resPtr := reflect.New(request.Type)
-	res := resPtr.Elem()
-	err := unmarshalParameters(res, handlerParameters, request.Fields, request.Type)
-	if err != nil {
-		return reflect.ValueOf(nil), &CallHandlerError{ErrorInParameters, err}
+	u := &parametersUnmarshaler{
+		handlerParameters:      handlerParameters,
+		handlerParametersValue: reflect.ValueOf(handlerParameters),
+	}
+	if strict {
+		u.keys, _ = handlerParameters.(IHandlerParametersKeys)
+	}
+	u.unmarshalParameters(resPtr.Elem(), request.Fields, "")
+	if len(u.errs) > 0 {
+		return reflect.ValueOf(nil), &CallHandlerError{ErrorInParameters, u.errs}
 	}
 	return resPtr, nil
 }
 
 func (hm *HandlersManager) CallHandler(ctx context.Context, handler HandlerVersion, params reflect.Value) (interface{}, *CallHandlerError) {
+	if len(hm.interceptors) == 0 {
+		return hm.callHandler(ctx, handler, params)
+	}
+
+	return hm.chain(0, handler)(ctx, params)
+}
+
+type callResult struct {
+	res interface{}
+	err *CallHandlerError
+}
+
+// CallHandlerWithContext calls the handler in a separate goroutine and waits for it or for the context's end. The panic
+// of the handler is returned as ErrorPanic, onPanic is called with its stack trace if it isn't nil. Caller must check
+// ctx.Err() after the call, results are empty if the context was done first.
+func (hm *HandlersManager) CallHandlerWithContext(ctx context.Context, handler HandlerVersion, params reflect.Value,
+	onPanic func(r interface{}, trace []byte)) (interface{}, *CallHandlerError) {
+
+	done := make(chan callResult, 1)
+
+	go func() {
+		var res callResult
+		defer func() {
+			if r := recover(); r != nil {
+				res.err = RecoverPanic(r, onPanic)
+			}
+			done <- res
+		}()
+		res.res, res.err = hm.CallHandler(ctx, handler, params)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, nil
+	case res := <-done:
+		return res.res, res.err
+	}
+}
+
+// RecoverPanic converts the value recovered from the handler's panic into ErrorPanic with the stack trace of the current
+// goroutine, so it must be called by the deferred function. onPanic is called with the trace if it isn't nil.
+func RecoverPanic(r interface{}, onPanic func(r interface{}, trace []byte)) *CallHandlerError {
+	trace := make([]byte, 16*1024)
+	n := runtime.Stack(trace, false)
+	trace = trace[:n]
+
+	if onPanic != nil {
+		onPanic(r, trace)
+	}
+	return &CallHandlerError{
+		Type: ErrorPanic,
+		Err:  fmt.Errorf("Panic in handler:\n%#v\n\n%s", r, string(trace)),
+	}
+}
+
+func (hm *HandlersManager) chain(i int, handler HandlerVersion) InterceptorNext {
+	if i == len(hm.interceptors) {
+		return func(ctx context.Context, params reflect.Value) (interface{}, *CallHandlerError) {
+			return hm.callHandler(ctx, handler, params)
+		}
+	}
+
+	return func(ctx context.Context, params reflect.Value) (interface{}, *CallHandlerError) {
+		return hm.interceptors[i](ctx, handler, params, hm.chain(i+1, handler))
+	}
+}
+
+func (hm *HandlersManager) callHandler(ctx context.Context, handler HandlerVersion, params reflect.Value) (interface{}, *CallHandlerError) {
 	in := []reflect.Value{reflect.ValueOf(handler.handlerStruct), reflect.ValueOf(ctx), params}
 
 	if callback := hm.callbacks.AppendInParams; callback != nil {
@@ -552,15 +755,34 @@ func (hm *HandlersManager) getHandlerByPath(path string) *handlerEntity {
 	return hm.handlers[path]
 }
 
-func unmarshalParameters(res reflect.Value, handlerParameters IHandlerParameters, parameters []HandlerParameter,
-	parametersStructType reflect.Type) error {
+// parametersUnmarshaler fills the arguments structure and collects all problems with parameters
+type parametersUnmarshaler struct {
+	handlerParameters IHandlerParameters
+	// methods are looked up by name because getters may have more methods than IHandlerParameters
+	handlerParametersValue reflect.Value
+	// keys is set in the strict mode only
+	keys IHandlerParametersKeys
+	errs ParameterErrors
+}
+
+func (u *parametersUnmarshaler) unmarshalParameters(res reflect.Value, parameters []HandlerParameter, location string) {
+	handlerParameters := u.handlerParameters
+	handlerParametersValue := u.handlerParametersValue
+	errs := &u.errs
 
-	handlerParametersType := reflect.TypeOf(handlerParameters)
+	u.checkUnknownKeys(parameters, location)
 
 	for _, param := range parameters {
+		param := param
+		paramLocation := fieldLocation(location, &param)
+
 		if !handlerParameters.IsExists(param.Path, param.GetKey()) {
+			if param.defaultValue.IsValid() {
+				setDefaultValue(res.FieldByIndex(param.structField.Index), param.defaultValue)
+				continue
+			}
 			if param.IsRequired {
-				return fmt.Errorf("Missed required field '%s'", param.GetKey())
+				errs.add(paramLocation, ParameterErrorRequired, fmt.Sprintf("Missed required field '%s'", paramLocation))
 			}
 			continue
 		}
@@ -570,68 +792,73 @@ func unmarshalParameters(res reflect.Value, handlerParameters IHandlerParameters
 			structField = structField.Elem()
 		}
 
+		// constraints are checked only if the value itself was unmarshaled without errors
+		errsCount := len(*errs)
+
 		t := param.RawType
 		if t.Kind() == reflect.Ptr {
 			t = t.Elem()
 		}
-		if t.Kind() == reflect.Struct {
+		if param.valueKind == valueKindFile {
+			if err := u.unmarshalFiles(structField, &param); err != nil {
+				errs.add(paramLocation, ParameterErrorInvalidValue, fmt.Sprintf("Wrong value of param \"%s\": %v", paramLocation, err))
+				continue
+			}
+
+		} else if param.valueKind != valueKindPlain {
+			raw, err := param.valueKind.getValue(handlerParameters, param.Path, param.GetKey())
+			if err != nil {
+				errs.add(paramLocation, ParameterErrorInvalidValue, err.Error())
+				continue
+			}
+			val, err := param.valueKind.decodeValue(t, raw)
+			if err != nil {
+				errs.add(paramLocation, ParameterErrorInvalidValue, fmt.Sprintf("Wrong value of param \"%s\": %v", paramLocation, err))
+				continue
+			}
+			structField.Set(val)
+
+		} else if t.Kind() == reflect.Struct {
 			fields := make([]HandlerParameter, len(param.Fields))
 			copy(fields, param.Fields)
 			for i := range fields {
-				fields[i].Path = append(param.Path, param.Key)
-			}
-			err := unmarshalParameters(structField, handlerParameters, fields, param.RawType)
-			if err != nil {
-				return err
+				fields[i].Path = childPath(param.Path, param.Key)
 			}
+			u.unmarshalParameters(structField, fields, location)
 
 		} else if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
 			container := structField
 			ok, err := handlerParameters.TraverseSlice(param.Path, param.Key, func(i int, v interface{}) error {
-				fields := param.Fields
-				if t.Elem().Kind() == reflect.Struct {
-					f, _ := processRequestType(t.Elem())
-					fields = f.Fields
+				index := strconv.FormatInt(int64(i), 10)
+				elemLocation := joinLocation(paramLocation, index)
+
+				if t.Elem().Kind() == reflect.Struct && valueKindOf(t.Elem()) == valueKindPlain {
+					// the getter switches its root to the element, so the fields are relative to it
+					if _, isObject := v.(map[string]interface{}); !isObject {
+						errs.add(elemLocation, ParameterErrorInvalidValue, fmt.Sprintf("Wrong value of param \"%s\". It must be object", elemLocation))
+						container = reflect.Append(container, reflect.Zero(t.Elem()))
+						return nil
+					}
+					val := reflect.New(t.Elem()).Elem()
+					u.unmarshalParameters(val, param.Fields, elemLocation)
+					container = reflect.Append(container, val)
+					return nil
 				}
-				val, err := createContainerValue(t.Elem(), v,
+
+				val, _ := u.createContainerValue(t.Elem(), v,
 					HandlerParameter{
-						Path:    append(param.Path, param.Key),
-						Key:     strconv.FormatInt(int64(i), 10),
+						Path:    childPath(param.Path, param.Key),
+						Key:     index,
 						RawType: t.Elem(),
-						Fields:  fields,
+						Fields:  param.Fields,
 					},
-					handlerParameters)
-				if err != nil {
-					return err
-				}
-
-				//ToDo: Fix it to right way, need change major version
-				if container.Type().Elem().Kind() != val.Kind() && val.Kind() == reflect.String {
-					newVal := reflect.New(container.Type().Elem()).Elem()
-					switch container.Type().Elem().Kind() {
-					case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-						if v, err := strconv.ParseInt(val.String(), 10, 64); err == nil {
-							newVal.SetInt(v)
-						} else {
-							return err
-						}
-					case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-						if v, err := strconv.ParseUint(val.String(), 10, 64); err == nil {
-							newVal.SetUint(v)
-						} else {
-							return err
-						}
-					default:
-						return fmt.Errorf("Cannot convert to %s", container.Type().Elem().Kind().String())
-					}
-					val = newVal
-				}
-
+					location)
 				container = reflect.Append(container, val)
 				return nil
 			})
 			if err != nil {
-				return err
+				errs.add(paramLocation, ParameterErrorInvalidValue, err.Error())
+				continue
 			}
 			if ok {
 				structField.Set(container)
@@ -640,80 +867,217 @@ func unmarshalParameters(res reflect.Value, handlerParameters IHandlerParameters
 		} else if t.Kind() == reflect.Map {
 			container := reflect.MakeMap(t)
 			ok, err := handlerParameters.TraverseMap(param.Path, param.Key, func(k string, v interface{}) error {
-				val, err := createContainerValue(t.Elem(), v, param, handlerParameters)
-				if err != nil {
-					return err
+				elemLocation := joinLocation(paramLocation, k)
+
+				if t.Elem().Kind() == reflect.Struct && valueKindOf(t.Elem()) == valueKindPlain {
+					// the getter switches its root to the element, so the fields are relative to it
+					if _, isObject := v.(map[string]interface{}); !isObject {
+						errs.add(elemLocation, ParameterErrorInvalidValue, fmt.Sprintf("Wrong value of param \"%s\". It must be object", elemLocation))
+						return nil
+					}
+					val := reflect.New(t.Elem()).Elem()
+					u.unmarshalParameters(val, param.Fields, elemLocation)
+					container.SetMapIndex(reflect.ValueOf(k), val)
+					return nil
+				}
+
+				val, ok := u.createContainerValue(t.Elem(), v, HandlerParameter{Key: k, RawType: t.Elem(), Fields: param.Fields}, paramLocation)
+				if ok {
+					container.SetMapIndex(reflect.ValueOf(k), val)
 				}
-				container.SetMapIndex(reflect.ValueOf(k), val)
 				return nil
 			})
 			if err != nil {
-				return err
+				errs.add(paramLocation, ParameterErrorInvalidValue, err.Error())
+				continue
 			}
 			if ok {
 				structField.Set(container)
 			}
 
 		} else {
-			method := handlerParametersType.Method(param.getMethod.Index)
-			retValues := method.Func.Call([]reflect.Value{reflect.ValueOf(handlerParameters), reflect.ValueOf(param.Path), reflect.ValueOf(param.GetKey())})
+			method := handlerParametersValue.MethodByName(param.getMethod.Name)
+			retValues := method.Call([]reflect.Value{reflect.ValueOf(param.Path), reflect.ValueOf(param.GetKey())})
 			if len(retValues) > 1 && !retValues[1].IsNil() {
-				return retValues[1].Interface().(error)
+				errs.add(paramLocation, ParameterErrorInvalidValue, retValues[1].Interface().(error).Error())
+				continue
 			}
 			structField.Set(retValues[0])
 		}
+
+		if len(*errs) > errsCount {
+			continue
+		}
+		if err := param.Constraints.Validate(structField); err != nil {
+			code := ParameterErrorInvalidValue
+			if paramErr, ok := err.(*ParameterError); ok {
+				code = paramErr.Code
+			}
+			errs.add(paramLocation, code, fmt.Sprintf("Invalid value of field '%s': %s", paramLocation, err))
+		}
 	}
-	return nil
 }
 
-func createContainerValue(t reflect.Type, v interface{}, param HandlerParameter, handlerParameters IHandlerParameters) (reflect.Value, error) {
-	val := reflect.ValueOf(v)
-	if t.Kind() == reflect.Ptr {
-		t = val.Elem().Type()
+// createContainerValue converts an element of slice or map into the value of type t. The path of param is relative
+// to the current root of handlerParameters which is placed at location. Problems are collected into u.errs and
+// false is returned in this case.
+func (u *parametersUnmarshaler) createContainerValue(t reflect.Type, v interface{}, param HandlerParameter, location string) (reflect.Value, bool) {
+	errs := &u.errs
+	valueLocation := fieldLocation(location, &param)
+
+	if v == nil {
+		return reflect.Zero(t), true
 	}
-	if t.Kind() == reflect.Struct {
-		val = reflect.New(t).Elem()
-		err := unmarshalParameters(val, handlerParameters, param.Fields, t)
+
+	if kind := valueKindOf(t); kind != valueKindPlain {
+		val, err := kind.decodeRawValue(t, v)
 		if err != nil {
-			return reflect.ValueOf(nil), err
+			errs.add(valueLocation, ParameterErrorInvalidValue, fmt.Sprintf("Wrong value of param \"%s\": %v", valueLocation, err))
+			return reflect.Zero(t), false
 		}
+		return val, true
 	}
-	if t.Kind() == reflect.Slice {
-		sliceVal := reflect.New(t).Elem()
-		for i := 0; i < val.Len(); i++ {
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		val, ok := u.createContainerValue(t.Elem(), v, param, location)
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(val)
+		return ptr, ok
+
+	case reflect.Struct:
+		// structures are unmarshaled only from objects and the getter has already switched its root to them
+		if _, isObject := v.(map[string]interface{}); !isObject {
+			errs.add(valueLocation, ParameterErrorInvalidValue, fmt.Sprintf("Wrong value of param \"%s\". It must be object", valueLocation))
+			return reflect.Zero(t), false
+		}
+		errsCount := len(*errs)
+		val := reflect.New(t).Elem()
+		u.unmarshalParameters(val, param.Fields, valueLocation)
+		return val, len(*errs) == errsCount
+
+	case reflect.Slice:
+		items, isSlice := v.([]interface{})
+		if !isSlice {
+			errs.add(valueLocation, ParameterErrorInvalidValue, fmt.Sprintf("Wrong value of param \"%s\". It must be array", valueLocation))
+			return reflect.Zero(t), false
+		}
+		errsCount := len(*errs)
+		sliceVal := reflect.MakeSlice(t, 0, len(items))
+		for i, item := range items {
+			index := strconv.FormatInt(int64(i), 10)
 			elemVal := reflect.New(t.Elem()).Elem()
-			fields := param.Fields
-			if t.Elem().Kind() == reflect.Struct {
-				f, _ := processRequestType(t.Elem())
-				fields = f.Fields
-				for fi, _ := range fields {
-					fields[fi].Path = append(param.Path, param.Key, strconv.FormatInt(int64(i), 10))
+			if structType := t.Elem(); isPlainStruct(structType) {
+				// the root isn't switched to the element here, so the fields are addressed from the current root
+				// fields of structures in nested slices aren't processed on registration
+				fields, err := processParamFields(&handlerRequest{Type: structType}, structType, reflect.TypeOf(new(IHandlerParameters)).Elem(), nil, nil)
+				if err != nil {
+					errs.add(valueLocation, ParameterErrorInvalidValue, fmt.Sprintf("Wrong value of param \"%s\": %v", valueLocation, err))
+					return reflect.Zero(t), false
 				}
-			}
-			err := unmarshalParameters(
-				elemVal,
-				handlerParameters,
-				[]HandlerParameter{
+				for fi := range fields {
+					fields[fi].Path = childPath(param.Path, param.Key, index)
+				}
+				if item == nil {
+					// leave nil pointer or zero structure
+				} else if _, isObject := item.(map[string]interface{}); !isObject {
+					elemLocation := joinLocation(valueLocation, index)
+					errs.add(elemLocation, ParameterErrorInvalidValue, fmt.Sprintf("Wrong value of param \"%s\". It must be object", elemLocation))
+				} else {
+					if elemVal.Kind() == reflect.Ptr {
+						elemVal.Set(reflect.New(elemVal.Type().Elem()))
+						elemVal = elemVal.Elem()
+					}
+					u.unmarshalParameters(elemVal, fields, location)
+					if t.Elem().Kind() == reflect.Ptr {
+						elemVal = elemVal.Addr()
+					}
+				}
+			} else {
+				elemVal, _ = u.createContainerValue(t.Elem(), item,
 					HandlerParameter{
-						Path:    append(param.Path, param.Key),
-						Key:     strconv.FormatInt(int64(i), 10),
+						Path:    childPath(param.Path, param.Key),
+						Key:     index,
 						RawType: t.Elem(),
-						Fields:  fields,
+						Fields:  param.Fields,
 					},
-				},
-				t.Elem(),
-			)
-			if err != nil {
-				return reflect.ValueOf(nil), err
+					location)
 			}
-
 			sliceVal = reflect.Append(sliceVal, elemVal)
+		}
+		return sliceVal, len(*errs) == errsCount
+
+	default:
+		val, err := convertContainerValue(t, v)
+		if err != nil {
+			errs.add(valueLocation, ParameterErrorInvalidValue, fmt.Sprintf("Wrong value of param \"%s\": %v", valueLocation, err))
+			return reflect.Zero(t), false
+		}
+		return val, true
+	}
+}
+
+// convertContainerValue converts a primitive element of slice or map into the value of type t.
+// Numbers may come as strings (query parameters, json.Number).
+func convertContainerValue(t reflect.Type, v interface{}) (reflect.Value, error) {
+	val := reflect.ValueOf(v)
+	switch {
+	case val.Type().AssignableTo(t):
+		return val, nil
+	case val.Kind() == reflect.String && t.Kind() != reflect.String:
+		return parsePrimitiveValue(t, val.String())
+	case val.Kind() == t.Kind() || isNumericKind(val.Kind()) && isNumericKind(t.Kind()):
+		return val.Convert(t), nil
+	default:
+		return reflect.Value{}, fmt.Errorf("%v is not a valid %s", v, t)
+	}
+}
 
-			//pretty.Println(val.Index(i).Interface())
+// checkUnknownKeys reports keys of the object which aren't declared in the structure. All parameters of one
+// structure have the same path, so it's taken from the first one.
+func (u *parametersUnmarshaler) checkUnknownKeys(parameters []HandlerParameter, location string) {
+	if u.keys == nil || len(parameters) == 0 {
+		return
+	}
+
+	path := parameters[0].Path
+	declared := make(map[string]bool, len(parameters))
+	for i := range parameters {
+		declared[parameters[i].GetKey()] = true
+	}
+
+	keys := u.keys.Keys(path)
+	sort.Strings(keys)
+	for _, key := range keys {
+		if declared[key] {
+			continue
 		}
-		val = sliceVal
+		keyLocation := fieldLocation(location, &HandlerParameter{Path: path, Key: key})
+		u.errs.add(keyLocation, ParameterErrorUnknown, fmt.Sprintf("Unknown field '%s'", keyLocation))
 	}
-	return val, nil
+}
+
+// isPlainStruct returns true for structures (or pointers to them) which are unmarshaled field by field
+func isPlainStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && valueKindOf(t) == valueKindPlain
+}
+
+// fieldLocation returns the path of the parameter for error messages, e.g. "f1[0][2].f11.f111"
+func fieldLocation(location string, param *HandlerParameter) string {
+	for _, key := range param.Path {
+		location = joinLocation(location, key)
+	}
+	return joinLocation(location, param.GetKey())
+}
+
+// childPath returns a new path with appended keys, so the parent's path can't be changed by append
+func childPath(path []string, keys ...string) []string {
+	res := make([]string, 0, len(path)+len(keys))
+	res = append(res, path...)
+	return append(res, keys...)
 }
 
 func findParameterGetMethod(handlerParametersType reflect.Type, field reflect.Type) (reflect.Method, bool) {
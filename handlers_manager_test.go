@@ -7,14 +7,18 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
 	test_handler1 "github.com/sergei-svistunov/gorpc/test/handler1"
+	test_handler_behavior "github.com/sergei-svistunov/gorpc/test/handler_behavior"
 	test_handler_common_type_in_different_versions_args "github.com/sergei-svistunov/gorpc/test/handler_common_type_in_different_versions_arguments"
 	test_handler_common_type_in_different_versions "github.com/sergei-svistunov/gorpc/test/handler_common_type_in_different_versions_return_values"
 	test_handler_common_type_in_return_and_arguments "github.com/sergei-svistunov/gorpc/test/handler_common_type_in_return_and_arguments"
+	test_handler_error_status "github.com/sergei-svistunov/gorpc/test/handler_error_status"
 	test_handler_foreign_arguments "github.com/sergei-svistunov/gorpc/test/handler_foreign_arguments"
 	test_handler_foreign_return_values "github.com/sergei-svistunov/gorpc/test/handler_foreign_return_values"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -151,6 +155,74 @@ func (s *HandlersManagerSuite) TestHandlerManager_PrepareParametersWithError() {
 	s.Error(err)
 }
 
+func (s *HandlersManagerSuite) TestHandlerManager_Interceptors() {
+	var calls []string
+	s.hm.Use(
+		func(ctx context.Context, handler HandlerVersion, params reflect.Value, next InterceptorNext) (interface{}, *CallHandlerError) {
+			calls = append(calls, "first:"+handler.Route)
+			res, err := next(ctx, params)
+			if err == nil {
+				res.(*test_handler1.V1Res).String += " rewritten"
+			}
+			return res, err
+		},
+		func(ctx context.Context, handler HandlerVersion, params reflect.Value, next InterceptorNext) (interface{}, *CallHandlerError) {
+			calls = append(calls, "second:"+handler.Version)
+			params.Elem().FieldByName("ReqInt").SetInt(321)
+			return next(ctx, params)
+		},
+	)
+
+	hanlerVersion := s.hm.FindHandler("/test/handler1", 1)
+	params, err := s.hm.UnmarshalParameters(context.TODO(), hanlerVersion, &ParametersGetter{
+		map[string][]string{
+			"req_int": []string{"123"},
+		},
+	})
+	s.NoError(err)
+
+	res, callErr := s.hm.CallHandler(context.TODO(), hanlerVersion, params)
+	s.Nil(callErr)
+	s.Equal(&test_handler1.V1Res{String: "Test rewritten", Int: 321}, res)
+	s.Equal([]string{"first:/test/handler1/v1/", "second:v1"}, calls)
+}
+
+func (s *HandlersManagerSuite) TestHandlerManager_InterceptorShortCircuit() {
+	s.hm.Use(func(ctx context.Context, handler HandlerVersion, params reflect.Value, next InterceptorNext) (interface{}, *CallHandlerError) {
+		return nil, &CallHandlerError{Type: ErrorReturnedFromCall, Err: &HandlerError{UserMessage: "Forbidden", Code: "FORBIDDEN"}}
+	})
+
+	hanlerVersion := s.hm.FindHandler("/test/handler1", 1)
+	params, err := s.hm.UnmarshalParameters(context.TODO(), hanlerVersion, &ParametersGetter{
+		map[string][]string{
+			"req_int": []string{"123"},
+		},
+	})
+	s.NoError(err)
+
+	res, callErr := s.hm.CallHandler(context.TODO(), hanlerVersion, params)
+	s.Nil(res)
+	if s.NotNil(callErr) {
+		s.Equal("FORBIDDEN", callErr.ErrorCode())
+	}
+}
+
+func (s *HandlersManagerSuite) TestHandlerManager_ErrorStatus() {
+	s.Require().NoError(s.hm.RegisterHandler(test_handler_error_status.NewHandler()))
+
+	statuses := map[string]int{}
+	for _, e := range s.hm.FindHandlerByRoute("/test/handler_error_status/v1/").Errors {
+		statuses[e.Code] = e.HTTPStatus
+	}
+	s.Equal(map[string]int{"NOT_FOUND": 404, "FORBIDDEN": 403, "GONE": 404, "CONFLICT": 0}, statuses)
+
+	_, err := s.hm.Invoke(context.TODO(), "/test/handler_error_status/v1/", &test_handler_error_status.V1Args{ErrorID: 2})
+	if s.NotNil(err) {
+		s.Equal("FORBIDDEN", err.ErrorCode())
+		s.Equal(403, err.HTTPStatus())
+	}
+}
+
 func TestUnmarshalJsonParameters(t *testing.T) {
 	type Request struct {
 		IntField          int     `key:"int" json:"int" description:"int field"`
@@ -244,7 +316,7 @@ func unmarshalJsonParameters(t *testing.T, request interface{}) error {
 	}
 	requestValue, err := unmarshalRequest(handlerRequest, &JsonParametersGetter{
 		Req: string(body),
-	})
+	}, false)
 	if err != nil {
 		return err
 	}
@@ -254,3 +326,61 @@ func unmarshalJsonParameters(t *testing.T, request interface{}) error {
 	}
 	return nil
 }
+
+func TestUnmarshalParametersTypes(t *testing.T) {
+	type Request struct {
+		Since   time.Time     `key:"since" description:"time field"`
+		Timeout time.Duration `key:"timeout" description:"duration field"`
+	}
+	handlerRequest, err := processRequestType(reflect.TypeOf(&Request{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `{"since": "2020-01-02T03:04:05Z", "timeout": "1m"}`
+
+	requestValue, err := unmarshalRequest(handlerRequest, &JsonParametersGetter{Req: body}, false)
+	if assert.NoError(t, err) {
+		assert.Equal(t, &Request{Since: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), Timeout: time.Minute}, requestValue.Interface())
+	}
+
+	// the getter which doesn't implement IHandlerParametersTypes can't get the values
+	getter := struct{ IHandlerParameters }{&JsonParametersGetter{Req: body}}
+	_, err = unmarshalRequest(handlerRequest, getter, false)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `Type of param "since" isn't supported by the transport`)
+		assert.Contains(t, err.Error(), `Type of param "timeout" isn't supported by the transport`)
+	}
+}
+
+func TestHandlersManager_CallHandlerWithContext(t *testing.T) {
+	hm := NewHandlersManager("github.com/sergei-svistunov/gorpc", HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(test_handler_behavior.NewHandler())
+	handler := hm.FindHandlerByRoute("/test/handler_behavior/v1/")
+
+	res, err := hm.CallHandlerWithContext(context.Background(), handler, reflect.ValueOf(&test_handler_behavior.V1Args{Value: 1}), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, &test_handler_behavior.V1Res{Value: 1}, res)
+
+	var trace []byte
+	doPanic := true
+	_, err = hm.CallHandlerWithContext(context.Background(), handler, reflect.ValueOf(&test_handler_behavior.V1Args{Panic: &doPanic}),
+		func(r interface{}, t []byte) {
+			trace = t
+		})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, ErrorPanic, err.Type)
+		assert.Contains(t, err.Error(), "panic requested")
+		code, data := err.CodeAndData(false)
+		assert.Equal(t, ErrorCodeInternal, code)
+		assert.Equal(t, "Internal Server Error", data)
+	}
+	assert.Contains(t, string(trace), "handler_behavior")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	sleep := time.Second
+	res, err = hm.CallHandlerWithContext(ctx, handler, reflect.ValueOf(&test_handler_behavior.V1Args{Sleep: &sleep}), nil)
+	assert.Nil(t, res)
+	assert.Nil(t, err)
+	assert.Equal(t, context.DeadlineExceeded, ctx.Err())
+}
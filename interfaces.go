@@ -1,5 +1,7 @@
 package gorpc
 
+import "time"
+
 type IHandler interface {
 	Caption() string
 	Description() string
@@ -34,3 +36,26 @@ type IHandlerParameters interface {
 	TraverseSlice(path []string, name string, h func(i int, v interface{}) error) (bool, error)
 	TraverseMap(path []string, name string, h func(k string, v interface{}) error) (bool, error)
 }
+
+// IHandlerParametersKeys is implemented by parameters getters which can list passed keys, it's required by the strict mode
+type IHandlerParametersKeys interface {
+	// Keys returns keys of the object placed by the path, nil path means the root
+	Keys(path []string) []string
+}
+
+// IHandlerParametersFiles is implemented by parameters getters which can receive uploaded files, see File
+type IHandlerParametersFiles interface {
+	// GetFiles returns the files uploaded with the key, nil if there are none
+	GetFiles(path []string, name string) ([]*File, error)
+}
+
+// IHandlerParametersTypes is implemented by parameters getters which can decode time.Time, time.Duration and types
+// implementing json.Unmarshaler, parameters of these types are invalid for other getters
+type IHandlerParametersTypes interface {
+	// GetTime returns time in RFC3339 format
+	GetTime([]string, string) (time.Time, error)
+	// GetDuration returns duration written as a string like "1h30m" or as a number of nanoseconds
+	GetDuration([]string, string) (time.Duration, error)
+	// GetRawJSON returns JSON representation of the value, it's used for types implementing json.Unmarshaler
+	GetRawJSON([]string, string) ([]byte, error)
+}
@@ -0,0 +1,19 @@
+// Package params contains helpers of decoding parameters shared by gorpc and its transports
+package params
+
+import (
+	"strconv"
+	"time"
+)
+
+// ParseDuration parses durations like "1h30m" as well as integer numbers of nanoseconds
+func ParseDuration(s string) (time.Duration, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+	ns, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ns), nil
+}
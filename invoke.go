@@ -0,0 +1,74 @@
+package gorpc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Invoke calls the handler version by its route (e.g. "/test/handler1/v1/") with already built arguments.
+// args must be the arguments structure of the handler or a pointer to it, nil means zero arguments. The call goes
+// through the interceptors and the callbacks like CallHandler() does, but arguments are passed as is: defaults
+// and constraints from the struct tags are not applied. The structure is copied, so interceptors can't modify args.
+func (hm *HandlersManager) Invoke(ctx context.Context, route string, args interface{}) (interface{}, *CallHandlerError) {
+	handler := hm.FindHandlerByRoute(route)
+	if handler == nil {
+		return nil, &CallHandlerError{
+			Type: ErrorNotFound,
+			Err:  fmt.Errorf("Handler with route %q is not found", route),
+		}
+	}
+
+	params, err := invokeParams(handler, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return hm.CallHandler(ctx, handler, params)
+}
+
+// Invoke is a typed version of HandlersManager.Invoke():
+//
+//	res, err := gorpc.Invoke[*users.V1Res](ctx, hm, "/users/v1/", &users.V1Args{ID: 1})
+func Invoke[Res any, Args any](ctx context.Context, hm *HandlersManager, route string, args *Args) (Res, *CallHandlerError) {
+	var res Res
+
+	val, err := hm.Invoke(ctx, route, args)
+	if err != nil || val == nil {
+		return res, err
+	}
+
+	res, ok := val.(Res)
+	if !ok {
+		return res, &CallHandlerError{
+			Type: ErrorUnknown,
+			Err:  fmt.Errorf("Handler with route %q returns %T instead of %s", route, val, reflect.TypeOf(&res).Elem()),
+		}
+	}
+	return res, nil
+}
+
+func invokeParams(handler HandlerVersion, args interface{}) (reflect.Value, *CallHandlerError) {
+	params := reflect.New(handler.Request.Type)
+
+	v := reflect.ValueOf(args)
+	if v.Kind() == reflect.Ptr && v.Type().Elem() == handler.Request.Type {
+		if v.IsNil() {
+			return params, nil
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case !v.IsValid():
+	case v.Type() == handler.Request.Type:
+		params.Elem().Set(v)
+	default:
+		return reflect.Value{}, &CallHandlerError{
+			Type: ErrorInParameters,
+			Err:  fmt.Errorf("Invalid type of arguments %T for handler with route %q, expected %s", args, handler.Route, handler.Request.Type),
+		}
+	}
+
+	return params, nil
+}
@@ -0,0 +1,74 @@
+package gorpc
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	test_handler_validation "github.com/sergei-svistunov/gorpc/test/handler_validation"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlersManager_Invoke(t *testing.T) {
+	var intercepted []string
+	hm := NewHandlersManager("github.com/sergei-svistunov/gorpc", HandlersManagerCallbacks{})
+	hm.Use(func(ctx context.Context, handler HandlerVersion, params reflect.Value, next InterceptorNext) (interface{}, *CallHandlerError) {
+		intercepted = append(intercepted, handler.Route)
+		if limit := params.Elem().FieldByName("Limit"); limit.IsValid() {
+			limit.SetInt(42)
+		}
+		return next(ctx, params)
+	})
+	if !assert.NoError(t, hm.RegisterHandler(test_handler_validation.NewHandler())) {
+		return
+	}
+
+	args := &test_handler_validation.V1Args{Limit: 10}
+	res, err := hm.Invoke(context.TODO(), "/test/handler_validation/v1", args)
+	assert.Nil(t, err)
+	assert.Equal(t, &test_handler_validation.V1Args{Limit: 42}, res)
+	assert.Equal(t, 10, args.Limit, "arguments must be copied")
+
+	res, err = hm.Invoke(context.TODO(), "/test/handler_validation/v1/", test_handler_validation.V1Args{Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, &test_handler_validation.V1Args{Limit: 42}, res)
+
+	res, err = hm.Invoke(context.TODO(), "/test/handler_validation/v4/", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "", res)
+
+	assert.Equal(t, []string{"/test/handler_validation/v1/", "/test/handler_validation/v1/", "/test/handler_validation/v4/"}, intercepted)
+
+	_, err = hm.Invoke(context.TODO(), "/test/handler_validation/v100/", nil)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, ErrorNotFound, err.Type)
+	}
+
+	_, err = hm.Invoke(context.TODO(), "/test/handler_validation/v1/", &test_handler_validation.V2Request{})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, ErrorInParameters, err.Type)
+	}
+}
+
+func TestInvoke_Typed(t *testing.T) {
+	hm := NewHandlersManager("github.com/sergei-svistunov/gorpc", HandlersManagerCallbacks{})
+	if !assert.NoError(t, hm.RegisterHandler(test_handler_validation.NewHandler())) {
+		return
+	}
+
+	res, err := Invoke[*test_handler_validation.V1Args](context.TODO(), hm, "/test/handler_validation/v1/", &test_handler_validation.V1Args{Limit: 5})
+	assert.Nil(t, err)
+	assert.Equal(t, 5, res.Limit)
+
+	count, err := Invoke[int](context.TODO(), hm, "/test/handler_validation/v2/", &test_handler_validation.V2Request{
+		Items: []test_handler_validation.V2Item{{ID: 1}, {ID: 2}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+
+	_, err = Invoke[string](context.TODO(), hm, "/test/handler_validation/v2/", &test_handler_validation.V2Request{})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, ErrorUnknown, err.Type)
+	}
+}
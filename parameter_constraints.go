@@ -0,0 +1,211 @@
+package gorpc
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParameterConstraints contains declarative validation rules of a handler parameter.
+// They are taken from the struct tags "min", "max", "minLen", "maxLen", "pattern" and "enum":
+//
+//	type Args struct {
+//	    Limit  int    `key:"limit" description:"Limit" min:"1" max:"100"`
+//	    Login  string `key:"login" description:"Login" minLen:"3" maxLen:"32" pattern:"^[a-z0-9_]+$"`
+//	    Status string `key:"status" description:"Status" enum:"active,blocked"`
+//	}
+type ParameterConstraints struct {
+	Min       *float64
+	Max       *float64
+	MinLength *int
+	MaxLength *int
+	Pattern   *regexp.Regexp
+	Enum      []string
+
+	enumNumbers []float64
+}
+
+// ParseParameterConstraints parses validation tags of the field and checks that they can be applied to its type
+func ParseParameterConstraints(field reflect.StructField) (ParameterConstraints, error) {
+	var c ParameterConstraints
+
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	for _, tag := range []string{"min", "max"} {
+		value, ok := field.Tag.Lookup(tag)
+		if !ok {
+			continue
+		}
+		if !isNumericKind(t.Kind()) {
+			return c, fmt.Errorf("tag %q is allowed only for numeric parameters, field %q has type %s", tag, field.Name, t)
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return c, fmt.Errorf("invalid value %q of tag %q for field %q", value, tag, field.Name)
+		}
+		if tag == "min" {
+			c.Min = &f
+		} else {
+			c.Max = &f
+		}
+	}
+
+	for _, tag := range []string{"minLen", "maxLen"} {
+		value, ok := field.Tag.Lookup(tag)
+		if !ok {
+			continue
+		}
+		switch t.Kind() {
+		case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		default:
+			return c, fmt.Errorf("tag %q is allowed only for strings, slices and maps, field %q has type %s", tag, field.Name, t)
+		}
+		l, err := strconv.Atoi(value)
+		if err != nil || l < 0 {
+			return c, fmt.Errorf("invalid value %q of tag %q for field %q", value, tag, field.Name)
+		}
+		if tag == "minLen" {
+			c.MinLength = &l
+		} else {
+			c.MaxLength = &l
+		}
+	}
+
+	if value, ok := field.Tag.Lookup("pattern"); ok {
+		if t.Kind() != reflect.String {
+			return c, fmt.Errorf("tag \"pattern\" is allowed only for string parameters, field %q has type %s", field.Name, t)
+		}
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return c, fmt.Errorf("invalid pattern of field %q: %v", field.Name, err)
+		}
+		c.Pattern = re
+	}
+
+	if value, ok := field.Tag.Lookup("enum"); ok {
+		if t.Kind() != reflect.String && !isNumericKind(t.Kind()) {
+			return c, fmt.Errorf("tag \"enum\" is allowed only for strings and numbers, field %q has type %s", field.Name, t)
+		}
+		for _, v := range strings.Split(value, ",") {
+			v = strings.TrimSpace(v)
+			if isNumericKind(t.Kind()) {
+				f, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					return c, fmt.Errorf("invalid enum value %q for numeric field %q", v, field.Name)
+				}
+				c.enumNumbers = append(c.enumNumbers, f)
+			}
+			c.Enum = append(c.Enum, v)
+		}
+	}
+
+	return c, nil
+}
+
+// IsEmpty returns true if there are no constraints
+func (c *ParameterConstraints) IsEmpty() bool {
+	return c.Min == nil && c.Max == nil && c.MinLength == nil && c.MaxLength == nil && c.Pattern == nil && len(c.Enum) == 0
+}
+
+// Validate checks the value against the constraints. Nil pointers are considered valid.
+// The returned error is *ParameterError without Path.
+func (c *ParameterConstraints) Validate(v reflect.Value) error {
+	if c.IsEmpty() {
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if isNumericKind(v.Kind()) {
+		var f float64
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			f = float64(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			f = float64(v.Uint())
+		default:
+			f = v.Float()
+		}
+		if c.Min != nil && f < *c.Min {
+			return constraintError(ParameterErrorMin, "must be greater than or equal to %s", formatFloat(*c.Min))
+		}
+		if c.Max != nil && f > *c.Max {
+			return constraintError(ParameterErrorMax, "must be less than or equal to %s", formatFloat(*c.Max))
+		}
+		if len(c.enumNumbers) > 0 {
+			for _, e := range c.enumNumbers {
+				if e == f {
+					return nil
+				}
+			}
+			return constraintError(ParameterErrorEnum, "must be one of: %s", strings.Join(c.Enum, ", "))
+		}
+		return nil
+	}
+
+	var length int
+	switch v.Kind() {
+	case reflect.String:
+		length = utf8.RuneCountInString(v.String())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		length = v.Len()
+	default:
+		return nil
+	}
+	if c.MinLength != nil && length < *c.MinLength {
+		return constraintError(ParameterErrorMinLength, "length must be greater than or equal to %d", *c.MinLength)
+	}
+	if c.MaxLength != nil && length > *c.MaxLength {
+		return constraintError(ParameterErrorMaxLength, "length must be less than or equal to %d", *c.MaxLength)
+	}
+
+	if v.Kind() != reflect.String {
+		return nil
+	}
+	if c.Pattern != nil && !c.Pattern.MatchString(v.String()) {
+		return constraintError(ParameterErrorPattern, "must match pattern %q", c.Pattern.String())
+	}
+	if len(c.Enum) > 0 {
+		for _, e := range c.Enum {
+			if e == v.String() {
+				return nil
+			}
+		}
+		return constraintError(ParameterErrorEnum, "must be one of: %s", strings.Join(c.Enum, ", "))
+	}
+
+	return nil
+}
+
+func constraintError(code string, format string, args ...interface{}) error {
+	return &ParameterError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func isNumericKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,130 @@
+package gorpc
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	test_handler_validation "github.com/sergei-svistunov/gorpc/test/handler_validation"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParameterConstraints_Validation(t *testing.T) {
+	hm := NewHandlersManager("github.com/sergei-svistunov/gorpc", HandlersManagerCallbacks{})
+	if !assert.NoError(t, hm.RegisterHandler(test_handler_validation.NewHandler())) {
+		return
+	}
+
+	v1 := hm.FindHandler("/test/handler_validation", 1)
+	v2 := hm.FindHandler("/test/handler_validation", 2)
+
+	for _, testCase := range []struct {
+		handler HandlerVersion
+		body    string
+		err     string
+	}{
+		{v1, `{"limit": 10, "login": "john_1", "status": "active"}`, ""},
+		{v1, `{"limit": 0}`, "Invalid value of field 'limit': must be greater than or equal to 1"},
+		{v1, `{"limit": 101}`, "Invalid value of field 'limit': must be less than or equal to 100"},
+		{v1, `{"limit": 1, "login": "jo"}`, "Invalid value of field 'login': length must be greater than or equal to 3"},
+		{v1, `{"limit": 1, "login": "john_smith"}`, "Invalid value of field 'login': length must be less than or equal to 8"},
+		{v1, `{"limit": 1, "login": "John"}`, "Invalid value of field 'login': must match pattern \"^[a-z0-9_]+$\""},
+		{v1, `{"limit": 1, "status": "deleted"}`, "Invalid value of field 'status': must be one of: active, blocked"},
+		{v2, `{"items": [{"id": 1, "level": 2}]}`, ""},
+		{v2, `{"items": []}`, "Invalid value of field 'items': length must be greater than or equal to 1"},
+		{v2, `{"items": [{"id": 1}, {"id": 0}]}`, "Invalid value of field 'items[1].id': must be greater than or equal to 1"},
+		{v2, `{"items": [{"id": 1, "level": 4}]}`, "Invalid value of field 'items[0].level': must be one of: 1, 2, 3"},
+	} {
+		_, err := hm.UnmarshalParameters(context.TODO(), testCase.handler, &JsonParametersGetter{Req: testCase.body})
+		if testCase.err == "" {
+			assert.NoError(t, err, testCase.body)
+		} else if assert.Error(t, err, testCase.body) {
+			assert.Equal(t, testCase.err, err.Error(), testCase.body)
+		}
+	}
+}
+
+func TestParameterConstraints_InvalidTags(t *testing.T) {
+	for _, request := range []interface{}{
+		&struct {
+			F string `key:"f" description:"f" min:"1"`
+		}{},
+		&struct {
+			F int `key:"f" description:"f" maxLen:"1"`
+		}{},
+		&struct {
+			F int `key:"f" description:"f" pattern:"^a$"`
+		}{},
+		&struct {
+			F string `key:"f" description:"f" pattern:"("`
+		}{},
+		&struct {
+			F int `key:"f" description:"f" enum:"1,a"`
+		}{},
+	} {
+		_, err := processRequestType(reflect.TypeOf(request))
+		assert.Error(t, err, "%T", request)
+	}
+}
+
+func TestParameterDefaults(t *testing.T) {
+	hm := NewHandlersManager("github.com/sergei-svistunov/gorpc", HandlersManagerCallbacks{})
+	if !assert.NoError(t, hm.RegisterHandler(test_handler_validation.NewHandler())) {
+		return
+	}
+
+	v3 := hm.FindHandler("/test/handler_validation", 3)
+	assert.False(t, v3.Request.Fields[0].IsRequired)
+	defaultValue, ok := v3.Request.Fields[0].DefaultValue()
+	assert.True(t, ok)
+	assert.Equal(t, 20, defaultValue)
+
+	params, err := hm.UnmarshalParameters(context.TODO(), v3, &JsonParametersGetter{Req: `{}`})
+	if assert.NoError(t, err) {
+		args := params.Interface().(*test_handler_validation.V3Args)
+		assert.Equal(t, 20, args.Limit)
+		assert.Equal(t, 0, *args.Offset)
+		assert.Equal(t, "asc", *args.Sort)
+		assert.Equal(t, []uint64{1, 2}, args.IDs)
+
+		// handler must not be able to modify the default value
+		args.IDs[0] = 10
+	}
+
+	params, err = hm.UnmarshalParameters(context.TODO(), v3, &JsonParametersGetter{Req: `{"limit": 5, "sort": "desc", "ids": [3]}`})
+	if assert.NoError(t, err) {
+		args := params.Interface().(*test_handler_validation.V3Args)
+		assert.Equal(t, 5, args.Limit)
+		assert.Equal(t, 0, *args.Offset)
+		assert.Equal(t, "desc", *args.Sort)
+		assert.Equal(t, []uint64{3}, args.IDs)
+	}
+
+	params, err = hm.UnmarshalParameters(context.TODO(), v3, &JsonParametersGetter{Req: `{}`})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []uint64{1, 2}, params.Interface().(*test_handler_validation.V3Args).IDs)
+	}
+}
+
+func TestParameterDefaults_Invalid(t *testing.T) {
+	for _, request := range []interface{}{
+		&struct {
+			F int `key:"f" description:"f" default:"abc"`
+		}{},
+		&struct {
+			F *uint8 `key:"f" description:"f" default:"256"`
+		}{},
+		&struct {
+			F int `key:"f" description:"f" default:"0" min:"1"`
+		}{},
+		&struct {
+			F struct {
+				A int `key:"a" description:"a"`
+			} `key:"f" description:"f" default:"1"`
+		}{},
+	} {
+		_, err := processRequestType(reflect.TypeOf(request))
+		assert.Error(t, err, "%T", request)
+	}
+}
@@ -0,0 +1,87 @@
+package gorpc
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ParseParameterDefault converts the value of "default" tag into the value of type t (pointers are dereferenced).
+// Slices of primitive types are written as comma separated lists, e.g. `default:"1,2,3"`.
+func ParseParameterDefault(t reflect.Type, value string) (reflect.Value, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() == reflect.Slice {
+		res := reflect.MakeSlice(t, 0, 0)
+		if value == "" {
+			return res, nil
+		}
+		for _, v := range strings.Split(value, ",") {
+			elem, err := parsePrimitiveValue(t.Elem(), strings.TrimSpace(v))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			res = reflect.Append(res, elem)
+		}
+		return res, nil
+	}
+
+	return parsePrimitiveValue(t, value)
+}
+
+func parsePrimitiveValue(t reflect.Type, value string) (reflect.Value, error) {
+	res := reflect.New(t).Elem()
+
+	switch t.Kind() {
+	case reflect.String:
+		res.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("%q is not a valid %s", value, t)
+		}
+		res.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 0, t.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("%q is not a valid %s", value, t)
+		}
+		res.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 0, t.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("%q is not a valid %s", value, t)
+		}
+		res.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, t.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("%q is not a valid %s", value, t)
+		}
+		res.SetFloat(f)
+	default:
+		return reflect.Value{}, fmt.Errorf("default values are not supported for type %s", t)
+	}
+
+	return res, nil
+}
+
+// setDefaultValue assigns a copy of the default value to the field, so handlers can't modify the default itself
+func setDefaultValue(field reflect.Value, defaultValue reflect.Value) {
+	value := defaultValue
+	if defaultValue.Kind() == reflect.Slice {
+		value = reflect.MakeSlice(defaultValue.Type(), defaultValue.Len(), defaultValue.Len())
+		reflect.Copy(value, defaultValue)
+	}
+
+	if field.Kind() == reflect.Ptr {
+		ptr := reflect.New(field.Type().Elem())
+		ptr.Elem().Set(value)
+		field.Set(ptr)
+		return
+	}
+	field.Set(value)
+}
@@ -0,0 +1,61 @@
+package gorpc
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Reason codes of ParameterError
+const (
+	ParameterErrorRequired     = "REQUIRED"
+	ParameterErrorInvalidValue = "INVALID_VALUE"
+	ParameterErrorMin          = "MIN"
+	ParameterErrorMax          = "MAX"
+	ParameterErrorMinLength    = "MIN_LENGTH"
+	ParameterErrorMaxLength    = "MAX_LENGTH"
+	ParameterErrorPattern      = "PATTERN"
+	ParameterErrorEnum         = "ENUM"
+	ParameterErrorUnknown      = "UNKNOWN"
+)
+
+// ParameterError describes a problem with one field of the request. Path is written in JSON path like style,
+// e.g. "f1[0][2].f11.f111".
+type ParameterError struct {
+	Path    string `json:"path"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ParameterError) Error() string {
+	return e.Message
+}
+
+// ParameterErrors contains all problems found while unmarshaling of the request parameters
+type ParameterErrors []*ParameterError
+
+func (e ParameterErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *ParameterErrors) add(path, code, message string) {
+	*e = append(*e, &ParameterError{
+		Path:    path,
+		Code:    code,
+		Message: message,
+	})
+}
+
+// joinLocation appends the key of the field to the location of its parent, indexes are written in brackets
+func joinLocation(location, key string) string {
+	if _, err := strconv.Atoi(key); err == nil {
+		return location + "[" + key + "]"
+	}
+	if location == "" {
+		return key
+	}
+	return location + "." + key
+}
@@ -0,0 +1,80 @@
+package gorpc
+
+import (
+	"context"
+	"testing"
+
+	test_handler_validation "github.com/sergei-svistunov/gorpc/test/handler_validation"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParameterErrors_Aggregation(t *testing.T) {
+	hm := NewHandlersManager("github.com/sergei-svistunov/gorpc", HandlersManagerCallbacks{})
+	if !assert.NoError(t, hm.RegisterHandler(test_handler_validation.NewHandler())) {
+		return
+	}
+
+	v2 := hm.FindHandler("/test/handler_validation", 2)
+	_, err := hm.UnmarshalParameters(context.TODO(), v2, &JsonParametersGetter{
+		Req: `{"items": [{"id": 0}, {"level": 4}, {"id": "abc"}, 5]}`,
+	})
+	if !assert.Error(t, err) {
+		return
+	}
+
+	callErr, ok := err.(*CallHandlerError)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, ErrorInParameters, callErr.Type)
+	assert.Equal(t, ParameterErrors{
+		{Path: "items[0].id", Code: ParameterErrorMin, Message: "Invalid value of field 'items[0].id': must be greater than or equal to 1"},
+		{Path: "items[1].id", Code: ParameterErrorRequired, Message: "Missed required field 'items[1].id'"},
+		{Path: "items[1].level", Code: ParameterErrorEnum, Message: "Invalid value of field 'items[1].level': must be one of: 1, 2, 3"},
+		{Path: "items[2].id", Code: ParameterErrorInvalidValue, Message: "Wrong value of param \"id\". It must be number"},
+		{Path: "items[3]", Code: ParameterErrorInvalidValue, Message: "Wrong value of param \"items[3]\". It must be object"},
+	}, callErr.ParameterErrors())
+}
+
+func TestParameterErrors_JoinLocation(t *testing.T) {
+	assert.Equal(t, "f1", joinLocation("", "f1"))
+	assert.Equal(t, "f1[0][2]", joinLocation(joinLocation("f1", "0"), "2"))
+	assert.Equal(t, "f1[0].f11.f111", joinLocation(joinLocation("f1[0]", "f11"), "f111"))
+}
+
+func TestParameterErrors_StrictParameters(t *testing.T) {
+	hm := NewHandlersManager("github.com/sergei-svistunov/gorpc", HandlersManagerCallbacks{})
+	if !assert.NoError(t, hm.RegisterHandler(test_handler_validation.NewHandler())) {
+		return
+	}
+
+	v2 := hm.FindHandler("/test/handler_validation", 2)
+	v4 := hm.FindHandler("/test/handler_validation", 4)
+	body := `{"items": [{"id": 1, "levl": 2}], "item": []}`
+
+	_, err := hm.UnmarshalParameters(context.TODO(), v2, &JsonParametersGetter{Req: body})
+	assert.NoError(t, err)
+
+	hm.SetStrictParameters(true)
+	assert.True(t, hm.IsStrictParameters(v2))
+	assert.False(t, hm.IsStrictParameters(v4))
+
+	_, err = hm.UnmarshalParameters(context.TODO(), v2, &JsonParametersGetter{Req: body})
+	if assert.Error(t, err) {
+		assert.Equal(t, ParameterErrors{
+			{Path: "item", Code: ParameterErrorUnknown, Message: "Unknown field 'item'"},
+			{Path: "items[0].levl", Code: ParameterErrorUnknown, Message: "Unknown field 'items[0].levl'"},
+		}, err.(*CallHandlerError).ParameterErrors())
+	}
+
+	_, err = hm.UnmarshalParameters(context.TODO(), hm.FindHandler("/test/handler_validation", 1), &ParametersGetter{
+		Values: map[string][]string{"limit": {"1"}, "offset": {"2"}},
+	})
+	if assert.Error(t, err) {
+		assert.Equal(t, "Unknown field 'offset'", err.Error())
+	}
+
+	_, err = hm.UnmarshalParameters(context.TODO(), v4, &JsonParametersGetter{Req: `{"name": "a", "nmae": "b"}`})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,159 @@
+package gorpc
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/sergei-svistunov/gorpc/internal/params"
+)
+
+// valueKind describes how a parameter value that isn't a plain Go kind has to be decoded
+type valueKind int
+
+const (
+	valueKindPlain valueKind = iota
+	valueKindTime
+	valueKindDuration
+	valueKindText
+	valueKindJSON
+	// valueKindFile is set for File and slices of files, they are received by IHandlerParametersFiles
+	valueKindFile
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf(new(encoding.TextUnmarshaler)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf(new(json.Unmarshaler)).Elem()
+)
+
+func valueKindOf(t reflect.Type) valueKind {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == fileType:
+		return valueKindFile
+	case t == timeType:
+		return valueKindTime
+	case t == durationType:
+		return valueKindDuration
+	case reflect.PtrTo(t).Implements(textUnmarshalerType):
+		return valueKindText
+	case reflect.PtrTo(t).Implements(jsonUnmarshalerType):
+		return valueKindJSON
+	default:
+		return valueKindPlain
+	}
+}
+
+// ParameterFormat describes types of parameters which aren't decoded by their kind, e.g. time.Time. It returns
+// the type of the value in JSON, it's empty if the value can be anything, and the format of strings. ok is false
+// for other types.
+func ParameterFormat(t reflect.Type) (typ, format string, ok bool) {
+	switch valueKindOf(t) {
+	case valueKindTime:
+		return "string", "date-time", true
+	case valueKindDuration:
+		return "string", "duration", true
+	case valueKindText:
+		return "string", "", true
+	case valueKindJSON:
+		return "", "", true
+	default:
+		return "", "", false
+	}
+}
+
+// getValue returns the value of the parameter to be decoded by decodeValue, values other than text are got by
+// IHandlerParametersTypes
+func (k valueKind) getValue(handlerParameters IHandlerParameters, path []string, name string) (reflect.Value, error) {
+	if k == valueKindText {
+		s, err := handlerParameters.GetString(path, name)
+		return reflect.ValueOf(s), err
+	}
+
+	getter, ok := handlerParameters.(IHandlerParametersTypes)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("Type of param \"%s\" isn't supported by the transport", name)
+	}
+	switch k {
+	case valueKindTime:
+		t, err := getter.GetTime(path, name)
+		return reflect.ValueOf(t), err
+	case valueKindDuration:
+		d, err := getter.GetDuration(path, name)
+		return reflect.ValueOf(d), err
+	case valueKindJSON:
+		b, err := getter.GetRawJSON(path, name)
+		return reflect.ValueOf(b), err
+	default:
+		return reflect.Value{}, fmt.Errorf("Type of param \"%s\" isn't supported", name)
+	}
+}
+
+// decodeValue converts the value returned by the get method into the value of type t
+func (k valueKind) decodeValue(t reflect.Type, v reflect.Value) (reflect.Value, error) {
+	switch k {
+	case valueKindText:
+		res := reflect.New(t)
+		if err := res.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(v.String())); err != nil {
+			return reflect.Value{}, err
+		}
+		return res.Elem(), nil
+	case valueKindJSON:
+		res := reflect.New(t)
+		if err := res.Interface().(json.Unmarshaler).UnmarshalJSON(v.Bytes()); err != nil {
+			return reflect.Value{}, err
+		}
+		return res.Elem(), nil
+	default:
+		return v, nil
+	}
+}
+
+// decodeRawValue converts an element of slice or map returned by TraverseSlice or TraverseMap into the value of type t
+func (k valueKind) decodeRawValue(t reflect.Type, v interface{}) (reflect.Value, error) {
+	if t.Kind() == reflect.Ptr {
+		val, err := k.decodeRawValue(t.Elem(), v)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(val)
+		return ptr, nil
+	}
+
+	switch k {
+	case valueKindTime:
+		s, ok := v.(string)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("Wrong value %v. It should be Time in RFC3339 format", v)
+		}
+		tm, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("Wrong value %q. It should be Time in RFC3339 format", s)
+		}
+		return reflect.ValueOf(tm), nil
+	case valueKindDuration:
+		d, err := params.ParseDuration(fmt.Sprint(v))
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("Wrong value %v. It should be Duration", v)
+		}
+		return reflect.ValueOf(d), nil
+	case valueKindText:
+		return k.decodeValue(t, reflect.ValueOf(fmt.Sprint(v)))
+	case valueKindJSON:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return k.decodeValue(t, reflect.ValueOf(b))
+	default:
+		return reflect.ValueOf(v), nil
+	}
+}
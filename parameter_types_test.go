@@ -0,0 +1,30 @@
+package gorpc
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParameterFormat(t *testing.T) {
+	for _, test := range []struct {
+		value  interface{}
+		typ    string
+		format string
+		ok     bool
+	}{
+		{time.Time{}, "string", "date-time", true},
+		{new(time.Duration), "string", "duration", true},
+		{net.IP{}, "string", "", true},
+		{json.RawMessage{}, "", "", true},
+		{0, "", "", false},
+		{File{}, "", "", false},
+	} {
+		typ, format, ok := ParameterFormat(reflect.TypeOf(test.value))
+		assert.Equal(t, []interface{}{test.typ, test.format, test.ok}, []interface{}{typ, format, ok}, "%T", test.value)
+	}
+}
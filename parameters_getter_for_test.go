@@ -4,8 +4,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/sergei-svistunov/gorpc/internal/params"
 )
 
 type ParametersGetter struct {
@@ -173,6 +177,29 @@ func (pg *ParametersGetter) GetFloat64(path []string, name string) (float64, err
 	return v, err
 }
 
+func (pg *ParametersGetter) GetTime(path []string, name string) (time.Time, error) {
+	return time.Parse(time.RFC3339, pg.get(name))
+}
+
+func (pg *ParametersGetter) GetDuration(path []string, name string) (time.Duration, error) {
+	return params.ParseDuration(pg.get(name))
+}
+
+func (pg *ParametersGetter) GetRawJSON(path []string, name string) ([]byte, error) {
+	return json.Marshal(pg.get(name))
+}
+
+func (pg *ParametersGetter) Keys(path []string) []string {
+	if len(path) > 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(pg.Values))
+	for k := range pg.Values {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
 func (pg *ParametersGetter) get(name string) string {
 	slice := pg.Values[name]
 
@@ -287,6 +314,24 @@ func (p *JsonParametersGetter) GetFloat64(path []string, name string) (float64,
 	return n.Float64()
 }
 
+func (p *JsonParametersGetter) GetTime(path []string, name string) (time.Time, error) {
+	s, err := p.GetString(path, name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func (p *JsonParametersGetter) GetDuration(path []string, name string) (time.Duration, error) {
+	v, _ := p.get(path, name)
+	return params.ParseDuration(fmt.Sprint(v))
+}
+
+func (p *JsonParametersGetter) GetRawJSON(path []string, name string) ([]byte, error) {
+	v, _ := p.get(path, name)
+	return json.Marshal(v)
+}
+
 func (p *JsonParametersGetter) getNumber(path []string, name string) (json.Number, error) {
 	v, _ := p.get(path, name)
 	if n, ok := v.(json.Number); ok {
@@ -335,6 +380,19 @@ func (p *JsonParametersGetter) TraverseMap(path []string, name string, h func(k
 	return false, nil
 }
 
+func (p *JsonParametersGetter) Keys(path []string) []string {
+	var v interface{} = p.values
+	if len(path) > 0 {
+		v, _ = p.get(path[:len(path)-1], path[len(path)-1])
+	}
+	m, _ := v.(map[string]interface{})
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
 func (p *JsonParametersGetter) get(path []string, name string) (interface{}, bool) {
 	m := p.values
 	for _, key := range path {
@@ -0,0 +1,62 @@
+package gorpc
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+)
+
+// RawResponse is written by HTTP transport as is instead of the JSON envelope, so handlers can return files,
+// images or CSV:
+//
+//	func (*Handler) V1(ctx context.Context, opts *V1Args) (*gorpc.RawResponse, error) {
+//		return &gorpc.RawResponse{ContentType: "text/csv", Body: data}, nil
+//	}
+//
+//	// V1Produces declares content types of the response for documentation
+//	func (*Handler) V1Produces() []string {
+//		return []string{"text/csv"}
+//	}
+//
+// The content is Body or it's read from Reader which is closed after the write if it's io.Closer. Reader is read into
+// memory only if the response is cached or has ETag. Errors of the handler are returned in the JSON envelope.
+//
+// Transports which can't write raw responses send it as JSON object with base64 encoded body.
+type RawResponse struct {
+	ContentType string
+	Header      http.Header
+	Body        []byte
+	Reader      io.Reader
+}
+
+var rawResponseType = reflect.TypeOf(RawResponse{})
+
+// ReadBody returns Body or reads the whole Reader and closes it
+func (r *RawResponse) ReadBody() ([]byte, error) {
+	if r.Reader == nil {
+		return r.Body, nil
+	}
+	if closer, ok := r.Reader.(io.Closer); ok {
+		defer closer.Close()
+	}
+	body, err := ioutil.ReadAll(r.Reader)
+	if err != nil {
+		return nil, err
+	}
+	r.Body, r.Reader = body, nil
+	return body, nil
+}
+
+func (r RawResponse) MarshalJSON() ([]byte, error) {
+	body, err := r.ReadBody()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		ContentType string      `json:"content_type"`
+		Header      http.Header `json:"header,omitempty"`
+		Body        []byte      `json:"body"`
+	}{r.ContentType, r.Header, body})
+}
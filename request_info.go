@@ -0,0 +1,52 @@
+package gorpc
+
+import (
+	"context"
+	"net/http"
+)
+
+// IncomingRequest describes the request of the handler's call, transports put it into the context before
+// the unmarshaling of parameters, so handlers and interceptors don't depend on the transport:
+//
+//	if info := gorpc.RequestInfo(ctx); info != nil {
+//		log.Printf("%s called by %s via %s", info.Route, info.RemoteAddr, info.Transport)
+//	}
+type IncomingRequest struct {
+	// Transport is the name of the transport's package, e.g. "http_json" or "frame_json"
+	Transport string
+	// Route and Version are the called handler's route (e.g. "/test/handler1/v1/") and version (e.g. "v1")
+	Route   string
+	Version string
+	// RemoteAddr is the network address of the client, it isn't the address from X-Forwarded-For
+	RemoteAddr string
+	// Header contains headers of HTTP request or of WebSocket handshake, it's nil if the transport doesn't have
+	// headers. It must not be modified.
+	Header http.Header
+	// HTTPRequest is the HTTP request of the call or of WebSocket handshake, it's nil for non-HTTP transports.
+	// Its body is read already.
+	HTTPRequest *http.Request
+}
+
+type requestInfoContextKey struct{}
+
+// NewRequestInfoContext returns the context with the information about the request, it's called by transports
+func NewRequestInfoContext(ctx context.Context, info *IncomingRequest) context.Context {
+	return context.WithValue(ctx, requestInfoContextKey{}, info)
+}
+
+// RequestInfo returns the information about the request of the handler's call or nil if the handler is called
+// without a transport, e.g. by HandlersManager.Invoke()
+func RequestInfo(ctx context.Context) *IncomingRequest {
+	info, _ := ctx.Value(requestInfoContextKey{}).(*IncomingRequest)
+	return info
+}
+
+// NewIncomingRequest returns the request of the handler's call through the transport, the transport fills the rest
+// of fields
+func NewIncomingRequest(transport string, handler HandlerVersion) *IncomingRequest {
+	return &IncomingRequest{
+		Transport: transport,
+		Route:     handler.Route,
+		Version:   handler.Version,
+	}
+}
@@ -0,0 +1,21 @@
+package gorpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	test_handler_validation "github.com/sergei-svistunov/gorpc/test/handler_validation"
+)
+
+func TestRequestInfo(t *testing.T) {
+	hm := NewHandlersManager("github.com/sergei-svistunov/gorpc", HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(test_handler_validation.NewHandler())
+
+	assert.Nil(t, RequestInfo(context.TODO()))
+
+	info := NewIncomingRequest("test", hm.FindHandlerByRoute("/test/handler_validation/v1/"))
+	assert.Equal(t, &IncomingRequest{Transport: "test", Route: "/test/handler_validation/v1/", Version: "v1"}, info)
+	assert.Same(t, info, RequestInfo(NewRequestInfoContext(context.TODO(), info)))
+}
@@ -0,0 +1,95 @@
+package gorpc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// streamItemType returns the type of items if the handler's result is a stream or nil otherwise. A stream is
+// a channel which the handler closes after the last item:
+//
+//	func (*Handler) V1(ctx context.Context, opts *V1Args) (<-chan *V1Item, error)
+//
+// or an iterator which returns an error if the stream can't be finished:
+//
+//	func (*Handler) V1(ctx context.Context, opts *V1Args) (func(yield func(*V1Item) bool) error, error)
+func streamItemType(t reflect.Type) reflect.Type {
+	switch t.Kind() {
+	case reflect.Chan:
+		if t.ChanDir()&reflect.RecvDir != 0 {
+			return t.Elem()
+		}
+	case reflect.Func:
+		if t.NumIn() != 1 || t.NumOut() != 1 || t.Out(0) != errorType {
+			return nil
+		}
+		yield := t.In(0)
+		if yield.Kind() == reflect.Func && yield.NumIn() == 1 && yield.NumOut() == 1 && yield.Out(0).Kind() == reflect.Bool {
+			return yield.In(0)
+		}
+	}
+	return nil
+}
+
+// ReadStream passes items of the stream returned by a handler with the StreamItem to fn until the stream ends.
+// It stops when fn returns an error or ctx is done and returns that error. An error returned by the iterator is
+// returned as *CallHandlerError.
+//
+// Handlers returning a channel must stop sending when ctx is done, otherwise their goroutines leak.
+func ReadStream(ctx context.Context, stream interface{}, fn func(item interface{}) error) error {
+	v := reflect.ValueOf(stream)
+	if !v.IsValid() || streamItemType(v.Type()) == nil {
+		return fmt.Errorf("Type %T is not a stream", stream)
+	}
+	if v.IsNil() {
+		return nil
+	}
+
+	if v.Kind() == reflect.Chan {
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+			{Dir: reflect.SelectRecv, Chan: v},
+		}
+		for {
+			chosen, item, ok := reflect.Select(cases)
+			if chosen == 0 {
+				return ctx.Err()
+			}
+			if !ok {
+				return nil
+			}
+			if err := fn(item.Interface()); err != nil {
+				return err
+			}
+		}
+	}
+
+	var fnErr error
+	yield := reflect.MakeFunc(v.Type().In(0), func(args []reflect.Value) []reflect.Value {
+		if fnErr == nil {
+			if fnErr = ctx.Err(); fnErr == nil {
+				fnErr = fn(args[0].Interface())
+			}
+		}
+		return []reflect.Value{reflect.ValueOf(fnErr == nil)}
+	})
+	out := v.Call([]reflect.Value{yield})
+	if fnErr != nil {
+		return fnErr
+	}
+	if out[0].IsNil() {
+		return nil
+	}
+
+	switch err := out[0].Interface().(error).(type) {
+	case *HandlerError:
+		return &CallHandlerError{ErrorReturnedFromCall, err}
+	case *CallHandlerError:
+		return err
+	default:
+		return &CallHandlerError{ErrorUnknown, err}
+	}
+}
@@ -0,0 +1,87 @@
+package gorpc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	test_handler_stream "github.com/sergei-svistunov/gorpc/test/handler_stream"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newStreamHandlersManager(t *testing.T) *HandlersManager {
+	hm := NewHandlersManager("github.com/sergei-svistunov/gorpc", HandlersManagerCallbacks{})
+	if err := hm.RegisterHandler(test_handler_stream.NewHandler()); err != nil {
+		t.Fatal(err)
+	}
+	return hm
+}
+
+func readStreamItems(t *testing.T, hm *HandlersManager, ctx context.Context, route string, args interface{}, limit int) ([]int, error) {
+	stream, callErr := hm.Invoke(ctx, route, args)
+	if callErr != nil {
+		t.Fatal(callErr)
+	}
+
+	var items []int
+	err := ReadStream(ctx, stream, func(item interface{}) error {
+		n := int(reflect.ValueOf(item).Elem().FieldByName("N").Int())
+		items = append(items, n)
+		if len(items) == limit {
+			return errors.New("limit reached")
+		}
+		return nil
+	})
+	return items, err
+}
+
+func TestStream_Registration(t *testing.T) {
+	hm := newStreamHandlersManager(t)
+
+	assert.Equal(t, reflect.TypeOf(&test_handler_stream.V1Item{}), hm.FindHandler("/test/handler_stream", 1).StreamItem)
+	assert.Equal(t, reflect.TypeOf(&test_handler_stream.V2Item{}), hm.FindHandler("/test/handler_stream", 2).StreamItem)
+}
+
+func TestStream_Channel(t *testing.T) {
+	hm := newStreamHandlersManager(t)
+
+	items, err := readStreamItems(t, hm, context.Background(), "/test/handler_stream/v1/", test_handler_stream.V1Args{Count: 3}, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2}, items)
+
+	items, err = readStreamItems(t, hm, context.Background(), "/test/handler_stream/v1/", test_handler_stream.V1Args{Count: 3}, 2)
+	assert.EqualError(t, err, "limit reached")
+	assert.Equal(t, []int{0, 1}, items)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = readStreamItems(t, hm, ctx, "/test/handler_stream/v1/", test_handler_stream.V1Args{Count: 3}, 0)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestStream_Iterator(t *testing.T) {
+	hm := newStreamHandlersManager(t)
+
+	items, err := readStreamItems(t, hm, context.Background(), "/test/handler_stream/v2/", test_handler_stream.V2Args{Count: 3}, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2}, items)
+
+	items, err = readStreamItems(t, hm, context.Background(), "/test/handler_stream/v2/", test_handler_stream.V2Args{Count: 3}, 1)
+	assert.EqualError(t, err, "limit reached")
+	assert.Equal(t, []int{0}, items)
+
+	failAt := 2
+	items, err = readStreamItems(t, hm, context.Background(), "/test/handler_stream/v2/", test_handler_stream.V2Args{Count: 3, FailAt: &failAt}, 0)
+	assert.Equal(t, []int{0, 1}, items)
+	if assert.IsType(t, &CallHandlerError{}, err) {
+		assert.Equal(t, ErrorReturnedFromCall, err.(*CallHandlerError).Type)
+		assert.Equal(t, "STREAM_FAILED", err.(*CallHandlerError).ErrorCode())
+	}
+}
+
+func TestStream_NotStream(t *testing.T) {
+	assert.Error(t, ReadStream(context.Background(), 5, func(interface{}) error { return nil }))
+	assert.Error(t, ReadStream(context.Background(), nil, func(interface{}) error { return nil }))
+}
@@ -0,0 +1,16 @@
+package handler_behavior
+
+type Handler struct {
+}
+
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+func (h *Handler) Caption() string {
+	return "Behavior handler"
+}
+
+func (h *Handler) Description() string {
+	return "Handler with controllable behavior (delays, panics, caching) for transport tests"
+}
@@ -0,0 +1,37 @@
+package handler_behavior
+
+import (
+	"context"
+	"time"
+
+	"github.com/sergei-svistunov/gorpc/transport/cache"
+)
+
+type V1Args struct {
+	Value int            `key:"value" description:"Value returned back"`
+	Sleep *time.Duration `key:"sleep" description:"Delay before the response"`
+	Panic *bool          `key:"panic" description:"Panic instead of the response"`
+	Cache *bool          `key:"cache" description:"Enable transport cache and ETag"`
+}
+
+type V1Res struct {
+	Value int `json:"value" description:"Value from arguments"`
+}
+
+func (*Handler) V1(ctx context.Context, opts *V1Args) (*V1Res, error) {
+	if opts.Sleep != nil {
+		select {
+		case <-time.After(*opts.Sleep):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if opts.Panic != nil && *opts.Panic {
+		panic("panic requested")
+	}
+	if opts.Cache != nil && *opts.Cache {
+		cache.EnableTransportCache(ctx)
+		cache.EnableETag(ctx)
+	}
+	return &V1Res{Value: opts.Value}, nil
+}
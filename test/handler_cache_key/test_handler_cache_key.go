@@ -0,0 +1,17 @@
+package handler_cache_key
+
+type Handler struct {
+	calls int
+}
+
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+func (h *Handler) Caption() string {
+	return "Cache key handler"
+}
+
+func (h *Handler) Description() string {
+	return "Handler which is cached by its parameters except tracking ones and by the language and the user"
+}
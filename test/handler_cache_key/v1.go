@@ -0,0 +1,44 @@
+package handler_cache_key
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/sergei-svistunov/gorpc/transport/cache"
+)
+
+// UserContextKey is the key of the user's name in the context
+type UserContextKey struct{}
+
+type V1Args struct {
+	ID     int    `key:"id" description:"Item ID"`
+	Source string `key:"source" description:"Source of the request for tracking" default:"" cache:"-"`
+}
+
+type V1Res struct {
+	Calls int    `json:"calls" description:"Number of calls"`
+	Text  string `json:"text" description:"Text of the item"`
+}
+
+func (*Handler) V1CacheVary() gorpc.CacheVary {
+	return gorpc.CacheVary{
+		Headers:     []string{"Accept-Language"},
+		ContextKeys: []interface{}{UserContextKey{}},
+	}
+}
+
+func (h *Handler) V1(ctx context.Context, opts *V1Args) (*V1Res, error) {
+	cache.EnableTransportCache(ctx)
+	h.calls++
+
+	lang := "en"
+	if info := gorpc.RequestInfo(ctx); info != nil && info.Header.Get("Accept-Language") != "" {
+		lang = info.Header.Get("Accept-Language")
+	}
+	text := fmt.Sprintf("item %d in %s", opts.ID, lang)
+	if user, ok := ctx.Value(UserContextKey{}).(string); ok {
+		text += " for " + user
+	}
+	return &V1Res{Calls: h.calls, Text: text}, nil
+}
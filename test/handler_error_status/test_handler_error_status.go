@@ -0,0 +1,16 @@
+package handler_error_status
+
+type Handler struct {
+}
+
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+func (h *Handler) Caption() string {
+	return "Error status handler"
+}
+
+func (h *Handler) Description() string {
+	return "Handler with business errors which have HTTP statuses"
+}
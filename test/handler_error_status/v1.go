@@ -0,0 +1,40 @@
+package handler_error_status
+
+import (
+	"context"
+)
+
+type V1Args struct {
+	ErrorID int `key:"error_id" description:"Handler returns error by id if it isn't zero"`
+}
+
+type V1Res struct {
+	OK bool `json:"ok" description:"Always true"`
+}
+
+type V1ErrorTypes struct {
+	NOT_FOUND error `text:"Not found" status:"404"`
+	FORBIDDEN error `text:"Forbidden" status:"403"`
+	GONE      error `text:"Gone" status:"404"`
+	CONFLICT  error `text:"Conflict"`
+}
+
+var v1Errors V1ErrorTypes
+
+func (*Handler) V1ErrorsVar() *V1ErrorTypes {
+	return &v1Errors
+}
+
+func (*Handler) V1(ctx context.Context, opts *V1Args) (*V1Res, error) {
+	switch opts.ErrorID {
+	case 1:
+		return nil, v1Errors.NOT_FOUND
+	case 2:
+		return nil, v1Errors.FORBIDDEN
+	case 3:
+		return nil, v1Errors.GONE
+	case 4:
+		return nil, v1Errors.CONFLICT
+	}
+	return &V1Res{OK: true}, nil
+}
@@ -0,0 +1,16 @@
+package handler_raw
+
+type Handler struct {
+}
+
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+func (h *Handler) Caption() string {
+	return "Raw handler"
+}
+
+func (h *Handler) Description() string {
+	return "Handler with the content returned as is"
+}
@@ -0,0 +1,49 @@
+package handler_raw
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/sergei-svistunov/gorpc"
+)
+
+type V1Args struct {
+	Rows   int  `key:"rows" description:"Number of rows"`
+	Stream bool `key:"stream" description:"Return the content as reader" default:"false"`
+}
+
+type V1ErrorTypes struct {
+	NO_ROWS error `text:"Rows must be positive"`
+}
+
+var v1Errors V1ErrorTypes
+
+func (*Handler) V1ErrorsVar() *V1ErrorTypes {
+	return &v1Errors
+}
+
+// V1 returns rows in CSV
+
+func (*Handler) V1(ctx context.Context, opts *V1Args) (*gorpc.RawResponse, error) {
+	if opts.Rows <= 0 {
+		return nil, v1Errors.NO_ROWS
+	}
+
+	content := "id,name\n" + strings.Repeat("1,test\n", opts.Rows)
+	res := &gorpc.RawResponse{
+		ContentType: "text/csv",
+		Header:      http.Header{"Content-Disposition": {`attachment; filename="rows.csv"`}},
+	}
+	if opts.Stream {
+		res.Reader = ioutil.NopCloser(strings.NewReader(content))
+	} else {
+		res.Body = []byte(content)
+	}
+	return res, nil
+}
+
+func (*Handler) V1Produces() []string {
+	return []string{"text/csv"}
+}
@@ -0,0 +1,16 @@
+package handler_request_info
+
+type Handler struct {
+}
+
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+func (h *Handler) Caption() string {
+	return "Request info handler"
+}
+
+func (h *Handler) Description() string {
+	return "Handler which returns the information about its request"
+}
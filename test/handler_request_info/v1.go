@@ -0,0 +1,34 @@
+package handler_request_info
+
+import (
+	"context"
+
+	"github.com/sergei-svistunov/gorpc"
+)
+
+type V1Args struct {
+}
+
+type V1Res struct {
+	Transport  string `json:"transport" description:"Name of the transport"`
+	Route      string `json:"route" description:"Route of the handler"`
+	Version    string `json:"version" description:"Version of the handler"`
+	RemoteAddr string `json:"remote_addr" description:"Address of the client"`
+	UserAgent  string `json:"user_agent" description:"User-Agent header"`
+	HTTP       bool   `json:"http" description:"The request is HTTP one"`
+}
+
+func (*Handler) V1(ctx context.Context, opts *V1Args) (*V1Res, error) {
+	info := gorpc.RequestInfo(ctx)
+	if info == nil {
+		return &V1Res{}, nil
+	}
+	return &V1Res{
+		Transport:  info.Transport,
+		Route:      info.Route,
+		Version:    info.Version,
+		RemoteAddr: info.RemoteAddr,
+		UserAgent:  info.Header.Get("User-Agent"),
+		HTTP:       info.HTTPRequest != nil,
+	}, nil
+}
@@ -0,0 +1,16 @@
+package handler_stream
+
+type Handler struct {
+}
+
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+func (h *Handler) Caption() string {
+	return "Stream handler"
+}
+
+func (h *Handler) Description() string {
+	return "Handler with streamed responses"
+}
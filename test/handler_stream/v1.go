@@ -0,0 +1,29 @@
+package handler_stream
+
+import (
+	"context"
+)
+
+type V1Args struct {
+	Count int `key:"count" description:"Number of items"`
+}
+
+type V1Item struct {
+	N int `json:"n" description:"Number of the item"`
+}
+
+// V1 streams items through a channel
+func (*Handler) V1(ctx context.Context, opts *V1Args) (<-chan *V1Item, error) {
+	items := make(chan *V1Item)
+	go func() {
+		defer close(items)
+		for i := 0; i < opts.Count; i++ {
+			select {
+			case items <- &V1Item{N: i}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return items, nil
+}
@@ -0,0 +1,39 @@
+package handler_stream
+
+import (
+	"context"
+)
+
+type V2Args struct {
+	Count  int  `key:"count" description:"Number of items"`
+	FailAt *int `key:"fail_at" description:"Number of the item after which the stream fails"`
+}
+
+type V2Item struct {
+	N int `json:"n" description:"Number of the item"`
+}
+
+type V2ErrorTypes struct {
+	STREAM_FAILED error `text:"Stream failed"`
+}
+
+var v2Errors V2ErrorTypes
+
+func (*Handler) V2ErrorsVar() *V2ErrorTypes {
+	return &v2Errors
+}
+
+// V2 streams items through an iterator
+func (*Handler) V2(ctx context.Context, opts *V2Args) (func(yield func(*V2Item) bool) error, error) {
+	return func(yield func(*V2Item) bool) error {
+		for i := 0; i < opts.Count; i++ {
+			if opts.FailAt != nil && *opts.FailAt == i {
+				return v2Errors.STREAM_FAILED
+			}
+			if !yield(&V2Item{N: i}) {
+				return nil
+			}
+		}
+		return nil
+	}, nil
+}
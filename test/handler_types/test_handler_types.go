@@ -0,0 +1,16 @@
+package handler_types
+
+type Handler struct {
+}
+
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+func (h *Handler) Caption() string {
+	return "Types handler"
+}
+
+func (h *Handler) Description() string {
+	return "Handler with parameters of special types for tests"
+}
@@ -0,0 +1,41 @@
+package handler_types
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Color is decoded via encoding.TextUnmarshaler
+type Color string
+
+func (c *Color) UnmarshalText(text []byte) error {
+	*c = Color(strings.ToUpper(string(text)))
+	return nil
+}
+
+// Point is decoded via json.Unmarshaler from "x,y" string or [x, y] array
+type Point [2]int
+
+func (p *Point) UnmarshalJSON(data []byte) error {
+	var arr [2]int
+	if err := json.Unmarshal(data, &arr); err != nil {
+		return err
+	}
+	*p = arr
+	return nil
+}
+
+type V1Args struct {
+	Since    time.Time                 `key:"since" description:"Time field"`
+	Timeout  *time.Duration            `key:"timeout" description:"Duration field"`
+	Color    *Color                    `key:"color" description:"Text unmarshaler field"`
+	Point    *Point                    `key:"point" description:"JSON unmarshaler field"`
+	Times    []time.Time               `key:"times" description:"Slice of times"`
+	Timeouts *map[string]time.Duration `key:"timeouts" description:"Map of durations"`
+}
+
+func (*Handler) V1(ctx context.Context, opts *V1Args) (*V1Args, error) {
+	return opts, nil
+}
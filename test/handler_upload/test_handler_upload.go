@@ -0,0 +1,16 @@
+package handler_upload
+
+type Handler struct {
+}
+
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+func (h *Handler) Caption() string {
+	return "Upload handler"
+}
+
+func (h *Handler) Description() string {
+	return "Handler with uploaded files"
+}
@@ -0,0 +1,52 @@
+package handler_upload
+
+import (
+	"context"
+	"io/ioutil"
+
+	"github.com/sergei-svistunov/gorpc"
+)
+
+type V1Args struct {
+	Title       string         `key:"title" description:"Title of the upload"`
+	Avatar      gorpc.File     `key:"avatar" description:"Single file"`
+	Attachments *[]*gorpc.File `key:"attachments" description:"Several files with the same key"`
+}
+
+type V1File struct {
+	Name        string `json:"name" description:"Name of the file"`
+	Size        int64  `json:"size" description:"Size of the file"`
+	ContentType string `json:"content_type" description:"Content type of the file"`
+	Content     string `json:"content" description:"Content of the file"`
+}
+
+type V1Res struct {
+	Title string    `json:"title" description:"Title of the upload"`
+	Files []*V1File `json:"files" description:"Uploaded files"`
+}
+
+func (*Handler) V1(ctx context.Context, opts *V1Args) (*V1Res, error) {
+	files := []*gorpc.File{&opts.Avatar}
+	if opts.Attachments != nil {
+		files = append(files, *opts.Attachments...)
+	}
+
+	res := &V1Res{Title: opts.Title}
+	for _, file := range files {
+		content, err := ioutil.ReadAll(file)
+		if err != nil {
+			return nil, err
+		}
+		res.Files = append(res.Files, &V1File{
+			Name:        file.Name,
+			Size:        file.Size,
+			ContentType: file.ContentType,
+			Content:     string(content),
+		})
+	}
+	return res, nil
+}
+
+func (*Handler) V1MaxUploadSize() int64 {
+	return 1 << 20
+}
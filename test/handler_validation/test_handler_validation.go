@@ -0,0 +1,16 @@
+package handler_validation
+
+type Handler struct {
+}
+
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+func (h *Handler) Caption() string {
+	return "Validation handler"
+}
+
+func (h *Handler) Description() string {
+	return "Handler with declarative parameters validation for tests"
+}
@@ -0,0 +1,15 @@
+package handler_validation
+
+import (
+	"context"
+)
+
+type V1Args struct {
+	Limit  int     `key:"limit" description:"Limit" min:"1" max:"100"`
+	Login  *string `key:"login" description:"Login" minLen:"3" maxLen:"8" pattern:"^[a-z0-9_]+$"`
+	Status *string `key:"status" description:"Status" enum:"active,blocked"`
+}
+
+func (*Handler) V1(ctx context.Context, opts *V1Args) (*V1Args, error) {
+	return opts, nil
+}
@@ -0,0 +1,18 @@
+package handler_validation
+
+import (
+	"context"
+)
+
+type V2Request struct {
+	Items []V2Item `key:"items" description:"Items" minLen:"1" maxLen:"3"`
+}
+
+type V2Item struct {
+	ID    int    `key:"id" description:"Item ID" min:"1"`
+	Level *int64 `key:"level" description:"Item level" enum:"1,2,3"`
+}
+
+func (*Handler) V2(ctx context.Context, opts *V2Request) (int, error) {
+	return len(opts.Items), nil
+}
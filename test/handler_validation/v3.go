@@ -0,0 +1,16 @@
+package handler_validation
+
+import (
+	"context"
+)
+
+type V3Args struct {
+	Limit  int      `key:"limit" description:"Limit" default:"20" max:"100"`
+	Offset *int     `key:"offset" description:"Offset" default:"0"`
+	Sort   *string  `key:"sort" description:"Sort order" default:"asc" enum:"asc,desc"`
+	IDs    []uint64 `key:"ids" description:"IDs" default:"1,2"`
+}
+
+func (*Handler) V3(ctx context.Context, opts *V3Args) (*V3Args, error) {
+	return opts, nil
+}
@@ -0,0 +1,18 @@
+package handler_validation
+
+import (
+	"context"
+)
+
+type V4Args struct {
+	Name string `key:"name" description:"Name"`
+}
+
+func (*Handler) V4(ctx context.Context, opts *V4Args) (string, error) {
+	return opts.Name, nil
+}
+
+// V4StrictParameters allows unknown parameters even if the strict mode is enabled
+func (*Handler) V4StrictParameters() bool {
+	return false
+}
@@ -1,6 +1,10 @@
 package cache
 
-import "time"
+import (
+	"io"
+	"net/http"
+	"time"
+)
 
 type ICache interface {
 	// Get returns response (response's content, compressed) by key
@@ -16,6 +20,24 @@ type TTLAwareCachePutter interface {
 	PutWithTTL(key []byte, entry *CacheEntry, ttl time.Duration)
 }
 
+// TagAwareCache indexes entries by tags, so they can be removed when the data they depend on changes
+type TagAwareCache interface {
+	// PutWithTags puts response in cache with specified ttl and tags, the default ttl is used if ttl is 0
+	PutWithTags(key []byte, entry *CacheEntry, ttl time.Duration, tags []string)
+	// InvalidateTags removes all entries with any of the tags
+	InvalidateTags(tags ...string)
+}
+
+// InvalidateTags removes entries with any of the tags from the cache, it returns false if the cache isn't
+// TagAwareCache
+func InvalidateTags(c ICache, tags ...string) bool {
+	tc, ok := c.(TagAwareCache)
+	if ok {
+		tc.InvalidateTags(tags...)
+	}
+	return ok
+}
+
 type ICacheLocker interface {
 	Lock(key []byte)
 	Unlock(key []byte)
@@ -26,4 +48,35 @@ type CacheEntry struct {
 	CompressedContent []byte
 	Hash              string
 	Body              interface{}
+	// ContentType is set for raw responses which are written as is
+	ContentType string
+	// Header contains headers set by the handler, they are written with the cached response too
+	Header http.Header
+	// Reader is the content of the raw response which isn't read into memory, such entries aren't cached
+	Reader io.Reader
+	// Expires is the end of freshness of the entry set by TTL, StaleUntil and StaleIfErrorUntil are ends of its stale
+	// windows
+	Expires           time.Time
+	StaleUntil        time.Time
+	StaleIfErrorUntil time.Time
+	// CacheControl, WeakETag and LastModified are set by the handler, they are sent with the cached response too
+	CacheControl CacheControl
+	WeakETag     bool
+	LastModified time.Time
+}
+
+// IsFresh reports whether the entry can be served as is, entries without Expires are always fresh
+func (e *CacheEntry) IsFresh(now time.Time) bool {
+	return e.Expires.IsZero() || now.Before(e.Expires)
+}
+
+// IsStale reports whether the expired entry can be served while it's refreshed
+func (e *CacheEntry) IsStale(now time.Time) bool {
+	return !e.IsFresh(now) && now.Before(e.StaleUntil)
+}
+
+// IsStaleIfError reports whether the expired entry can be served if the handler fails, the stale entry can be
+// served too
+func (e *CacheEntry) IsStaleIfError(now time.Time) bool {
+	return e.IsStale(now) || (!e.IsFresh(now) && now.Before(e.StaleIfErrorUntil))
 }
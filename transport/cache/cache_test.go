@@ -41,3 +41,31 @@ func TestLockCache(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestCacheEntry_Freshness(t *testing.T) {
+	now := time.Now()
+	entry := &CacheEntry{}
+	if !entry.IsFresh(now) || entry.IsStale(now) || entry.IsStaleIfError(now) {
+		t.Error("Entry without Expires must be always fresh")
+	}
+
+	entry = &CacheEntry{
+		Expires:           now.Add(time.Second),
+		StaleUntil:        now.Add(2 * time.Second),
+		StaleIfErrorUntil: now.Add(3 * time.Second),
+	}
+	for _, c := range []struct {
+		after                      time.Duration
+		fresh, stale, staleIfError bool
+	}{
+		{0, true, false, false},
+		{time.Second, false, true, true},
+		{2 * time.Second, false, false, true},
+		{3 * time.Second, false, false, false},
+	} {
+		at := now.Add(c.after)
+		if entry.IsFresh(at) != c.fresh || entry.IsStale(at) != c.stale || entry.IsStaleIfError(at) != c.staleIfError {
+			t.Errorf("Invalid freshness after %s", c.after)
+		}
+	}
+}
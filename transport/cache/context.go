@@ -9,11 +9,31 @@ type key int
 
 var requestInfoKey key
 
+// CacheControl is the policy of HTTP caches for the response, see SetCacheControl
+type CacheControl int
+
+const (
+	// CacheControlDefault allows only the client's cache to store the response for TTL, as CacheControlPrivate does
+	CacheControlDefault CacheControl = iota
+	// CacheControlPublic allows shared caches (e.g. CDN) to store the response
+	CacheControlPublic
+	// CacheControlPrivate allows only the client's cache to store the response, e.g. it's personal
+	CacheControlPrivate
+	// CacheControlNoStore forbids all HTTP caches to store the response
+	CacheControlNoStore
+)
+
 type requestInfo struct {
-	useCache bool
-	useETag  bool
-	ttl      time.Duration // if ttl = 0 will be used default cache ttl
-	debug    bool          // if true IsETagEnabled and IsTransportCacheEnabled will return false
+	useCache        bool
+	useETag         bool
+	ttl             time.Duration // if ttl = 0 will be used default cache ttl
+	staleTTL        time.Duration // time after ttl when the expired entry is served while it's refreshed or if the handler fails
+	staleIfErrorTTL time.Duration // time after ttl when the expired entry is served if the handler fails
+	debug           bool          // if true IsETagEnabled and IsTransportCacheEnabled will return false
+	tags            []string      // tags of the response for invalidation, see AddTags
+	cacheControl    CacheControl  // Cache-Control of the response, its max-age is ttl
+	weakETag        bool          // if true ETag of the response is weak
+	lastModified    time.Time     // Last-Modified of the response, it's not sent if it's zero
 }
 
 func NewContext(parent context.Context) context.Context {
@@ -168,6 +188,148 @@ func NewContextWithoutTTL(parent context.Context) context.Context {
 	return newContext(parent, &info)
 }
 
+// StaleTTL returns the stale window of the response, see SetStaleTTL
+func StaleTTL(ctx context.Context) time.Duration {
+	if info, ok := fromContext(ctx); ok {
+		return info.staleTTL
+	}
+	return time.Duration(0)
+}
+
+// SetStaleTTL sets the time after TTL when the expired response is still served. The stale response is returned
+// while a single background call of the handler refreshes it (stale-while-revalidate) and if the handler fails with
+// an internal error (stale-if-error). It works only with TTL set by SetTTL.
+func SetStaleTTL(ctx context.Context, staleTTL time.Duration) {
+	if info, ok := fromContext(ctx); ok {
+		info.staleTTL = staleTTL
+	}
+}
+
+func NewContextWithStaleTTL(parent context.Context, staleTTL time.Duration) context.Context {
+	var info requestInfo
+	if c, ok := fromContext(parent); ok {
+		info = *c
+	}
+	info.staleTTL = staleTTL
+	return newContext(parent, &info)
+}
+
+// StaleIfErrorTTL returns the stale-if-error window of the response, see SetStaleIfErrorTTL
+func StaleIfErrorTTL(ctx context.Context) time.Duration {
+	if info, ok := fromContext(ctx); ok {
+		return info.staleIfErrorTTL
+	}
+	return time.Duration(0)
+}
+
+// SetStaleIfErrorTTL sets the time after TTL when the expired response is served if the handler fails with
+// an internal error, it's useful if it's longer than the window set by SetStaleTTL. It works only with TTL set by
+// SetTTL.
+func SetStaleIfErrorTTL(ctx context.Context, staleIfErrorTTL time.Duration) {
+	if info, ok := fromContext(ctx); ok {
+		info.staleIfErrorTTL = staleIfErrorTTL
+	}
+}
+
+func NewContextWithStaleIfErrorTTL(parent context.Context, staleIfErrorTTL time.Duration) context.Context {
+	var info requestInfo
+	if c, ok := fromContext(parent); ok {
+		info = *c
+	}
+	info.staleIfErrorTTL = staleIfErrorTTL
+	return newContext(parent, &info)
+}
+
+// Tags returns tags of the response, see AddTags
+func Tags(ctx context.Context) []string {
+	if info, ok := fromContext(ctx); ok {
+		return info.tags
+	}
+	return nil
+}
+
+// AddTags attaches tags to the cached response, e.g. "user:42". Entries are removed by InvalidateTags when
+// the data they depend on changes. Tags are ignored if the cache isn't TagAwareCache.
+func AddTags(ctx context.Context, tags ...string) {
+	if info, ok := fromContext(ctx); ok {
+		// the slice may be shared with the parent context
+		info.tags = append(info.tags[:len(info.tags):len(info.tags)], tags...)
+	}
+}
+
+// NewContextWithTags returns new context with the tags added to tags of the parent context
+func NewContextWithTags(parent context.Context, tags ...string) context.Context {
+	var info requestInfo
+	if c, ok := fromContext(parent); ok {
+		info = *c
+	}
+	info.tags = append(info.tags[:len(info.tags):len(info.tags)], tags...)
+	return newContext(parent, &info)
+}
+
+// GetCacheControl returns the policy of HTTP caches for the response, see SetCacheControl
+func GetCacheControl(ctx context.Context) CacheControl {
+	if info, ok := fromContext(ctx); ok {
+		return info.cacheControl
+	}
+	return CacheControlDefault
+}
+
+// SetCacheControl sets the policy of HTTP caches for the response. Cache-Control header is sent with max-age equal to
+// the time left of TTL set by SetTTL, the header set by the handler itself isn't replaced. Shared caches store
+// the response only if it's CacheControlPublic.
+func SetCacheControl(ctx context.Context, cacheControl CacheControl) {
+	if info, ok := fromContext(ctx); ok {
+		info.cacheControl = cacheControl
+	}
+}
+
+func NewContextWithCacheControl(parent context.Context, cacheControl CacheControl) context.Context {
+	var info requestInfo
+	if c, ok := fromContext(parent); ok {
+		info = *c
+	}
+	info.cacheControl = cacheControl
+	return newContext(parent, &info)
+}
+
+// IsWeakETagEnabled returns true if ETag of the response is weak, i.e. the response is equivalent for the client but
+// it's not the same byte by byte, e.g. it's compressed
+func IsWeakETagEnabled(ctx context.Context) bool {
+	if info, ok := fromContext(ctx); ok {
+		return info.weakETag
+	}
+	return false
+}
+
+func EnableWeakETag(ctx context.Context) {
+	if info, ok := fromContext(ctx); ok {
+		info.weakETag = true
+	}
+}
+
+func DisableWeakETag(ctx context.Context) {
+	if info, ok := fromContext(ctx); ok {
+		info.weakETag = false
+	}
+}
+
+// LastModified returns the time of the last modification of the response's data, see SetLastModified
+func LastModified(ctx context.Context) time.Time {
+	if info, ok := fromContext(ctx); ok {
+		return info.lastModified
+	}
+	return time.Time{}
+}
+
+// SetLastModified sets the time of the last modification of the response's data, it's sent in Last-Modified header
+// and the response to the request with later If-Modified-Since is 304 Not Modified
+func SetLastModified(ctx context.Context, lastModified time.Time) {
+	if info, ok := fromContext(ctx); ok {
+		info.lastModified = lastModified
+	}
+}
+
 func fromContext(ctx context.Context) (info *requestInfo, ok bool) {
 	if val := ctx.Value(requestInfoKey); val != nil {
 		info, ok = val.(*requestInfo)
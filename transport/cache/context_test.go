@@ -5,6 +5,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
 	"testing"
+	"time"
 )
 
 func TestCacheRequestInfo(t *testing.T) {
@@ -47,3 +48,58 @@ func TestCacheRequestInfo(t *testing.T) {
 	assert.Equal(t, true, cache.IsETagEnabled(ctx2), "ETag flag should not be modified in child context")
 	assert.Equal(t, true, cache.IsTransportCacheEnabled(ctx2), "Chache flag should not be modified in child context")
 }
+
+func TestCacheStaleTTL(t *testing.T) {
+	ctx := cache.NewContext(context.Background())
+	assert.Equal(t, time.Duration(0), cache.StaleTTL(ctx), "Stale TTL should be 0 for empty context")
+
+	cache.SetStaleTTL(ctx, time.Minute)
+	cache.SetStaleIfErrorTTL(ctx, time.Hour)
+	assert.Equal(t, time.Minute, cache.StaleTTL(ctx), "Stale TTL should be set by SetStaleTTL")
+	assert.Equal(t, time.Hour, cache.StaleIfErrorTTL(ctx), "Stale-if-error TTL should be set by SetStaleIfErrorTTL")
+
+	ctx2 := cache.NewContextWithStaleTTL(ctx, time.Second)
+	ctx2 = cache.NewContextWithStaleIfErrorTTL(ctx2, 2*time.Second)
+	assert.Equal(t, time.Minute, cache.StaleTTL(ctx), "Stale TTL should not be modified in parent context")
+	assert.Equal(t, time.Hour, cache.StaleIfErrorTTL(ctx), "Stale-if-error TTL should not be modified in parent context")
+	assert.Equal(t, time.Second, cache.StaleTTL(ctx2), "Stale TTL should be set by NewContextWithStaleTTL")
+	assert.Equal(t, 2*time.Second, cache.StaleIfErrorTTL(ctx2), "Stale-if-error TTL should be set by NewContextWithStaleIfErrorTTL")
+}
+
+func TestCacheTags(t *testing.T) {
+	ctx := cache.NewContext(context.Background())
+	assert.Nil(t, cache.Tags(ctx), "Tags should be empty for empty context")
+
+	cache.AddTags(ctx, "user:1")
+	cache.AddTags(ctx, "user:2", "user:3")
+	assert.Equal(t, []string{"user:1", "user:2", "user:3"}, cache.Tags(ctx), "Tags should be added by AddTags")
+
+	ctx2 := cache.NewContextWithTags(ctx, "user:4")
+	cache.AddTags(ctx2, "user:5")
+	assert.Equal(t, []string{"user:1", "user:2", "user:3"}, cache.Tags(ctx), "Tags should not be modified in parent context")
+	assert.Equal(t, []string{"user:1", "user:2", "user:3", "user:4", "user:5"}, cache.Tags(ctx2), "Tags should be added to tags of parent context")
+
+	assert.Equal(t, false, cache.InvalidateTags(cache.NewMapCache(), "user:1"), "Map cache isn't tag aware")
+}
+
+func TestCacheHTTPHeaders(t *testing.T) {
+	ctx := cache.NewContext(context.Background())
+	assert.Equal(t, cache.CacheControlDefault, cache.GetCacheControl(ctx), "Cache-Control should be default for empty context")
+	assert.Equal(t, false, cache.IsWeakETagEnabled(ctx), "Weak ETag should be disabled for empty context")
+	assert.True(t, cache.LastModified(ctx).IsZero(), "Last-Modified should be zero for empty context")
+
+	lastModified := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache.SetCacheControl(ctx, cache.CacheControlPrivate)
+	cache.EnableWeakETag(ctx)
+	cache.SetLastModified(ctx, lastModified)
+	assert.Equal(t, cache.CacheControlPrivate, cache.GetCacheControl(ctx), "Cache-Control should be set by SetCacheControl")
+	assert.Equal(t, true, cache.IsWeakETagEnabled(ctx), "Weak ETag should be enabled after calling EnableWeakETag")
+	assert.Equal(t, lastModified, cache.LastModified(ctx), "Last-Modified should be set by SetLastModified")
+
+	ctx2 := cache.NewContextWithCacheControl(ctx, cache.CacheControlNoStore)
+	cache.DisableWeakETag(ctx2)
+	assert.Equal(t, cache.CacheControlPrivate, cache.GetCacheControl(ctx), "Cache-Control should not be modified in parent context")
+	assert.Equal(t, true, cache.IsWeakETagEnabled(ctx), "Weak ETag flag should not be modified in parent context")
+	assert.Equal(t, cache.CacheControlNoStore, cache.GetCacheControl(ctx2), "Cache-Control should be set by NewContextWithCacheControl")
+	assert.Equal(t, false, cache.IsWeakETagEnabled(ctx2), "Weak ETag should be disabled after calling DisableWeakETag")
+}
@@ -8,6 +8,8 @@ type mapCache struct {
 	*LocalCacheLocker
 }
 
+// NewMapCache returns the unbounded cache which ignores TTL and never evicts entries, it's suitable for tests.
+// Use NewMemoryCache in production.
 func NewMapCache() *mapCache {
 	return &mapCache{
 		values:           make(map[string]*CacheEntry),
@@ -0,0 +1,306 @@
+package cache
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// EvictionPolicy chooses the entry which is removed when MemoryCache is full
+type EvictionPolicy int
+
+const (
+	// EvictLRU removes the least recently used entry
+	EvictLRU EvictionPolicy = iota
+	// EvictLFU removes the least frequently used entry, the least recently used one of entries with the same number
+	// of hits
+	EvictLFU
+)
+
+// DefaultCleanupInterval is the period of removing expired entries of MemoryCache
+const DefaultCleanupInterval = time.Minute
+
+type MemoryCacheConfig struct {
+	// MaxEntries limits the number of entries, 0 means no limit
+	MaxEntries int
+	// MaxBytes limits the size of keys and contents of entries, 0 means no limit. Entries larger than the limit
+	// aren't cached.
+	MaxBytes int64
+	// TTL is the time to live of entries put without TTL, 0 means entries don't expire
+	TTL time.Duration
+	// Policy is the eviction policy, LRU by default
+	Policy EvictionPolicy
+	// CleanupInterval is the period of removing expired entries in background, DefaultCleanupInterval is used if
+	// it's 0, negative value disables the cleanup. Expired entries are never returned anyway.
+	CleanupInterval time.Duration
+}
+
+// MemoryCacheStats are counters of MemoryCache since its creation
+type MemoryCacheStats struct {
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64
+	Expirations uint64
+	Entries     int
+	Bytes       int64
+}
+
+// MemoryCache is the bounded in-memory cache with TTL of entries. It evicts entries by the policy when the limits
+// are reached. Concurrent requests with the same key are blocked by LocalCacheLocker until the first one puts
+// the result.
+type MemoryCache struct {
+	*LocalCacheLocker
+
+	config MemoryCacheConfig
+	now    func() time.Time
+
+	mtx   sync.Mutex
+	items map[string]*memoryCacheItem
+	tags  map[string]map[*memoryCacheItem]struct{}
+	queue memoryCacheQueue
+	tick  uint64
+	stats MemoryCacheStats
+
+	done chan struct{}
+	once sync.Once
+}
+
+type memoryCacheItem struct {
+	key       string
+	entry     *CacheEntry
+	size      int64
+	expiresAt time.Time
+	tags      []string
+	hits      uint64
+	tick      uint64
+	index     int
+}
+
+func NewMemoryCache(config MemoryCacheConfig) *MemoryCache {
+	c := &MemoryCache{
+		LocalCacheLocker: NewLocalCacheLocker(),
+		config:           config,
+		now:              time.Now,
+		items:            make(map[string]*memoryCacheItem),
+		tags:             make(map[string]map[*memoryCacheItem]struct{}),
+		done:             make(chan struct{}),
+	}
+	c.queue.policy = config.Policy
+
+	interval := config.CleanupInterval
+	if interval == 0 {
+		interval = DefaultCleanupInterval
+	}
+	if interval > 0 {
+		go c.cleanup(interval)
+	}
+	return c
+}
+
+func (c *MemoryCache) Get(key []byte) *CacheEntry {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	item, ok := c.items[string(key)]
+	if ok && c.expired(item) {
+		c.remove(item)
+		c.stats.Expirations++
+		ok = false
+	}
+	if !ok {
+		c.stats.Misses++
+		return nil
+	}
+
+	c.stats.Hits++
+	c.tick++
+	item.hits++
+	item.tick = c.tick
+	heap.Fix(&c.queue, item.index)
+	return item.entry
+}
+
+func (c *MemoryCache) Put(key []byte, entry *CacheEntry) {
+	c.PutWithTTL(key, entry, c.config.TTL)
+}
+
+// PutWithTTL puts the entry which expires after ttl, 0 means it doesn't expire
+func (c *MemoryCache) PutWithTTL(key []byte, entry *CacheEntry, ttl time.Duration) {
+	c.put(key, entry, ttl, nil)
+}
+
+// PutWithTags puts the entry which is removed by InvalidateTags with any of the tags, it expires after ttl or after
+// TTL of the config if ttl is 0
+func (c *MemoryCache) PutWithTags(key []byte, entry *CacheEntry, ttl time.Duration, tags []string) {
+	if ttl == 0 {
+		ttl = c.config.TTL
+	}
+	c.put(key, entry, ttl, tags)
+}
+
+func (c *MemoryCache) put(key []byte, entry *CacheEntry, ttl time.Duration, tags []string) {
+	size := int64(len(key) + len(entry.Content) + len(entry.CompressedContent))
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if item, ok := c.items[string(key)]; ok {
+		c.remove(item)
+	}
+	if c.config.MaxBytes > 0 && size > c.config.MaxBytes {
+		return
+	}
+
+	c.tick++
+	item := &memoryCacheItem{
+		key:   string(key),
+		entry: entry,
+		size:  size,
+		tick:  c.tick,
+		tags:  tags,
+	}
+	if ttl > 0 {
+		item.expiresAt = c.now().Add(ttl)
+	}
+	c.items[item.key] = item
+	for _, tag := range tags {
+		tagged, ok := c.tags[tag]
+		if !ok {
+			tagged = make(map[*memoryCacheItem]struct{})
+			c.tags[tag] = tagged
+		}
+		tagged[item] = struct{}{}
+	}
+	c.stats.Bytes += size
+
+	// the new item isn't in the queue yet, so it isn't evicted before others even if it has no hits
+	for c.queue.Len() > 0 && ((c.config.MaxEntries > 0 && len(c.items) > c.config.MaxEntries) ||
+		(c.config.MaxBytes > 0 && c.stats.Bytes > c.config.MaxBytes)) {
+		c.remove(c.queue.items[0])
+		c.stats.Evictions++
+	}
+	heap.Push(&c.queue, item)
+}
+
+// Delete removes the entry
+func (c *MemoryCache) Delete(key []byte) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if item, ok := c.items[string(key)]; ok {
+		c.remove(item)
+	}
+}
+
+// InvalidateTags removes entries with any of the tags
+func (c *MemoryCache) InvalidateTags(tags ...string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for _, tag := range tags {
+		for item := range c.tags[tag] {
+			c.remove(item)
+		}
+	}
+}
+
+// Stats returns counters of the cache
+func (c *MemoryCache) Stats() MemoryCacheStats {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	stats := c.stats
+	stats.Entries = len(c.items)
+	return stats
+}
+
+// Close stops the background cleanup, the cache can be used after it
+func (c *MemoryCache) Close() error {
+	c.once.Do(func() {
+		close(c.done)
+	})
+	return nil
+}
+
+func (c *MemoryCache) cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.removeExpired()
+		}
+	}
+}
+
+func (c *MemoryCache) removeExpired() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for _, item := range c.items {
+		if c.expired(item) {
+			c.remove(item)
+			c.stats.Expirations++
+		}
+	}
+}
+
+func (c *MemoryCache) expired(item *memoryCacheItem) bool {
+	return !item.expiresAt.IsZero() && !c.now().Before(item.expiresAt)
+}
+
+// remove deletes the item, the caller must hold the mutex
+func (c *MemoryCache) remove(item *memoryCacheItem) {
+	heap.Remove(&c.queue, item.index)
+	delete(c.items, item.key)
+	c.stats.Bytes -= item.size
+	for _, tag := range item.tags {
+		if tagged := c.tags[tag]; tagged != nil {
+			delete(tagged, item)
+			if len(tagged) == 0 {
+				delete(c.tags, tag)
+			}
+		}
+	}
+}
+
+// memoryCacheQueue is the heap of items with the item to evict on the top
+type memoryCacheQueue struct {
+	items  []*memoryCacheItem
+	policy EvictionPolicy
+}
+
+func (q memoryCacheQueue) Len() int {
+	return len(q.items)
+}
+
+func (q memoryCacheQueue) Less(i, j int) bool {
+	a, b := q.items[i], q.items[j]
+	if q.policy == EvictLFU && a.hits != b.hits {
+		return a.hits < b.hits
+	}
+	return a.tick < b.tick
+}
+
+func (q memoryCacheQueue) Swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+	q.items[i].index = i
+	q.items[j].index = j
+}
+
+func (q *memoryCacheQueue) Push(x interface{}) {
+	item := x.(*memoryCacheItem)
+	item.index = len(q.items)
+	q.items = append(q.items, item)
+}
+
+func (q *memoryCacheQueue) Pop() interface{} {
+	n := len(q.items)
+	item := q.items[n-1]
+	q.items[n-1] = nil
+	q.items = q.items[:n-1]
+	return item
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestMemoryCache(config MemoryCacheConfig) (*MemoryCache, *time.Time) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	config.CleanupInterval = -1
+	c := NewMemoryCache(config)
+	c.now = func() time.Time {
+		return now
+	}
+	return c, &now
+}
+
+func putContent(c *MemoryCache, keys ...string) {
+	for _, key := range keys {
+		c.Put([]byte(key), &CacheEntry{Content: []byte("content_" + key)})
+	}
+}
+
+func cachedKeys(t *testing.T, c *MemoryCache, keys ...string) []string {
+	var res []string
+	for _, key := range keys {
+		if entry := c.Get([]byte(key)); entry != nil {
+			assert.Equal(t, "content_"+key, string(entry.Content))
+			res = append(res, key)
+		}
+	}
+	return res
+}
+
+func TestMemoryCache_LRU(t *testing.T) {
+	c, _ := newTestMemoryCache(MemoryCacheConfig{MaxEntries: 3})
+
+	putContent(c, "a", "b", "c")
+	c.Get([]byte("a"))
+	putContent(c, "d")
+	assert.Equal(t, []string{"a", "c", "d"}, cachedKeys(t, c, "a", "b", "c", "d"))
+
+	// replacing of the entry doesn't evict others
+	putContent(c, "c")
+	assert.Equal(t, []string{"a", "c", "d"}, cachedKeys(t, c, "a", "b", "c", "d"))
+
+	assert.Equal(t, MemoryCacheStats{Hits: 7, Misses: 2, Evictions: 1, Entries: 3, Bytes: 30}, c.Stats())
+}
+
+func TestMemoryCache_LFU(t *testing.T) {
+	c, _ := newTestMemoryCache(MemoryCacheConfig{MaxEntries: 3, Policy: EvictLFU})
+
+	putContent(c, "a", "b", "c")
+	for i := 0; i < 3; i++ {
+		c.Get([]byte("a"))
+		c.Get([]byte("b"))
+	}
+	c.Get([]byte("c"))
+	putContent(c, "d")
+	assert.Equal(t, []string{"a", "b", "d"}, cachedKeys(t, c, "a", "b", "c", "d"))
+
+	// "d" has the least number of hits
+	putContent(c, "e")
+	assert.Equal(t, []string{"a", "b", "e"}, cachedKeys(t, c, "a", "b", "d", "e"))
+}
+
+func TestMemoryCache_MaxBytes(t *testing.T) {
+	c, _ := newTestMemoryCache(MemoryCacheConfig{MaxBytes: 25})
+
+	putContent(c, "a", "b", "c")
+	assert.Equal(t, []string{"b", "c"}, cachedKeys(t, c, "a", "b", "c"))
+	assert.Equal(t, int64(20), c.Stats().Bytes)
+
+	c.Put([]byte("large"), &CacheEntry{Content: make([]byte, 10), CompressedContent: make([]byte, 16)})
+	assert.Nil(t, c.Get([]byte("large")))
+	assert.Equal(t, []string{"b", "c"}, cachedKeys(t, c, "b", "c"))
+}
+
+func TestMemoryCache_TTL(t *testing.T) {
+	c, now := newTestMemoryCache(MemoryCacheConfig{TTL: time.Minute})
+
+	putContent(c, "a")
+	c.PutWithTTL([]byte("b"), &CacheEntry{Content: []byte("content_b")}, time.Hour)
+	c.PutWithTTL([]byte("c"), &CacheEntry{Content: []byte("content_c")}, 0)
+
+	*now = now.Add(time.Minute)
+	assert.Equal(t, []string{"b", "c"}, cachedKeys(t, c, "a", "b", "c"))
+
+	*now = now.Add(time.Hour)
+	c.removeExpired()
+	assert.Equal(t, MemoryCacheStats{Hits: 2, Misses: 1, Expirations: 2, Entries: 1, Bytes: 10}, c.Stats())
+	assert.Equal(t, []string{"c"}, cachedKeys(t, c, "a", "b", "c"))
+
+	c.Delete([]byte("c"))
+	assert.Nil(t, c.Get([]byte("c")))
+}
+
+func TestMemoryCache_Cleanup(t *testing.T) {
+	c := NewMemoryCache(MemoryCacheConfig{TTL: 10 * time.Millisecond, CleanupInterval: 5 * time.Millisecond})
+	defer c.Close()
+
+	for i := 0; i < 10; i++ {
+		putContent(c, strconv.Itoa(i))
+	}
+	assert.Equal(t, 10, c.Stats().Entries)
+
+	time.Sleep(50 * time.Millisecond)
+	stats := c.Stats()
+	assert.Equal(t, 0, stats.Entries)
+	assert.Equal(t, uint64(10), stats.Expirations)
+	assert.Equal(t, int64(0), stats.Bytes)
+}
+
+func TestMemoryCache_Tags(t *testing.T) {
+	c, now := newTestMemoryCache(MemoryCacheConfig{TTL: time.Minute})
+
+	c.PutWithTags([]byte("a"), &CacheEntry{Content: []byte("content_a")}, 0, []string{"user:1"})
+	c.PutWithTags([]byte("b"), &CacheEntry{Content: []byte("content_b")}, time.Hour, []string{"user:1", "user:2"})
+	c.PutWithTags([]byte("c"), &CacheEntry{Content: []byte("content_c")}, 0, []string{"user:2"})
+	putContent(c, "d")
+
+	c.InvalidateTags("user:1", "unknown")
+	assert.Equal(t, []string{"c", "d"}, cachedKeys(t, c, "a", "b", "c", "d"))
+	assert.Equal(t, map[string]map[*memoryCacheItem]struct{}{"user:2": {c.items["c"]: {}}}, c.tags)
+
+	// the default TTL is used for tagged entries without TTL
+	*now = now.Add(time.Minute)
+	assert.Nil(t, c.Get([]byte("c")))
+	assert.Empty(t, c.tags)
+}
+
+var (
+	_ ICache              = (*MemoryCache)(nil)
+	_ TTLAwareCachePutter = (*MemoryCache)(nil)
+	_ TagAwareCache       = (*MemoryCache)(nil)
+)
@@ -0,0 +1,314 @@
+package frame_json
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sergei-svistunov/gorpc"
+)
+
+const (
+	defaultPoolSize    = 4
+	defaultDialTimeout = 5 * time.Second
+)
+
+// ErrClientClosed is returned by calls after the call of Client.Close
+var ErrClientClosed = errors.New("frame_json: Client closed")
+
+// CallError is the error returned by the handler or by the server, e.g. http_json.ErrorInvalidParameters
+type CallError struct {
+	Code string
+	Data json.RawMessage
+}
+
+func (e *CallError) Error() string {
+	var message string
+	if err := json.Unmarshal(e.Data, &message); err == nil {
+		return message
+	}
+	return e.Code + ": " + string(e.Data)
+}
+
+func (e *CallError) ErrorCode() string {
+	return e.Code
+}
+
+// ParameterErrors returns the list of invalid fields if the server rejected the call's parameters
+func (e *CallError) ParameterErrors() gorpc.ParameterErrors {
+	var paramErrs gorpc.ParameterErrors
+	if err := json.Unmarshal(e.Data, &paramErrs); err != nil {
+		return nil
+	}
+	return paramErrs
+}
+
+// rawResponse is the server's frame with the undecoded data
+type rawResponse struct {
+	ID     uint64          `json:"id"`
+	Result string          `json:"result"`
+	Data   json.RawMessage `json:"data"`
+	Error  string          `json:"error"`
+}
+
+// Client calls handlers served by Server. It keeps a pool of connections, every connection carries many
+// concurrent calls. Client is safe for concurrent use.
+type Client struct {
+	network     string
+	address     string
+	dialTimeout time.Duration
+
+	mu     sync.Mutex
+	closed bool
+	slots  []*poolSlot
+	next   uint64
+}
+
+type poolSlot struct {
+	mu   sync.Mutex
+	conn *clientConn
+}
+
+// NewClient creates the client of the server listening on the address, network is "tcp" or "unix" as in net.Dial.
+// Connections are established on demand.
+func NewClient(network, address string) *Client {
+	return (&Client{
+		network:     network,
+		address:     address,
+		dialTimeout: defaultDialTimeout,
+	}).SetPoolSize(defaultPoolSize)
+}
+
+// SetPoolSize sets the number of connections, it must be called before the first call
+func (c *Client) SetPoolSize(size int) *Client {
+	if size < 1 {
+		size = 1
+	}
+	c.slots = make([]*poolSlot, size)
+	for i := range c.slots {
+		c.slots[i] = &poolSlot{}
+	}
+	return c
+}
+
+// SetDialTimeout sets the timeout of establishing a connection
+func (c *Client) SetDialTimeout(timeout time.Duration) *Client {
+	c.dialTimeout = timeout
+	return c
+}
+
+// Call calls the handler with the route and decodes the data of the response into result. Params are encoded into
+// JSON if they aren't []byte or json.RawMessage. Errors returned by the server are *CallError.
+func (c *Client) Call(ctx context.Context, route string, params interface{}, result interface{}) error {
+	var data []byte
+	switch p := params.(type) {
+	case []byte:
+		data = p
+	case json.RawMessage:
+		data = p
+	default:
+		var err error
+		if data, err = json.Marshal(params); err != nil {
+			return err
+		}
+	}
+
+	resData, errCode, err := c.CallRaw(ctx, route, data)
+	if err != nil {
+		return err
+	}
+	if errCode != "" {
+		return &CallError{Code: errCode, Data: resData}
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(resData, result)
+}
+
+// CallRaw calls the handler with the route and JSON encoded params. It returns the data of the response and
+// the error code if the server returned an error.
+func (c *Client) CallRaw(ctx context.Context, route string, params []byte) (data []byte, errCode string, err error) {
+	conn, err := c.conn(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := conn.call(ctx, route, params)
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.Result != "OK" {
+		return resp.Data, resp.Error, nil
+	}
+	return resp.Data, "", nil
+}
+
+// Close closes all connections, the calls in progress return an error
+func (c *Client) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	for _, slot := range c.slots {
+		slot.mu.Lock()
+		if slot.conn != nil {
+			slot.conn.close(ErrClientClosed)
+			slot.conn = nil
+		}
+		slot.mu.Unlock()
+	}
+	return nil
+}
+
+// conn returns the next connection of the pool, broken connections are reestablished
+func (c *Client) conn(ctx context.Context) (*clientConn, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrClientClosed
+	}
+	slot := c.slots[c.next%uint64(len(c.slots))]
+	c.next++
+	c.mu.Unlock()
+
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+	if slot.conn != nil && slot.conn.broken() == nil {
+		return slot.conn, nil
+	}
+
+	dialer := net.Dialer{Timeout: c.dialTimeout}
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		conn.Close()
+		return nil, ErrClientClosed
+	}
+
+	slot.conn = newClientConn(conn)
+	return slot.conn, nil
+}
+
+// clientConn is one connection of the pool, responses are read in a separate goroutine and passed to the waiting
+// calls by id.
+type clientConn struct {
+	conn   net.Conn
+	writer frameWriter
+	lastID uint64
+
+	mu      sync.Mutex
+	err     error
+	pending map[uint64]chan *rawResponse
+}
+
+func newClientConn(conn net.Conn) *clientConn {
+	c := &clientConn{
+		conn:    conn,
+		writer:  frameWriter{conn: conn},
+		pending: make(map[uint64]chan *rawResponse),
+	}
+	go c.readLoop()
+	return c
+}
+
+func (c *clientConn) readLoop() {
+	reader := bufio.NewReader(c.conn)
+	for {
+		data, err := readFrame(reader)
+		if err != nil {
+			c.close(err)
+			return
+		}
+
+		var resp rawResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			c.close(err)
+			return
+		}
+
+		c.mu.Lock()
+		ch := c.pending[resp.ID]
+		delete(c.pending, resp.ID)
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- &resp
+		}
+	}
+}
+
+// close closes the connection and fails all pending calls with the error
+func (c *clientConn) close(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	c.conn.Close()
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+}
+
+func (c *clientConn) broken() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *clientConn) call(ctx context.Context, route string, params []byte) (*rawResponse, error) {
+	id := atomic.AddUint64(&c.lastID, 1)
+	ch := make(chan *rawResponse, 1)
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	// the request which can't be encoded fails alone, other calls of the connection go on
+	frame, err := encodeFrame(&Request{ID: id, Route: route, Params: params})
+	if err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return nil, err
+	}
+	if err := c.writer.write(frame); err != nil {
+		c.close(err)
+		return nil, err
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return nil, c.broken()
+		}
+		return resp, nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		if frame, err := encodeFrame(&Request{ID: id, Type: FrameCancel}); err == nil {
+			if err := c.writer.write(frame); err != nil {
+				c.close(err)
+			}
+		}
+		return nil, ctx.Err()
+	}
+}
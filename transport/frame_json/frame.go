@@ -0,0 +1,105 @@
+// Package frame_json implements a lightweight transport over any stream connection (TCP, Unix socket). Every frame
+// is a 4-byte big-endian length followed by a JSON payload:
+//
+//	-> {"id": 1, "route": "/users/v1/", "params": {"id": 10}}
+//	-> {"id": 2, "route": "/orders/v1/", "params": {"user_id": 10}}
+//	<- {"id": 2, "result": "OK", "data": [...], "error": ""}
+//	<- {"id": 1, "result": "OK", "data": {...}, "error": ""}
+//
+// Calls are pipelined: the client doesn't wait for the response before sending the next request and the server
+// answers in the order of completion. A call can be canceled by the frame {"id": 1, "type": "cancel"}, all calls
+// are canceled when the connection is closed.
+package frame_json
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/sergei-svistunov/gorpc/transport/internal/multiplex"
+)
+
+// Types of the client's frames
+const (
+	FrameCall   = "call"
+	FrameCancel = "cancel"
+)
+
+// Error codes of the calls which failed outside of the handler, see also error codes of http_json
+const (
+	ErrorCanceled     = multiplex.ErrorCanceled
+	ErrorInvalidFrame = multiplex.ErrorInvalidFrame
+)
+
+// MaxFrameSize is the maximum size of the frame's payload, the connection is closed if the peer sends more
+const MaxFrameSize = 16 << 20
+
+// Request is the client's frame. Type is FrameCall if it's empty.
+type Request struct {
+	ID     uint64          `json:"id"`
+	Type   string          `json:"type,omitempty"`
+	Route  string          `json:"route,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is the server's frame with the result of the call, it has the same fields as http_json.HttpSessionResponse
+type Response struct {
+	ID     uint64      `json:"id"`
+	Result string      `json:"result"`
+	Data   interface{} `json:"data"`
+	Error  string      `json:"error"`
+}
+
+// ErrFrameTooLarge is returned if the payload of the frame exceeds MaxFrameSize, the frame isn't written
+var ErrFrameTooLarge = errors.New("frame_json: frame exceeds the size limit")
+
+// encodeFrame returns the frame with the payload, errors of encoding concern only this frame
+func encodeFrame(v interface{}) ([]byte, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) > MaxFrameSize {
+		return nil, fmt.Errorf("%w: size %d, limit %d", ErrFrameTooLarge, len(payload), MaxFrameSize)
+	}
+
+	frame := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	copy(frame[4:], payload)
+	return frame, nil
+}
+
+// frameWriter writes frames from many goroutines, its errors are errors of the connection
+type frameWriter struct {
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (w *frameWriter) write(frame []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := w.conn.Write(frame)
+	return err
+}
+
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > MaxFrameSize {
+		return nil, fmt.Errorf("frame size %d exceeds the limit %d", size, MaxFrameSize)
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
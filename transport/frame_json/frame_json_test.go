@@ -0,0 +1,301 @@
+package frame_json
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/sergei-svistunov/gorpc/transport/http_json"
+	"github.com/stretchr/testify/assert"
+
+	test_handler1 "github.com/sergei-svistunov/gorpc/test/handler1"
+	test_handler_behavior "github.com/sergei-svistunov/gorpc/test/handler_behavior"
+	test_handler_request_info "github.com/sergei-svistunov/gorpc/test/handler_request_info"
+)
+
+func newTestServer(t *testing.T, network, address string, interceptors ...gorpc.Interceptor) (*Server, net.Listener) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.Use(interceptors...)
+	if err := hm.RegisterHandlers(test_handler1.NewHandler(), test_handler_behavior.NewHandler(), test_handler_request_info.NewHandler()); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(hm, Callbacks{}).SetTimeout(500 * time.Millisecond)
+	go server.Serve(l)
+	return server, l
+}
+
+func TestFrameJSON_Call(t *testing.T) {
+	for _, network := range []string{"tcp", "unix"} {
+		address := "127.0.0.1:0"
+		if network == "unix" {
+			address = filepath.Join(t.TempDir(), "gorpc.sock")
+		}
+		server, l := newTestServer(t, network, address)
+		client := NewClient(network, l.Addr().String())
+
+		var res test_handler1.V1Res
+		if assert.NoError(t, client.Call(context.Background(), "/test/handler1/v1/", map[string]interface{}{"req_int": 2}, &res), network) {
+			assert.Equal(t, test_handler1.V1Res{String: "Test", Int: 2}, res, network)
+		}
+
+		err := client.Call(context.Background(), "/test/handler1/v2/", []byte(`{"req_int": 2, "error_id": 1}`), nil)
+		if assert.IsType(t, &CallError{}, err, network) {
+			assert.Equal(t, "ERROR_TYPE1", err.(*CallError).ErrorCode(), network)
+			assert.Equal(t, "Error 1 description", err.Error(), network)
+		}
+
+		err = client.Call(context.Background(), "/test/handler1/v1/", nil, nil)
+		if assert.IsType(t, &CallError{}, err, network) {
+			assert.Equal(t, http_json.ErrorInvalidParameters, err.(*CallError).ErrorCode(), network)
+			assert.Equal(t, gorpc.ParameterErrors{
+				{Path: "req_int", Code: gorpc.ParameterErrorRequired, Message: "Missed required field 'req_int'"},
+			}, err.(*CallError).ParameterErrors(), network)
+		}
+
+		err = client.Call(context.Background(), "/test/unknown/v1/", nil, nil)
+		if assert.IsType(t, &CallError{}, err, network) {
+			assert.Equal(t, http_json.ErrorNotFound, err.(*CallError).ErrorCode(), network)
+		}
+
+		client.Close()
+		server.Close()
+	}
+}
+
+func TestFrameJSON_Pipelining(t *testing.T) {
+	server, l := newTestServer(t, "tcp", "127.0.0.1:0")
+	defer server.Close()
+	client := NewClient("tcp", l.Addr().String()).SetPoolSize(1)
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	startTime := time.Now()
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var res test_handler_behavior.V1Res
+			if assert.NoError(t, client.Call(context.Background(), "/test/handler_behavior/v1/", map[string]interface{}{"value": i, "sleep": "100ms"}, &res)) {
+				assert.Equal(t, i, res.Value)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.True(t, time.Since(startTime) < 500*time.Millisecond, "calls in one connection must be concurrent")
+}
+
+func TestFrameJSON_Cancel(t *testing.T) {
+	canceled := make(chan error, 1)
+	server, l := newTestServer(t, "tcp", "127.0.0.1:0", func(ctx context.Context, handler gorpc.HandlerVersion, params reflect.Value, next gorpc.InterceptorNext) (interface{}, *gorpc.CallHandlerError) {
+		res, err := next(ctx, params)
+		canceled <- ctx.Err()
+		return res, err
+	})
+	defer server.Close()
+	client := NewClient("tcp", l.Addr().String())
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := client.Call(ctx, "/test/handler_behavior/v1/", map[string]interface{}{"value": 1, "sleep": "300ms"}, nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	select {
+	case err := <-canceled:
+		assert.Equal(t, context.Canceled, err)
+	case <-time.After(200 * time.Millisecond):
+		t.Error("Call must be canceled on the server")
+	}
+
+	// the connection is still usable
+	var res test_handler_behavior.V1Res
+	if assert.NoError(t, client.Call(context.Background(), "/test/handler_behavior/v1/", map[string]interface{}{"value": 5}, &res)) {
+		assert.Equal(t, 5, res.Value)
+	}
+}
+
+func TestFrameJSON_CancelWaitingCall(t *testing.T) {
+	called := make(chan int, 2)
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.Use(func(ctx context.Context, handler gorpc.HandlerVersion, params reflect.Value, next gorpc.InterceptorNext) (interface{}, *gorpc.CallHandlerError) {
+		called <- params.Elem().FieldByName("Value").Interface().(int)
+		return next(ctx, params)
+	})
+	hm.MustRegisterHandler(test_handler_behavior.NewHandler())
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(hm, Callbacks{}).SetMaxConcurrency(1)
+	go server.Serve(l)
+	defer server.Close()
+	client := NewClient("tcp", l.Addr().String()).SetPoolSize(1)
+	defer client.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Call(context.Background(), "/test/handler_behavior/v1/", map[string]interface{}{"value": 1, "sleep": "200ms"}, nil)
+	}()
+	assert.Equal(t, 1, <-called)
+
+	// the second call waits for the slot of the first one, but its cancel frame is read
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = client.Call(ctx, "/test/handler_behavior/v1/", map[string]interface{}{"value": 2}, nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	assert.NoError(t, <-done)
+	select {
+	case value := <-called:
+		t.Errorf("Canceled call with value %d must not be called", value)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestFrameJSON_InvalidFrame(t *testing.T) {
+	server, l := newTestServer(t, "tcp", "127.0.0.1:0")
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	w := frameWriter{conn: conn}
+	reader := bufio.NewReader(conn)
+	frame, err := encodeFrame(map[string]interface{}{"id": 1, "type": "unknown"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, w.write(frame))
+	data, err := readFrame(reader)
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `{"id": 1, "result": "ERROR", "data": "Unknown frame type unknown", "error": "INVALID_FRAME"}`, string(data))
+	}
+
+	// too large frame closes the connection
+	_, err = conn.Write([]byte{0xff, 0xff, 0xff, 0xff})
+	assert.NoError(t, err)
+	_, err = readFrame(reader)
+	assert.Error(t, err)
+}
+
+// largeHandler returns responses of the requested size, it's declared here because handlers in the test directory
+// can't import the transport
+type largeHandler struct{}
+
+func (*largeHandler) Caption() string {
+	return "Large handler"
+}
+
+func (*largeHandler) Description() string {
+	return "Handler which returns responses of the requested size"
+}
+
+type largeV1Args struct {
+	Size int `key:"size" description:"Size of the response"`
+}
+
+type largeV1Res struct {
+	Data string `json:"data" description:"Data of the requested size"`
+}
+
+func (*largeHandler) V1(ctx context.Context, opts *largeV1Args) (*largeV1Res, error) {
+	return &largeV1Res{Data: strings.Repeat("x", opts.Size)}, nil
+}
+
+func TestFrameJSON_LargeResponse(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(&largeHandler{})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan *gorpc.CallHandlerError, 1)
+	server := NewServer(hm, Callbacks{
+		OnError: func(ctx context.Context, conn net.Conn, route string, err *gorpc.CallHandlerError) {
+			errs <- err
+		},
+	})
+	go server.Serve(l)
+	defer server.Close()
+
+	client := NewClient("tcp", l.Addr().String())
+	defer client.Close()
+
+	// the response which exceeds the frame size fails alone
+	var res largeV1Res
+	err = client.Call(context.Background(), "/transport/frame_json/v1/", map[string]interface{}{"size": MaxFrameSize}, &res)
+	if callErr, ok := err.(*CallError); assert.True(t, ok, "%v", err) {
+		assert.Equal(t, http_json.ErrorInternal, callErr.Code)
+	}
+	select {
+	case err := <-errs:
+		assert.Equal(t, gorpc.ErrorWriteResponse, err.Type)
+		assert.True(t, errors.Is(err.Err, ErrFrameTooLarge))
+	case <-time.After(time.Second):
+		t.Fatal("OnError wasn't called")
+	}
+
+	// so does the request
+	err = client.Call(context.Background(), "/transport/frame_json/v1/", map[string]interface{}{"size": 1, "padding": strings.Repeat("x", MaxFrameSize)}, &res)
+	assert.True(t, errors.Is(err, ErrFrameTooLarge))
+
+	// the connection is still usable
+	if assert.NoError(t, client.Call(context.Background(), "/transport/frame_json/v1/", map[string]interface{}{"size": 3}, &res)) {
+		assert.Equal(t, "xxx", res.Data)
+	}
+}
+
+func TestFrameJSON_ServerClose(t *testing.T) {
+	server, l := newTestServer(t, "tcp", "127.0.0.1:0")
+	client := NewClient("tcp", l.Addr().String())
+	defer client.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Call(context.Background(), "/test/handler_behavior/v1/", map[string]interface{}{"value": 1, "sleep": "300ms"}, nil)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	assert.NoError(t, server.Close())
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Error("Call must fail when the server is closed")
+	}
+	assert.Equal(t, ErrServerClosed, server.Serve(l))
+}
+
+func TestFrameJSON_RequestInfo(t *testing.T) {
+	server, l := newTestServer(t, "tcp", "127.0.0.1:0")
+	defer server.Close()
+	client := NewClient("tcp", l.Addr().String())
+	defer client.Close()
+
+	var res test_handler_request_info.V1Res
+	if assert.NoError(t, client.Call(context.Background(), "/test/handler_request_info/v1/", nil, &res)) {
+		assert.Equal(t, TransportName, res.Transport)
+		assert.Equal(t, "/test/handler_request_info/v1/", res.Route)
+		assert.Equal(t, "v1", res.Version)
+		assert.NotEmpty(t, res.RemoteAddr)
+		assert.False(t, res.HTTP)
+	}
+}
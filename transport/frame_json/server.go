@@ -0,0 +1,268 @@
+package frame_json
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/sergei-svistunov/gorpc/transport/internal/multiplex"
+)
+
+const defaultMaxConcurrency = 16
+
+// TransportName marks calls received by Server
+const TransportName = "frame_json"
+
+// ErrServerClosed is returned by Serve after the call of Close
+var ErrServerClosed = errors.New("frame_json: Server closed")
+
+type Callbacks struct {
+	OnInitCtx    func(ctx context.Context, conn net.Conn) context.Context
+	OnConnect    func(ctx context.Context, conn net.Conn)
+	OnDisconnect func(ctx context.Context, conn net.Conn, startTime time.Time)
+	OnError      func(ctx context.Context, conn net.Conn, route string, err *gorpc.CallHandlerError)
+	OnPanic      func(ctx context.Context, r interface{}, trace []byte, conn net.Conn)
+	OnSuccess    func(ctx context.Context, conn net.Conn, route string, result interface{}, startTime time.Time)
+}
+
+type Server struct {
+	hm             *gorpc.HandlersManager
+	callbacks      Callbacks
+	timeout        time.Duration
+	maxConcurrency int
+
+	mu        sync.Mutex
+	closed    bool
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	wg        sync.WaitGroup
+}
+
+func NewServer(hm *gorpc.HandlersManager, callbacks Callbacks) *Server {
+	return &Server{
+		hm:             hm,
+		callbacks:      callbacks,
+		maxConcurrency: defaultMaxConcurrency,
+		listeners:      make(map[net.Listener]struct{}),
+		conns:          make(map[net.Conn]struct{}),
+	}
+}
+
+// SetTimeout sets the timeout of every call
+func (s *Server) SetTimeout(timeout time.Duration) *Server {
+	s.timeout = timeout
+	return s
+}
+
+// SetMaxConcurrency sets the maximum number of concurrent calls of one connection. Calls over the limit wait for
+// a free slot and can be canceled while waiting.
+func (s *Server) SetMaxConcurrency(concurrency int) *Server {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	s.maxConcurrency = concurrency
+	return s
+}
+
+// Serve accepts connections on the listener and serves each of them in a separate goroutine. It always returns
+// a non-nil error, ErrServerClosed after the call of Close.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+		l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return ErrServerClosed
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go func() {
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+				s.wg.Done()
+			}()
+			s.serveConn(conn)
+		}()
+	}
+}
+
+// Close closes all listeners and connections, cancels the calls in progress and waits for their end
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
+
+// connection contains the state of one client's connection
+type connection struct {
+	conn   net.Conn
+	writer frameWriter
+}
+
+// send writes the response. The response which can't be encoded, e.g. it's too large, is replaced with the internal
+// error, and its error is returned. The connection is closed on errors of writing.
+func (c *connection) send(id uint64, res multiplex.Result) error {
+	frame, err := encodeFrame(&Response{ID: id, Result: res.Result, Data: res.Data, Error: res.Error})
+	if err != nil {
+		errRes, callErr := multiplex.WriteErrorResult(err)
+		if frame, err = encodeFrame(&Response{ID: id, Result: errRes.Result, Data: errRes.Data, Error: errRes.Error}); err != nil {
+			c.conn.Close()
+			return err
+		}
+		err = callErr
+	}
+	if writeErr := c.writer.write(frame); writeErr != nil {
+		c.conn.Close()
+		return writeErr
+	}
+	return err
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	startTime := time.Now()
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if s.callbacks.OnInitCtx != nil {
+		ctx = s.callbacks.OnInitCtx(ctx, conn)
+	}
+
+	if s.callbacks.OnConnect != nil {
+		s.callbacks.OnConnect(ctx, conn)
+	}
+	defer func() {
+		if s.callbacks.OnDisconnect != nil {
+			s.callbacks.OnDisconnect(ctx, conn, startTime)
+		}
+	}()
+
+	c := &connection{
+		conn:   conn,
+		writer: frameWriter{conn: conn},
+	}
+	caller := s.newCaller(conn)
+	dispatcher := multiplex.NewDispatcher(s.maxConcurrency)
+	reader := bufio.NewReader(conn)
+
+	for {
+		data, err := readFrame(reader)
+		if err != nil {
+			break
+		}
+
+		var request Request
+		if err := json.Unmarshal(data, &request); err != nil {
+			c.send(request.ID, multiplex.ErrorResult(ErrorInvalidFrame, err.Error()))
+			continue
+		}
+		id := strconv.FormatUint(request.ID, 10)
+
+		switch request.Type {
+		case FrameCancel:
+			dispatcher.Cancel(id)
+			continue
+		case "", FrameCall:
+		default:
+			c.send(request.ID, multiplex.ErrorResult(ErrorInvalidFrame, "Unknown frame type "+request.Type))
+			continue
+		}
+
+		call := func(ctx context.Context) multiplex.Result {
+			return caller.Call(ctx, request.Route, request.Params)
+		}
+		reply := func(ctx context.Context, res multiplex.Result) {
+			if err := c.send(request.ID, res); err != nil {
+				callErr, ok := err.(*gorpc.CallHandlerError)
+				if !ok {
+					callErr = &gorpc.CallHandlerError{Type: gorpc.ErrorWriteResponse, Err: err}
+				}
+				caller.OnError(ctx, request.Route, callErr)
+			}
+		}
+		if !dispatcher.Go(ctx, id, call, reply) {
+			c.send(request.ID, multiplex.ErrorResult(ErrorInvalidFrame, "Call with id "+id+" is in progress"))
+		}
+	}
+
+	// the client has gone, so nobody waits for results
+	cancel()
+	dispatcher.Wait()
+}
+
+// newCaller returns the caller of handlers which passes the connection to the callbacks
+func (s *Server) newCaller(conn net.Conn) *multiplex.Caller {
+	callbacks := multiplex.Callbacks{
+		OnRequest: func(info *gorpc.IncomingRequest) {
+			info.RemoteAddr = conn.RemoteAddr().String()
+		},
+	}
+	if s.callbacks.OnError != nil {
+		callbacks.OnError = func(ctx context.Context, route string, err *gorpc.CallHandlerError) {
+			s.callbacks.OnError(ctx, conn, route, err)
+		}
+	}
+	if s.callbacks.OnPanic != nil {
+		callbacks.OnPanic = func(ctx context.Context, r interface{}, trace []byte) {
+			s.callbacks.OnPanic(ctx, r, trace, conn)
+		}
+	}
+	if s.callbacks.OnSuccess != nil {
+		callbacks.OnSuccess = func(ctx context.Context, route string, result interface{}, startTime time.Time) {
+			s.callbacks.OnSuccess(ctx, conn, route, result, startTime)
+		}
+	}
+
+	return &multiplex.Caller{
+		HM:            s.hm,
+		TransportName: TransportName,
+		Timeout:       s.timeout,
+		Callbacks:     callbacks,
+	}
+}
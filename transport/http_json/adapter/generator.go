@@ -15,6 +15,8 @@ import (
 	"sort"
 )
 
+var fileType = reflect.TypeOf(gorpc.File{})
+
 type HttpJsonLibGenerator struct {
 	hm               *gorpc.HandlersManager
 	pkgName          string
@@ -22,6 +24,7 @@ type HttpJsonLibGenerator struct {
 	collectedStructs map[string]struct{}
 	extraImports     map[string]struct{}
 	convertedStructs map[reflect.Type]string
+	cacheKeyTypes    map[string]struct{}
 }
 
 func NewHttpJsonLibGenerator(hm *gorpc.HandlersManager, packageName, serviceName string) *HttpJsonLibGenerator {
@@ -32,6 +35,7 @@ func NewHttpJsonLibGenerator(hm *gorpc.HandlersManager, packageName, serviceName
 		collectedStructs: map[string]struct{}{},
 		extraImports:     map[string]struct{}{},
 		convertedStructs: map[reflect.Type]string{},
+		cacheKeyTypes:    map[string]struct{}{},
 	}
 	if packageName != "" {
 		generator.pkgName = packageName
@@ -97,12 +101,31 @@ func (v byString) Less(i, j int) bool {
 func (g *HttpJsonLibGenerator) generateAPI() ([]byte, error) {
 	var result bytes.Buffer
 	var typesBuf bytes.Buffer
+	var hasUploads, hasRaw bool
 	paths := g.hm.GetHandlersPaths()
 	sort.Sort(byString(paths))
 	for _, path := range paths {
 		info := g.hm.GetHandlerInfo(path)
 		for _, v := range info.Versions {
-			inTypeName, outTypeName, err := g.printHandlerInOutTypes(&typesBuf, v.Request.Type, v.Response)
+			responseType, methodTemplate := v.Response, handlerCallPostFuncTemplate
+			if v.StreamItem != nil {
+				responseType, methodTemplate = v.StreamItem, handlerCallStreamFuncTemplate
+			} else if v.Request.Multipart {
+				methodTemplate = handlerCallUploadFuncTemplate
+				hasUploads = true
+			}
+
+			var inTypeName, outTypeName string
+			var err error
+			if v.Raw {
+				// RawResponse is declared by rawTemplate, raw handlers can't receive files
+				methodTemplate = handlerCallRawFuncTemplate
+				hasRaw = true
+				inTypeName, err = g.convertStructToCode(&typesBuf, v.Request.Type, true)
+				outTypeName = "*RawResponse"
+			} else {
+				inTypeName, outTypeName, err = g.printHandlerInOutTypes(&typesBuf, v.Request.Type, responseType)
+			}
 			if err != nil {
 				return nil, err
 			}
@@ -110,18 +133,35 @@ func (g *HttpJsonLibGenerator) generateAPI() ([]byte, error) {
 			handlerTypeName = strings.Replace(handlerTypeName, "_", "", -1)
 
 			errVarName := g.printHandlerMethodError(&typesBuf, handlerTypeName, v.Errors)
+			g.printCacheKeyParams(&typesBuf, inTypeName, &v)
+
+			cacheVary := "nil"
+			if v.CacheVary != nil && len(v.CacheVary.Headers) > 0 {
+				cacheVary = fmt.Sprintf("%#v", v.CacheVary.Headers)
+			}
 
-			method := regexp.MustCompilePOSIX(">>>HANDLER_PATH<<<").ReplaceAll(handlerCallPostFuncTemplate, []byte(v.Route))
+			method := regexp.MustCompilePOSIX(">>>HANDLER_PATH<<<").ReplaceAll(methodTemplate, []byte(v.Route))
 			method = regexp.MustCompilePOSIX(">>>HANDLER_NAME<<<").ReplaceAll(method, []byte(handlerTypeName))
 			method = regexp.MustCompilePOSIX(">>>INPUT_TYPE<<<").ReplaceAll(method, []byte(inTypeName))
 			method = regexp.MustCompilePOSIX(">>>RETURNED_TYPE<<<").ReplaceAll(method, []byte(outTypeName))
 			method = regexp.MustCompilePOSIX(">>>HANDLER_ERRORS<<<").ReplaceAll(method, []byte(errVarName))
+			method = regexp.MustCompilePOSIX(">>>CACHE_VARY<<<").ReplaceAll(method, []byte(cacheVary))
 			method = regexp.MustCompilePOSIX(">>>API_NAME<<<").ReplaceAll(method, []byte(GetAPIName(g.serviceName)))
 
 			result.Write(method)
 		}
 	}
 
+	if hasRaw {
+		result.Write(rawTemplate)
+	}
+	if hasUploads {
+		result.Write(regexp.MustCompilePOSIX(">>>API_NAME<<<").ReplaceAll(uploadTemplate, []byte(GetAPIName(g.serviceName))))
+		for _, _import := range uploadImports {
+			g.extraImports[_import] = struct{}{}
+		}
+	}
+
 	typesBuf.WriteTo(&result)
 
 	return result.Bytes(), nil
@@ -168,6 +208,32 @@ func (g *HttpJsonLibGenerator) printHandlerMethodError(w io.Writer, handlerTypeN
 	return "_" + handlerErrorsName + "Mapping"
 }
 
+// printCacheKeyParams declares the method which returns parameters without ones excluded from the cache key by
+// "cache" tag, getCacheKey encodes its result instead of parameters
+func (g *HttpJsonLibGenerator) printCacheKeyParams(w io.Writer, typeName string, handler gorpc.HandlerVersion) {
+	if _, ok := g.cacheKeyTypes[typeName]; ok || strings.Contains(typeName, ".") {
+		return
+	}
+	var excluded bool
+	for _, field := range handler.Request.Fields {
+		excluded = excluded || field.NoCache
+	}
+	if !excluded {
+		return
+	}
+	g.cacheKeyTypes[typeName] = struct{}{}
+
+	fmt.Fprintf(w, "func (p %s) cacheKeyParams() interface{} {\n", typeName)
+	fmt.Fprintf(w, "return %s{\n", typeName)
+	for i, field := range handler.Request.Fields {
+		if !field.NoCache {
+			name := handler.Request.Type.Field(i).Name
+			fmt.Fprintf(w, "%s: p.%s,\n", name, name)
+		}
+	}
+	fmt.Fprint(w, "}\n}\n\n")
+}
+
 func (g *HttpJsonLibGenerator) needToMigratePkgStructs(pkgPath string) bool {
 	// TODO this check was removed and all types with non-empty package path will be migrated in library code
 	return pkgPath != ""
@@ -181,6 +247,11 @@ func (g *HttpJsonLibGenerator) convertStructToCode(w io.Writer, t reflect.Type,
 		g.convertedStructs[t] = typeName
 	}()
 
+	if t == fileType {
+		// File is declared by uploadTemplate
+		return "File", nil
+	}
+
 	// ignore slice of new types because this type exactly new and we're collecting its content right now below
 	typeName, _ = g.detectTypeName(t)
 	if strings.Contains(typeName, ".") {
@@ -226,6 +297,9 @@ func (g *HttpJsonLibGenerator) convertStructToCode(w io.Writer, t reflect.Type,
 				if jsonTag != "" {
 					str += (" `json:\"" + jsonTag + "\"`")
 				}
+				if defaultValue, ok := field.Tag.Lookup("default"); ok {
+					str += " // Default: " + defaultValue
+				}
 			}
 
 			str += "\n"
@@ -251,6 +325,17 @@ func (g *HttpJsonLibGenerator) convertStructToCode(w io.Writer, t reflect.Type,
 			writeType(w, typeName, sliceType)
 		}
 
+		return
+	case reflect.Array:
+		var elemType string
+		elemType, err = g.convertStructToCode(w, t.Elem(), false)
+		if err != nil {
+			return
+		}
+		arrayType := fmt.Sprintf("[%d]%s", t.Len(), elemType)
+		if typeName != arrayType {
+			writeType(w, typeName, arrayType)
+		}
 		return
 	case reflect.Map:
 		keyType, _ := g.convertStructToCode(w, t.Key(), false)
@@ -298,6 +383,13 @@ func (g *HttpJsonLibGenerator) detectTypeName(t reflect.Type) (name string, newT
 		}
 	}()
 	name = t.Name()
+	if t == fileType {
+		return "File", nil
+	}
+	if t.PkgPath() == "time" {
+		// time.Time and time.Duration are used as is, "time" is always in mainImports
+		return t.String(), nil
+	}
 	if name != "" {
 		// for custom types make unique names using package path
 		// because different packages can contains structs with same names
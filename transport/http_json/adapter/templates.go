@@ -1,9 +1,11 @@
 package adapter
 
 var mainImports = []string{
+	"bufio",
 	"bytes",
 	"encoding/json",
 	"fmt",
+	"io",
 	"io/ioutil",
 	"net/http",
 	"net/url",
@@ -27,6 +29,14 @@ type IBalancer interface {
     Next() (string, error)
 }
 
+// IRawTransport calls handlers without HTTP, e.g. frame_json.Client. It returns the data of the response and
+// the error code if the handler returned an error.
+type IRawTransport interface {
+	CallRaw(ctx context.Context, route string, params []byte) (data []byte, errCode string, err error)
+}
+
+// Callbacks are called on every request. Their req is nil until the request is created, requests made through
+// IRawTransport are synthesized with the route as URL and the headers of the context, they aren't sent.
 type Callbacks struct {
 	OnStart                func(ctx context.Context, req *http.Request) context.Context
 	OnPrepareRequest       func(ctx context.Context, req *http.Request, data interface{}) context.Context
@@ -43,6 +53,7 @@ type >>>API_NAME<<< struct {
 	balancer    IBalancer
 	callbacks   Callbacks
 	cache       cache.ICache
+	transport   IRawTransport
 }
 
 func (api *>>>API_NAME<<<) SetCache(c cache.ICache) *>>>API_NAME<<< {
@@ -50,6 +61,13 @@ func (api *>>>API_NAME<<<) SetCache(c cache.ICache) *>>>API_NAME<<< {
 	return api
 }
 
+// SetRawTransport makes the client to send all requests through the transport instead of HTTP. Handlers with
+// streamed responses are always called over HTTP.
+func (api *>>>API_NAME<<<) SetRawTransport(t IRawTransport) *>>>API_NAME<<< {
+	api.transport = t
+	return api
+}
+
 func New>>>API_NAME<<<(client *http.Client, balancer IBalancer, callbacks Callbacks) *>>>API_NAME<<< {
 	if client == nil {
 		client = http.DefaultClient
@@ -114,15 +132,6 @@ func (api *>>>API_NAME<<<) set(ctx context.Context, path string, data interface{
 		}
 	}()
 
-	apiURL, err = api.balancer.Next()
-	if err != nil {
-		err = fmt.Errorf("could not locate service '%s': %v", api.serviceName, err)
-		if api.callbacks.OnError != nil {
-			err = api.callbacks.OnError(ctx, req, err)
-		}
-		return err
-	}
-
 	b := bytes.NewBuffer(nil)
 	if m, ok := data.(easyjson.Marshaler); ok {
 		_, err = easyjson.MarshalToWriter(m, b)
@@ -138,6 +147,34 @@ func (api *>>>API_NAME<<<) set(ctx context.Context, path string, data interface{
 		return err
 	}
 
+	if api.transport != nil {
+		req = newRawRequest(ctx, path)
+		if api.callbacks.OnPrepareRequest != nil {
+			ctx = api.callbacks.OnPrepareRequest(ctx, req, data)
+		}
+
+		if err := api.doRawRequest(ctx, path, b.Bytes(), buf, handlerErrors); err != nil {
+			if api.callbacks.OnError != nil {
+				err = api.callbacks.OnError(ctx, req, err)
+			}
+			return err
+		}
+
+		if api.callbacks.OnSuccess != nil {
+			api.callbacks.OnSuccess(ctx, req, buf)
+		}
+		return nil
+	}
+
+	apiURL, err = api.balancer.Next()
+	if err != nil {
+		err = fmt.Errorf("could not locate service '%s': %v", api.serviceName, err)
+		if api.callbacks.OnError != nil {
+			err = api.callbacks.OnError(ctx, req, err)
+		}
+		return err
+	}
+
 	req, err = http.NewRequest("POST", createRawURL(apiURL, path, nil), b)
 	if err != nil {
 		if api.callbacks.OnError != nil {
@@ -146,6 +183,7 @@ func (api *>>>API_NAME<<<) set(ctx context.Context, path string, data interface{
 		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	setRequestHeaders(ctx, req)
 	if api.callbacks.OnPrepareRequest != nil {
 		ctx = api.callbacks.OnPrepareRequest(ctx, req, data)
 	}
@@ -164,9 +202,9 @@ func (api *>>>API_NAME<<<) set(ctx context.Context, path string, data interface{
 	return nil
 }
 
-func (api *>>>API_NAME<<<) setWithCache(ctx context.Context, path string, data interface{}, entry *cache.CacheEntry, handlerErrors map[string]int) error {
+func (api *>>>API_NAME<<<) setWithCache(ctx context.Context, path string, data interface{}, varyHeaders []string, entry *cache.CacheEntry, handlerErrors map[string]int) error {
 	if api.cache != nil && cache.IsTransportCacheEnabled(ctx) {
-		cacheKey := getCacheKey(path, data)
+		cacheKey := getCacheKey(ctx, path, data, varyHeaders)
 		if cacheKey != nil {
 			api.cache.Lock(cacheKey)
 			defer api.cache.Unlock(cacheKey)
@@ -214,6 +252,9 @@ func (api *>>>API_NAME<<<) doRequest(ctx context.Context, request *http.Request,
 
 		// Handle error
 		if response.StatusCode != http.StatusOK {
+			if err := statusServiceError(response, handlerErrors); err != nil {
+				return err
+			}
 			switch response.StatusCode {
 			// TODO separate error types for different status codes (and different callbacks)
 			/*
@@ -236,6 +277,13 @@ func (api *>>>API_NAME<<<) doRequest(ctx context.Context, request *http.Request,
 			api.callbacks.OnResponseUnmarshaling(ctx, request, response, result)
 		}
 
+		// raw responses aren't wrapped into the envelope, only errors are
+		if raw, ok := buf.(interface {
+			setRaw(*http.Response, []byte) bool
+		}); ok && raw.setRaw(response, result) {
+			return nil
+		}
+
 		var mainResp httpSessionResponse
 		if err := unmarshal(result, &mainResp); err != nil {
 			return fmt.Errorf("request %q failed to decode response %q: %v", request.URL.RequestURI(), string(result), err)
@@ -261,6 +309,136 @@ func (api *>>>API_NAME<<<) doRequest(ctx context.Context, request *http.Request,
 	})
 }
 
+// newRawRequest returns the request passed to callbacks instead of the HTTP one when the handler is called through
+// IRawTransport
+func newRawRequest(ctx context.Context, path string) *http.Request {
+	req := &http.Request{
+		Method: "POST",
+		URL:    &url.URL{Path: path},
+		Header: http.Header{"Content-Type": {"application/json"}},
+	}
+	setRequestHeaders(ctx, req)
+	return req.WithContext(ctx)
+}
+
+func (api *>>>API_NAME<<<) doRawRequest(ctx context.Context, path string, data []byte, buf interface{}, handlerErrors map[string]int) error {
+	result, errCode, err := api.transport.CallRaw(ctx, path, data)
+	if err != nil {
+		return err
+	}
+
+	if errCode == "" {
+		if err := unmarshal(result, buf); err != nil {
+			return fmt.Errorf("request %q failed to decode response data %q: %v", path, string(result), err)
+		}
+		return nil
+	}
+
+	if code, ok := handlerErrors[errCode]; ok {
+		return &ServiceError{
+			Code:    code,
+			Message: errCode,
+		}
+	}
+
+	return fmt.Errorf("request %q returned error %s: %s", path, errCode, string(result))
+}
+
+// stream calls the handler with streamed response and passes data of every item to fn. The stream is read until
+// its end, an error item, an error of fn or the end of ctx.
+func (api *>>>API_NAME<<<) stream(ctx context.Context, path string, data interface{}, handlerErrors map[string]int, fn func(data []byte) error) (err error) {
+	startTime := time.Now()
+
+	var req *http.Request
+
+	if api.callbacks.OnStart != nil {
+		ctx = api.callbacks.OnStart(ctx, req)
+	}
+
+	defer func() {
+		if api.callbacks.OnFinish != nil {
+			api.callbacks.OnFinish(ctx, req, startTime)
+		}
+		if err != nil && api.callbacks.OnError != nil {
+			err = api.callbacks.OnError(ctx, req, err)
+		}
+	}()
+
+	apiURL, err := api.balancer.Next()
+	if err != nil {
+		return fmt.Errorf("could not locate service '%s': %v", api.serviceName, err)
+	}
+
+	b := bytes.NewBuffer(nil)
+	if m, ok := data.(easyjson.Marshaler); ok {
+		_, err = easyjson.MarshalToWriter(m, b)
+	} else {
+		err = json.NewEncoder(b).Encode(data)
+	}
+	if err != nil {
+		return fmt.Errorf("could not marshal data %+v: %v", data, err)
+	}
+
+	req, err = http.NewRequest("POST", createRawURL(apiURL, path, nil), b)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/x-ndjson")
+	setRequestHeaders(ctx, req)
+	if api.callbacks.OnPrepareRequest != nil {
+		ctx = api.callbacks.OnPrepareRequest(ctx, req, data)
+	}
+
+	response, err := api.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		if err := statusServiceError(response, handlerErrors); err != nil {
+			return err
+		}
+		return fmt.Errorf("Request %q failed. Server returns status code %d", req.URL.RequestURI(), response.StatusCode)
+	}
+
+	// every line is a separate response, errors before the stream are returned as one response without a new line
+	reader := bufio.NewReader(response.Body)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var item httpSessionResponse
+			if err := unmarshal(line, &item); err != nil {
+				return fmt.Errorf("request %q failed to decode stream item %q: %v", req.URL.RequestURI(), string(line), err)
+			}
+			if item.Result != "OK" {
+				if errCode, ok := handlerErrors[item.Error]; ok {
+					return &ServiceError{
+						Code:    errCode,
+						Message: item.Error,
+					}
+				}
+				return fmt.Errorf("request %q returned error %s: %s", req.URL.RequestURI(), item.Error, string(item.Data))
+			}
+			if err := fn(item.Data); err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+
+	if api.callbacks.OnSuccess != nil {
+		api.callbacks.OnSuccess(ctx, req, nil)
+	}
+	return nil
+}
+
 // HTTPDo is taken and adapted from https://blog.golang.org/context
 func HTTPDo(ctx context.Context, client *http.Client, req *http.Request, f func(*http.Response, error) error) error {
 	c := make(chan error, 1)
@@ -286,6 +464,8 @@ type canceler interface {
 type ServiceError struct {
 	Code    int
 	Message string
+	// HTTPStatus is the status of the response if the service sets it for the error
+	HTTPStatus int
 }
 
 // Error method for implementing common error interface
@@ -293,7 +473,59 @@ func (err *ServiceError) Error() string {
 	return err.Message
 }
 
-func getCacheKey(route string, params interface{}) []byte {
+// statusServiceError returns the error of the handler written with not 200 OK status or nil if the response isn't
+// the envelope with the known error
+func statusServiceError(response *http.Response, handlerErrors map[string]int) error {
+	if !strings.HasPrefix(response.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+	result, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil
+	}
+
+	var mainResp httpSessionResponse
+	if err := unmarshal(result, &mainResp); err != nil || mainResp.Result != "ERROR" {
+		return nil
+	}
+	if errCode, ok := handlerErrors[mainResp.Error]; ok {
+		return &ServiceError{
+			Code:       errCode,
+			Message:    mainResp.Error,
+			HTTPStatus: response.StatusCode,
+		}
+	}
+	return nil
+}
+
+type requestHeadersContextKey struct{}
+
+// WithRequestHeader returns the context with the header which is sent with requests made with it. Handlers' cached
+// responses vary on such headers in the client's cache as well as on the server, headers set by OnPrepareRequest
+// aren't parts of the cache key.
+func WithRequestHeader(ctx context.Context, key, value string) context.Context {
+	header := http.Header{}
+	if h, ok := ctx.Value(requestHeadersContextKey{}).(http.Header); ok {
+		header = h.Clone()
+	}
+	header.Set(key, value)
+	return context.WithValue(ctx, requestHeadersContextKey{}, header)
+}
+
+func setRequestHeaders(ctx context.Context, req *http.Request) {
+	if h, ok := ctx.Value(requestHeadersContextKey{}).(http.Header); ok {
+		for k, v := range h {
+			req.Header[k] = v
+		}
+	}
+}
+
+// getCacheKey returns the key of the handler's response, parameters excluded by the handler aren't its part and
+// values of headers which the handler varies on are
+func getCacheKey(ctx context.Context, route string, params interface{}, varyHeaders []string) []byte {
+	if p, ok := params.(interface{ cacheKeyParams() interface{} }); ok {
+		params = p.cacheKeyParams()
+	}
 	buf := bytes.NewBufferString(route)
 	var err error
 	if m, ok := params.(easyjson.Marshaler); ok {
@@ -305,6 +537,10 @@ func getCacheKey(route string, params interface{}) []byte {
 	if err != nil {
 		return nil
 	}
+	header, _ := ctx.Value(requestHeadersContextKey{}).(http.Header)
+	for _, name := range varyHeaders {
+		fmt.Fprintf(buf, "\n%s:%s", http.CanonicalHeaderKey(name), strings.Join(header.Values(name), ","))
+	}
 	return buf.Bytes()
 }
 `)
@@ -313,10 +549,283 @@ var handlerCallPostFuncTemplate = []byte(`
 func (api *>>>API_NAME<<<) >>>HANDLER_NAME<<<(ctx context.Context, options >>>INPUT_TYPE<<<) (>>>RETURNED_TYPE<<<, error) {
 	var result >>>RETURNED_TYPE<<<
 	var entry = cache.CacheEntry{Body: &result}
-	err := api.setWithCache(ctx, ">>>HANDLER_PATH<<<", options, &entry, >>>HANDLER_ERRORS<<<)
+	err := api.setWithCache(ctx, ">>>HANDLER_PATH<<<", options, >>>CACHE_VARY<<<, &entry, >>>HANDLER_ERRORS<<<)
 	if result, ok := entry.Body.(*>>>RETURNED_TYPE<<<); ok {
 		return *result, err
 	}
 	return result, err
 }
 `)
+
+var handlerCallStreamFuncTemplate = []byte(`
+func (api *>>>API_NAME<<<) >>>HANDLER_NAME<<<(ctx context.Context, options >>>INPUT_TYPE<<<, fn func(item >>>RETURNED_TYPE<<<) error) error {
+	return api.stream(ctx, ">>>HANDLER_PATH<<<", options, >>>HANDLER_ERRORS<<<, func(data []byte) error {
+		var item >>>RETURNED_TYPE<<<
+		if err := unmarshal(data, &item); err != nil {
+			return err
+		}
+		return fn(item)
+	})
+}
+`)
+
+var handlerCallUploadFuncTemplate = []byte(`
+func (api *>>>API_NAME<<<) >>>HANDLER_NAME<<<(ctx context.Context, options >>>INPUT_TYPE<<<) (>>>RETURNED_TYPE<<<, error) {
+	var result >>>RETURNED_TYPE<<<
+	err := api.upload(ctx, ">>>HANDLER_PATH<<<", options, &result, >>>HANDLER_ERRORS<<<)
+	return result, err
+}
+`)
+
+var handlerCallRawFuncTemplate = []byte(`
+func (api *>>>API_NAME<<<) >>>HANDLER_NAME<<<(ctx context.Context, options >>>INPUT_TYPE<<<) (>>>RETURNED_TYPE<<<, error) {
+	var entry = cache.CacheEntry{Body: &RawResponse{}}
+	err := api.setWithCache(ctx, ">>>HANDLER_PATH<<<", options, >>>CACHE_VARY<<<, &entry, >>>HANDLER_ERRORS<<<)
+	result, _ := entry.Body.(*RawResponse)
+	return result, err
+}
+`)
+
+// rawTemplate is added if some handlers return raw responses
+var rawTemplate = []byte(`
+// RawResponse is the content returned by the handler as is
+type RawResponse struct {
+	ContentType string      ` + "`" + `json:"content_type"` + "`" + `
+	Header      http.Header ` + "`" + `json:"header,omitempty"` + "`" + `
+	Body        []byte      ` + "`" + `json:"body"` + "`" + `
+}
+
+// setRaw sets the content of the HTTP response, the response is declined if it's the JSON envelope with the error
+func (r *RawResponse) setRaw(response *http.Response, body []byte) bool {
+	contentType := response.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "application/json") {
+		var mainResp httpSessionResponse
+		if err := json.Unmarshal(body, &mainResp); err == nil && mainResp.Result == "ERROR" {
+			return false
+		}
+	}
+
+	r.ContentType = contentType
+	r.Header = response.Header
+	r.Body = body
+	return true
+}
+`)
+
+// uploadImports are imported by uploadTemplate
+var uploadImports = []string{
+	"mime/multipart",
+	"net/textproto",
+	"reflect",
+	"sort",
+	"strconv",
+}
+
+// uploadTemplate is added if some handlers have file parameters
+var uploadTemplate = []byte(`
+// File is a file uploaded in multipart form
+type File struct {
+	Name        string
+	ContentType string
+	Content     io.Reader
+}
+
+// MarshalJSON omits files from JSON, they are sent as separate parts of multipart form
+func (File) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+// upload calls the handler with file parameters, other parameters are sent as fields of multipart form in brackets
+// notation. Such calls aren't cached.
+func (api *>>>API_NAME<<<) upload(ctx context.Context, path string, data interface{}, buf interface{}, handlerErrors map[string]int) (err error) {
+	startTime := time.Now()
+
+	var apiURL string
+	var req *http.Request
+
+	if api.callbacks.OnStart != nil {
+		ctx = api.callbacks.OnStart(ctx, req)
+	}
+
+	defer func() {
+		if api.callbacks.OnFinish != nil {
+			api.callbacks.OnFinish(ctx, req, startTime)
+		}
+
+		if r := recover(); r != nil {
+			const size = 64 << 10
+			buf := make([]byte, size)
+			n := runtime.Stack(buf, false)
+			trace := buf[:n]
+
+			err = fmt.Errorf("panic while calling %q service: %v", api.serviceName, r)
+			if api.callbacks.OnPanic != nil {
+				err = api.callbacks.OnPanic(ctx, req, r, trace)
+			}
+		}
+	}()
+
+	if api.transport != nil {
+		err = fmt.Errorf("files can't be uploaded to %q through the raw transport", path)
+		if api.callbacks.OnError != nil {
+			err = api.callbacks.OnError(ctx, req, err)
+		}
+		return err
+	}
+
+	b := bytes.NewBuffer(nil)
+	writer := multipart.NewWriter(b)
+	if err = writeMultipart(writer, data); err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		err = fmt.Errorf("could not marshal data %+v: %v", data, err)
+		if api.callbacks.OnError != nil {
+			err = api.callbacks.OnError(ctx, req, err)
+		}
+		return err
+	}
+
+	apiURL, err = api.balancer.Next()
+	if err != nil {
+		err = fmt.Errorf("could not locate service '%s': %v", api.serviceName, err)
+		if api.callbacks.OnError != nil {
+			err = api.callbacks.OnError(ctx, req, err)
+		}
+		return err
+	}
+
+	req, err = http.NewRequest("POST", createRawURL(apiURL, path, nil), b)
+	if err != nil {
+		if api.callbacks.OnError != nil {
+			err = api.callbacks.OnError(ctx, req, err)
+		}
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	setRequestHeaders(ctx, req)
+	if api.callbacks.OnPrepareRequest != nil {
+		ctx = api.callbacks.OnPrepareRequest(ctx, req, data)
+	}
+
+	if err := api.doRequest(ctx, req, buf, handlerErrors); err != nil {
+		if api.callbacks.OnError != nil {
+			err = api.callbacks.OnError(ctx, req, err)
+		}
+		return err
+	}
+
+	if api.callbacks.OnSuccess != nil {
+		api.callbacks.OnSuccess(ctx, req, buf)
+	}
+
+	return nil
+}
+
+// writeMultipart writes files of data as file parts and other fields encoded to JSON as form fields in brackets
+// notation: {"filter": {"ids": [1, 2]}} is written as "filter[ids][0]=1" and "filter[ids][1]=2"
+func writeMultipart(writer *multipart.Writer, data interface{}) error {
+	if err := writeMultipartFiles(writer, "", reflect.ValueOf(data)); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	var values interface{}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return err
+	}
+	return writeMultipartValue(writer, "", values)
+}
+
+func writeMultipartValue(writer *multipart.Writer, key string, value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := writeMultipartValue(writer, multipartKey(key, k), value[k]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []interface{}:
+		for i, item := range value {
+			if err := writeMultipartValue(writer, multipartKey(key, strconv.Itoa(i)), item); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return writer.WriteField(key, fmt.Sprint(value))
+	}
+}
+
+var fileType = reflect.TypeOf(File{})
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"")
+
+// writeMultipartFiles writes files found in fields of v, all files of a slice are written with the same key
+func writeMultipartFiles(writer *multipart.Writer, key string, v reflect.Value) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Type() == fileType:
+		file := v.Interface().(File)
+		contentType := file.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf("form-data; name=\"%s\"; filename=\"%s\"",
+			quoteEscaper.Replace(key), quoteEscaper.Replace(file.Name)))
+		header.Set("Content-Type", contentType)
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		if file.Content != nil {
+			_, err = io.Copy(part, file.Content)
+		}
+		return err
+	case v.Kind() == reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := writeMultipartFiles(writer, key, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case v.Kind() == reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			name := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			if err := writeMultipartFiles(writer, multipartKey(key, name), v.Field(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func multipartKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "[" + key + "]"
+}
+`)
@@ -0,0 +1,303 @@
+package http_json
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/sergei-svistunov/gorpc/transport/cache"
+)
+
+const defaultBatchConcurrency = 8
+
+// Error codes of batch items which failed before or outside of the handler
+const (
+	ErrorNotFound = gorpc.ErrorCodeNotFound
+	ErrorTimeout  = gorpc.ErrorCodeTimeout
+	ErrorInternal = gorpc.ErrorCodeInternal
+)
+
+// BatchRequestItem is one call of the batch request:
+//
+//	[{"route": "/users/v1/", "params": {"id": 1}}, {"route": "/orders/v2/", "params": {"user_id": 1}}]
+type BatchRequestItem struct {
+	Route  string          `json:"route"`
+	Params json.RawMessage `json:"params"`
+}
+
+// SetBatchPath enables the batch endpoint on the path. It accepts POST requests with JSON array of BatchRequestItem
+// and returns JSON array of HttpSessionResponse in the same order. Every item passes through the cache and timeout
+// like a separate request, errors of items are returned in their responses. Callbacks of an item receive the batch
+// request with the path of the item's route. Headers set by handlers of items are written with the batch response,
+// see mergeBatchHeaders.
+func (h *APIHandler) SetBatchPath(path string) *APIHandler {
+	h.batchPath = path
+	return h
+}
+
+// SetBatchConcurrency sets the maximum number of items of one batch request executed concurrently
+func (h *APIHandler) SetBatchConcurrency(concurrency int) *APIHandler {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	h.batchConcurrency = concurrency
+	return h
+}
+
+func (h *APIHandler) serveBatch(w http.ResponseWriter, req *http.Request, startTime time.Time) {
+	ctx := req.Context()
+	if h.callbacks.OnInitCtx != nil {
+		ctx = h.callbacks.OnInitCtx(ctx, req)
+	}
+
+	if h.callbacks.OnStartServing != nil {
+		h.callbacks.OnStartServing(ctx, req)
+	}
+
+	defer func() {
+		if h.callbacks.OnEndServing != nil {
+			h.callbacks.OnEndServing(ctx, req, startTime)
+		}
+	}()
+
+	if req.Method != "POST" {
+		if h.callbacks.OnError != nil {
+			err := &gorpc.CallHandlerError{
+				Type: gorpc.ErrorInvalidMethod,
+				Err:  errors.New("Invalid method"),
+			}
+			h.callbacks.OnError(ctx, w, req, nil, err)
+		}
+		h.writeError(ctx, w, "", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// query parameters (e.g. "debug") are read by items concurrently, so the form is parsed beforehand
+	var items []BatchRequestItem
+	err := req.ParseForm()
+	if err == nil {
+		err = json.NewDecoder(http.MaxBytesReader(w, req.Body, defaultMaxFormSize)).Decode(&items)
+	}
+	if err != nil {
+		if h.callbacks.OnError != nil {
+			h.callbacks.OnError(ctx, w, req, nil, &gorpc.CallHandlerError{
+				Type: gorpc.ErrorInParameters,
+				Err:  err,
+			})
+		}
+		h.writeError(ctx, w, "Invalid batch request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responses := make([][]byte, len(items))
+	hashes := make([]string, len(items))
+	headers := make([]http.Header, len(items))
+	semaphore := make(chan struct{}, h.batchConcurrency)
+	var wg sync.WaitGroup
+	for i := range items {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			responses[i], hashes[i], headers[i] = h.callBatchItem(ctx, w, req, &items[i], startTime)
+		}(i)
+	}
+	wg.Wait()
+
+	if h.callbacks.OnBeforeWriteResponse != nil {
+		h.callbacks.OnBeforeWriteResponse(ctx, w)
+	}
+
+	writeHeaders(w, mergeBatchHeaders(headers))
+
+	// ETag of the batch is built from ETags of all items, so it's available only if all items have it
+	etag := ""
+	for _, hash := range hashes {
+		if hash == "" {
+			etag = ""
+			break
+		}
+		etag += hash
+	}
+	if etag != "" {
+		etag, _ = cache.ETagHash(etag)
+		w.Header().Set("Etag", formatETag(etag, false))
+		if etagMatches(strings.Join(req.Header.Values("If-None-Match"), ","), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			if h.callbacks.On304 != nil {
+				h.callbacks.On304(ctx, req)
+			}
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	buf := bytes.NewBuffer(make([]byte, 0, 1024))
+	buf.WriteByte('[')
+	for i, resp := range responses {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(resp)
+	}
+	buf.WriteByte(']')
+
+	if _, err := w.Write(buf.Bytes()); err != nil && h.callbacks.OnError != nil {
+		h.callbacks.OnError(ctx, w, req, nil, &gorpc.CallHandlerError{
+			Type: gorpc.ErrorWriteResponse,
+			Err:  err,
+		})
+	}
+}
+
+// callBatchItem executes one item of the batch and returns its marshaled response, ETag hash (if enabled) and headers
+// set by the handler
+func (h *APIHandler) callBatchItem(ctx context.Context, w http.ResponseWriter, req *http.Request, item *BatchRequestItem,
+	startTime time.Time) ([]byte, string, http.Header) {
+
+	// every item has its own cache settings and headers
+	ctx = withResponseHeaders(cache.NewContext(ctx))
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+	req = batchItemRequest(ctx, req, item)
+
+	var resp HttpSessionResponse
+
+	handler := h.hm.FindHandlerByRoute(item.Route)
+	if handler == nil {
+		if h.callbacks.OnError != nil {
+			h.callbacks.OnError(ctx, w, req, nil, &gorpc.CallHandlerError{
+				Type: gorpc.ErrorNotFound,
+				Err:  errors.New("Handler with route " + item.Route + " is not found"),
+			})
+		}
+		return batchItemError(ErrorNotFound, http.StatusText(http.StatusNotFound)), "", nil
+	}
+	if handler.StreamItem != nil {
+		return batchItemError(ErrorStreamNotSupported, "Streamed response can't be a part of the batch"), "", nil
+	}
+	if handler.Raw {
+		return batchItemError(ErrorRawNotSupported, "Raw response can't be a part of the batch"), "", nil
+	}
+	ctx = withRequestInfo(ctx, req, handler)
+
+	params := item.Params
+	if len(params) == 0 || string(params) == "null" {
+		params = json.RawMessage("{}")
+	}
+	paramsValue, err := h.hm.UnmarshalParameters(ctx, handler, &JsonParametersGetter{Req: ioutil.NopCloser(bytes.NewReader(params))})
+	if err != nil {
+		callErr, ok := err.(*gorpc.CallHandlerError)
+		if !ok {
+			callErr = &gorpc.CallHandlerError{Type: gorpc.ErrorInParameters, Err: err}
+		}
+		if h.callbacks.OnError != nil {
+			h.callbacks.OnError(ctx, w, req, resp, callErr)
+		}
+		if paramErrs := callErr.ParameterErrors(); paramErrs != nil {
+			return marshalBatchItem(&HttpSessionResponse{Result: "ERROR", Data: paramErrs, Error: ErrorInvalidParameters}), "", nil
+		}
+		return batchItemError(ErrorInvalidParameters, callErr.Error()), "", nil
+	}
+
+	cacheEntry, callErr := h.callWithTimeout(ctx, w, req, &resp, handler, paramsValue)
+	if ctx.Err() == context.DeadlineExceeded {
+		if h.callbacks.OnError != nil {
+			h.callbacks.OnError(ctx, w, req, nil, &gorpc.CallHandlerError{
+				Type: gorpc.ErrorReturnedFromCall,
+				Err:  errors.New("Request timed out"),
+			})
+		}
+		return batchItemError(ErrorTimeout, "Request timed out"), "", nil
+	}
+	if callErr != nil {
+		if callErr.Type != gorpc.ErrorPanic && h.callbacks.OnError != nil {
+			h.callbacks.OnError(ctx, w, req, &resp, callErr)
+		}
+		if callErr.Type == gorpc.ErrorReturnedFromCall {
+			return marshalBatchItem(&resp), "", responseHeadersFromContext(ctx)
+		}
+		code, data := callErr.CodeAndData(PrintDebug)
+		return marshalBatchItem(&HttpSessionResponse{Result: "ERROR", Data: data, Error: code}), "", nil
+	}
+
+	if h.callbacks.OnSuccess != nil {
+		h.callbacks.OnSuccess(ctx, req, &resp, startTime)
+	}
+
+	if cacheEntry == nil {
+		return marshalBatchItem(&resp), "", responseHeadersFromContext(ctx)
+	}
+	if cacheEntry.Content == nil && cacheEntry.CompressedContent != nil {
+		if gzipReader, err := gzip.NewReader(bytes.NewReader(cacheEntry.CompressedContent)); err == nil {
+			content, err := ioutil.ReadAll(gzipReader)
+			if err == nil {
+				return content, cacheEntry.Hash, cacheEntry.Header
+			}
+		}
+		return batchItemError(ErrorInternal, http.StatusText(http.StatusInternalServerError)), "", nil
+	}
+	return cacheEntry.Content, cacheEntry.Hash, cacheEntry.Header
+}
+
+// mergeBatchHeaders returns headers set by handlers of all items. Cookies of all items are set, values of other
+// headers are replaced by the items following in the batch.
+func mergeBatchHeaders(headers []http.Header) http.Header {
+	var res http.Header
+	for _, header := range headers {
+		for k, v := range header {
+			if res == nil {
+				res = make(http.Header)
+			}
+			if k == "Set-Cookie" {
+				res[k] = append(res[k], v...)
+			} else {
+				res[k] = v
+			}
+		}
+	}
+	return res
+}
+
+// batchItemRequest returns the request of the item, it's the batch request with the path of the item's route, so
+// callbacks, cache keys and RequestInfo see the route. Its body is already read.
+func batchItemRequest(ctx context.Context, req *http.Request, item *BatchRequestItem) *http.Request {
+	itemReq := req.Clone(ctx)
+	itemReq.URL.Path = item.Route
+	itemReq.URL.RawPath = ""
+	itemReq.RequestURI = itemReq.URL.RequestURI()
+	itemReq.Body = http.NoBody
+	return itemReq
+}
+
+func batchItemError(code string, message string) []byte {
+	return marshalBatchItem(&HttpSessionResponse{
+		Result: "ERROR",
+		Data:   message,
+		Error:  code,
+	})
+}
+
+func marshalBatchItem(resp *HttpSessionResponse) []byte {
+	content, err := resp.MarshalJSON()
+	if err != nil {
+		content, _ = (&HttpSessionResponse{Result: "ERROR", Data: err.Error(), Error: ErrorInternal}).MarshalJSON()
+	}
+	return content
+}
@@ -0,0 +1,176 @@
+package http_json
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/sergei-svistunov/gorpc/transport/cache"
+	"github.com/stretchr/testify/assert"
+
+	test_handler1 "github.com/sergei-svistunov/gorpc/test/handler1"
+	test_handler_behavior "github.com/sergei-svistunov/gorpc/test/handler_behavior"
+	test_handler_raw "github.com/sergei-svistunov/gorpc/test/handler_raw"
+)
+
+func newBatchTestHandler(t *testing.T, callbacks APIHandlerCallbacks) *APIHandler {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	if err := hm.RegisterHandlers(test_handler1.NewHandler(), test_handler_behavior.NewHandler(), test_handler_raw.NewHandler()); err != nil {
+		t.Fatal(err)
+	}
+	return NewAPIHandler(hm, cache.NewMapCache(), callbacks).
+		SetBatchPath("/batch").
+		SetTimeout(200 * time.Millisecond)
+}
+
+func callBatch(handler http.Handler, body string, header http.Header) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("POST", "/batch", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		request.Header[k] = v
+	}
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestAPIHandler_Batch(t *testing.T) {
+	handler := newBatchTestHandler(t, APIHandlerCallbacks{})
+	assert.True(t, handler.CanServe(httptest.NewRequest("POST", "/batch", nil)))
+
+	recorder := callBatch(handler, `[
+		{"route": "/test/handler1/v1/", "params": {"req_int": 1}},
+		{"route": "/test/handler1/v2/", "params": {"req_int": 1, "error_id": 3}},
+		{"route": "/test/handler1/v1/", "params": {}},
+		{"route": "/test/unknown/v1/"},
+		{"route": "/test/handler_behavior/v1/", "params": {"value": 1, "sleep": "1s"}},
+		{"route": "/test/handler_behavior/v1/", "params": {"value": 1, "panic": true}},
+		{"route": "/test/handler_raw/v1/", "params": {"rows": 1}},
+		{"route": "/test/handler_raw/v1/", "params": {"rows": 1, "stream": true}}
+	]`, nil)
+	if !assert.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String()) {
+		return
+	}
+
+	var responses []struct {
+		Result string
+		Data   json.RawMessage
+		Error  string
+	}
+	if !assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &responses)) || !assert.Len(t, responses, 8) {
+		return
+	}
+
+	assert.Equal(t, "OK", responses[0].Result)
+	assert.JSONEq(t, `{"string": "Test", "int": 1}`, string(responses[0].Data))
+
+	assert.Equal(t, "ERROR", responses[1].Result)
+	assert.Equal(t, "ERROR_TYPE3", responses[1].Error)
+
+	assert.Equal(t, ErrorInvalidParameters, responses[2].Error)
+	assert.JSONEq(t, `[{"path": "req_int", "code": "REQUIRED", "message": "Missed required field 'req_int'"}]`, string(responses[2].Data))
+
+	assert.Equal(t, ErrorNotFound, responses[3].Error)
+	assert.Equal(t, ErrorTimeout, responses[4].Error)
+	assert.Equal(t, ErrorInternal, responses[5].Error)
+	assert.Equal(t, ErrorRawNotSupported, responses[6].Error)
+	assert.Equal(t, ErrorRawNotSupported, responses[7].Error)
+}
+
+func TestAPIHandler_BatchConcurrency(t *testing.T) {
+	var running, maxRunning int32
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.Use(func(ctx context.Context, handler gorpc.HandlerVersion, params reflect.Value, next gorpc.InterceptorNext) (interface{}, *gorpc.CallHandlerError) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+				break
+			}
+		}
+		return next(ctx, params)
+	})
+	if err := hm.RegisterHandler(test_handler_behavior.NewHandler()); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewAPIHandler(hm, nil, APIHandlerCallbacks{}).SetBatchPath("/batch").SetBatchConcurrency(2)
+
+	items := make([]string, 6)
+	for i := range items {
+		items[i] = `{"route": "/test/handler_behavior/v1/", "params": {"value": 1, "sleep": "20ms"}}`
+	}
+	recorder := callBatch(handler, "["+strings.Join(items, ",")+"]", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxRunning))
+}
+
+func TestAPIHandler_BatchETag(t *testing.T) {
+	handler := newBatchTestHandler(t, APIHandlerCallbacks{})
+	body := `[{"route": "/test/handler_behavior/v1/", "params": {"value": 1, "cache": true}},
+		{"route": "/test/handler_behavior/v1/", "params": {"value": 2, "cache": true}}]`
+
+	recorder := callBatch(handler, body, nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	etag := recorder.Header().Get("Etag")
+	if !assert.NotEmpty(t, etag) {
+		return
+	}
+	assert.JSONEq(t, `[{"result": "OK", "data": {"value": 1}, "error": ""}, {"result": "OK", "data": {"value": 2}, "error": ""}]`, recorder.Body.String())
+
+	recorder = callBatch(handler, body, http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusNotModified, recorder.Code)
+
+	// item without ETag disables ETag of the whole batch
+	recorder = callBatch(handler, `[{"route": "/test/handler_behavior/v1/", "params": {"value": 1, "cache": true}},
+		{"route": "/test/handler_behavior/v1/", "params": {"value": 3}}]`, nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Empty(t, recorder.Header().Get("Etag"))
+}
+
+func TestAPIHandler_BatchInvalidRequest(t *testing.T) {
+	handler := newBatchTestHandler(t, APIHandlerCallbacks{})
+
+	assert.Equal(t, http.StatusBadRequest, callBatch(handler, `{"route": "/test/handler1/v1/"}`, nil).Code)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/batch", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
+
+func TestAPIHandler_BatchItemRequest(t *testing.T) {
+	var mu sync.Mutex
+	paths := map[string]string{}
+	handler := newBatchTestHandler(t, APIHandlerCallbacks{
+		GetCacheKey: func(ctx context.Context, req *http.Request, params interface{}) []byte {
+			mu.Lock()
+			defer mu.Unlock()
+			paths[req.URL.Path] = gorpc.RequestInfo(ctx).HTTPRequest.URL.Path
+			// the key is wrong if items get the batch request
+			return []byte(req.URL.Path)
+		},
+	})
+
+	recorder := callBatch(handler, `[
+		{"route": "/test/handler_behavior/v1/", "params": {"value": 1, "cache": true}},
+		{"route": "/test/handler1/v1/", "params": {"req_int": 1}}
+	]`, nil)
+	assert.Equal(t, map[string]string{
+		"/test/handler_behavior/v1/": "/test/handler_behavior/v1/",
+		"/test/handler1/v1/":         "/test/handler1/v1/",
+	}, paths)
+
+	var responses []HttpSessionResponse
+	if assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &responses)) && assert.Len(t, responses, 2) {
+		assert.Equal(t, map[string]interface{}{"value": float64(1)}, responses[0].Data)
+		assert.Equal(t, map[string]interface{}{"string": "Test", "int": float64(1)}, responses[1].Data)
+	}
+}
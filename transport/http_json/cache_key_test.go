@@ -0,0 +1,94 @@
+package http_json
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/sergei-svistunov/gorpc/transport/cache"
+
+	test_handler_cache_key "github.com/sergei-svistunov/gorpc/test/handler_cache_key"
+)
+
+func TestAPIHandler_CacheKey(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(test_handler_cache_key.NewHandler())
+	handler := NewAPIHandler(hm, cache.NewMapCache(), APIHandlerCallbacks{
+		OnInitCtx: func(ctx context.Context, req *http.Request) context.Context {
+			if user := req.Header.Get("X-User"); user != "" {
+				ctx = context.WithValue(ctx, test_handler_cache_key.UserContextKey{}, user)
+			}
+			return ctx
+		},
+	})
+
+	for i, c := range []struct {
+		query, lang, user string
+		body              string
+	}{
+		{"id=1&source=email", "", "", `{"calls":1,"text":"item 1 in en"}`},
+		// the tracking parameter isn't a part of the key
+		{"id=1&source=push", "", "", `{"calls":1,"text":"item 1 in en"}`},
+		{"id=2", "", "", `{"calls":2,"text":"item 2 in en"}`},
+		{"id=1", "ru", "", `{"calls":3,"text":"item 1 in ru"}`},
+		{"id=1&source=push", "ru", "", `{"calls":3,"text":"item 1 in ru"}`},
+		{"id=1", "ru", "alice", `{"calls":4,"text":"item 1 in ru for alice"}`},
+		{"id=1", "ru", "bob", `{"calls":5,"text":"item 1 in ru for bob"}`},
+		{"id=1", "ru", "alice", `{"calls":4,"text":"item 1 in ru for alice"}`},
+	} {
+		req := httptest.NewRequest("GET", "/test/handler_cache_key/v1/?"+c.query, nil)
+		if c.lang != "" {
+			req.Header.Set("Accept-Language", c.lang)
+		}
+		if c.user != "" {
+			req.Header.Set("X-User", c.user)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		assert.JSONEq(t, `{"result":"OK","data":`+c.body+`,"error":""}`, recorder.Body.String(), "request %d", i)
+		assert.Equal(t, "Accept-Language", recorder.Header().Get("Vary"))
+	}
+}
+
+type varyUser struct {
+	id int
+}
+
+func (u varyUser) String() string {
+	return strconv.Itoa(u.id)
+}
+
+func TestWriteCacheVary(t *testing.T) {
+	type key struct{}
+	vary := &gorpc.CacheVary{Headers: []string{"accept-language"}, ContextKeys: []interface{}{key{}}}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Language", "en")
+
+	write := func(value interface{}) (string, error) {
+		var buf bytes.Buffer
+		err := writeCacheVary(context.WithValue(context.Background(), key{}, value), &buf, req, vary)
+		return buf.String(), err
+	}
+	id1, id2 := 1, 1
+	for value, expected := range map[interface{}]string{
+		"user":          "\nAccept-Language:en\nctx0:user",
+		varyUser{id: 5}: "\nAccept-Language:en\nctx0:5",
+		&id1:            "\nAccept-Language:en\nctx0:1",
+		&id2:            "\nAccept-Language:en\nctx0:1",
+		nil:             "\nAccept-Language:en\nctx0:null",
+	} {
+		res, err := write(value)
+		if assert.NoError(t, err) {
+			assert.Equal(t, expected, res, "%v", value)
+		}
+	}
+
+	_, err := write(make(chan int))
+	assert.Error(t, err, "Values which can't be encoded must fail")
+}
@@ -0,0 +1,330 @@
+package http_json
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/sergei-svistunov/gorpc"
+)
+
+// Codec encodes responses and decodes requests' bodies of one media type. APIHandler chooses the codec of
+// the request by its Content-Type and the codec of the response by the Accept header.
+type Codec interface {
+	// ContentType returns the value of Content-Type header, its media type is used for the negotiation
+	ContentType() string
+	// Marshal encodes the response's envelope
+	Marshal(resp *HttpSessionResponse) ([]byte, error)
+	// NewParametersGetter returns the getter of the parameters encoded in the request's body
+	NewParametersGetter(body io.ReadCloser) gorpc.IHandlerParameters
+}
+
+// JSONCodec is the default codec, it's always registered in APIHandler
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string {
+	return "application/json; charset=utf-8"
+}
+
+func (JSONCodec) Marshal(resp *HttpSessionResponse) ([]byte, error) {
+	return resp.MarshalJSON()
+}
+
+func (JSONCodec) NewParametersGetter(body io.ReadCloser) gorpc.IHandlerParameters {
+	return &JsonParametersGetter{Req: body}
+}
+
+// MsgpackCodec encodes into MessagePack, structures are encoded by their json tags
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) ContentType() string {
+	return "application/msgpack"
+}
+
+func (MsgpackCodec) Marshal(resp *HttpSessionResponse) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetCustomStructTag("json")
+	if err := encoder.Encode(resp); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (MsgpackCodec) NewParametersGetter(body io.ReadCloser) gorpc.IHandlerParameters {
+	return &MsgpackParametersGetter{JsonParametersGetter{Req: body}}
+}
+
+// CBORCodec encodes into CBOR (RFC 7049), structures are encoded by their json tags
+type CBORCodec struct{}
+
+func (CBORCodec) ContentType() string {
+	return "application/cbor"
+}
+
+func (CBORCodec) Marshal(resp *HttpSessionResponse) ([]byte, error) {
+	return cbor.Marshal(resp)
+}
+
+func (CBORCodec) NewParametersGetter(body io.ReadCloser) gorpc.IHandlerParameters {
+	return &CBORParametersGetter{JsonParametersGetter{Req: body}}
+}
+
+// MsgpackParametersGetter reads parameters from the MessagePack body. Decoded values are converted into
+// the JSON representation, so they are accessed the same way as in JsonParametersGetter.
+type MsgpackParametersGetter struct {
+	JsonParametersGetter
+}
+
+func (p *MsgpackParametersGetter) Parse() error {
+	return p.parse(func(r io.Reader, v *interface{}) error {
+		return msgpack.NewDecoder(r).Decode(v)
+	})
+}
+
+// CBORParametersGetter reads parameters from the CBOR body. Decoded values are converted into the JSON
+// representation, so they are accessed the same way as in JsonParametersGetter.
+type CBORParametersGetter struct {
+	JsonParametersGetter
+}
+
+func (p *CBORParametersGetter) Parse() error {
+	return p.parse(func(r io.Reader, v *interface{}) error {
+		return cbor.NewDecoder(r).Decode(v)
+	})
+}
+
+// parse decodes the body by the decoder and converts the result into values of JsonParametersGetter
+func (p *JsonParametersGetter) parse(decode func(r io.Reader, v *interface{}) error) error {
+	if p.values != nil {
+		return nil
+	}
+	defer p.Req.Close()
+	if p.MaxFormSize == 0 {
+		p.MaxFormSize = defaultMaxFormSize
+	}
+
+	var v interface{}
+	if err := decode(io.LimitReader(p.Req, p.MaxFormSize), &v); err != nil {
+		return err
+	}
+	v, err := toJSONValue(v)
+	if err != nil {
+		return err
+	}
+	values, ok := v.(map[string]interface{})
+	if !ok {
+		return errors.New("Request body must be an object")
+	}
+	p.values = values
+	return nil
+}
+
+// toJSONValue converts the decoded value into the types produced by encoding/json with UseNumber
+func toJSONValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case nil, bool, string, json.Number:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case int8:
+		return json.Number(strconv.FormatInt(int64(v), 10)), nil
+	case int16:
+		return json.Number(strconv.FormatInt(int64(v), 10)), nil
+	case int32:
+		return json.Number(strconv.FormatInt(int64(v), 10)), nil
+	case int64:
+		return json.Number(strconv.FormatInt(v, 10)), nil
+	case int:
+		return json.Number(strconv.FormatInt(int64(v), 10)), nil
+	case uint8:
+		return json.Number(strconv.FormatUint(uint64(v), 10)), nil
+	case uint16:
+		return json.Number(strconv.FormatUint(uint64(v), 10)), nil
+	case uint32:
+		return json.Number(strconv.FormatUint(uint64(v), 10)), nil
+	case uint64:
+		return json.Number(strconv.FormatUint(v, 10)), nil
+	case uint:
+		return json.Number(strconv.FormatUint(uint64(v), 10)), nil
+	case float32:
+		return toJSONValue(float64(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("Unsupported number %v", v)
+		}
+		return json.Number(strconv.FormatFloat(v, 'g', -1, 64)), nil
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, item := range v {
+			var err error
+			if res[i], err = toJSONValue(item); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			var err error
+			if res[k], err = toJSONValue(item); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	case map[interface{}]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("Unsupported key %v, keys must be strings", k)
+			}
+			var err error
+			if res[key], err = toJSONValue(item); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	default:
+		return nil, fmt.Errorf("Unsupported value of type %T", v)
+	}
+}
+
+// RegisterCodec adds codecs to the negotiation, a codec replaces the registered one with the same media type
+func (h *APIHandler) RegisterCodec(codecs ...Codec) *APIHandler {
+	for _, codec := range codecs {
+		replaced := false
+		for i, registered := range h.codecs {
+			if mediaType(registered.ContentType()) == mediaType(codec.ContentType()) {
+				h.codecs[i] = codec
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			h.codecs = append(h.codecs, codec)
+		}
+	}
+	return h
+}
+
+// SetStrictAccept makes the handler to respond with 406 Not Acceptable if the Accept header lists no registered
+// codec. Otherwise the response is encoded by the first registered codec, it's JSON by default.
+func (h *APIHandler) SetStrictAccept(strict bool) *APIHandler {
+	h.strictAccept = strict
+	return h
+}
+
+// requestCodec returns the codec of the request's body or nil if the body isn't encoded by any registered codec
+func (h *APIHandler) requestCodec(req *http.Request) Codec {
+	contentType := req.Header.Get("Content-Type")
+	if contentType == "" {
+		return nil
+	}
+	return h.findCodec(mediaType(contentType))
+}
+
+func (h *APIHandler) findCodec(mType string) Codec {
+	for _, codec := range h.codecs {
+		if mediaType(codec.ContentType()) == mType {
+			return codec
+		}
+	}
+	return nil
+}
+
+// responseCodec chooses the codec of the response by the Accept header. The codec of the request is preferred if
+// the client accepts any type. It returns nil if no one codec is acceptable.
+func (h *APIHandler) responseCodec(req *http.Request) Codec {
+	preferred := h.requestCodec(req)
+	if preferred == nil {
+		preferred = h.codecs[0]
+	}
+
+	accept := req.Header.Get("Accept")
+	if accept == "" {
+		return preferred
+	}
+
+	type acceptRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if qValue, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(qValue, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, acceptRange{mType, q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	for _, r := range ranges {
+		if r.mediaType == "*/*" {
+			return preferred
+		}
+		if strings.HasSuffix(r.mediaType, "/*") {
+			if strings.HasPrefix(mediaType(preferred.ContentType()), strings.TrimSuffix(r.mediaType, "*")) {
+				return preferred
+			}
+			for _, codec := range h.codecs {
+				if strings.HasPrefix(mediaType(codec.ContentType()), strings.TrimSuffix(r.mediaType, "*")) {
+					return codec
+				}
+			}
+			continue
+		}
+		if codec := h.findCodec(r.mediaType); codec != nil {
+			return codec
+		}
+	}
+	return nil
+}
+
+func mediaType(contentType string) string {
+	mType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return mType
+}
+
+type codecContextKey struct{}
+
+func withCodec(ctx context.Context, codec Codec) context.Context {
+	return context.WithValue(ctx, codecContextKey{}, codec)
+}
+
+// codecFromContext returns the codec of the response, it's JSON if it wasn't negotiated (e.g. for items of a batch)
+func codecFromContext(ctx context.Context) Codec {
+	if codec, ok := ctx.Value(codecContextKey{}).(Codec); ok {
+		return codec
+	}
+	return JSONCodec{}
+}
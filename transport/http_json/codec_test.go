@@ -0,0 +1,196 @@
+package http_json
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/sergei-svistunov/gorpc/transport/cache"
+
+	test_handler1 "github.com/sergei-svistunov/gorpc/test/handler1"
+	test_handler_behavior "github.com/sergei-svistunov/gorpc/test/handler_behavior"
+)
+
+func newCodecTestServer(t *testing.T) *httptest.Server {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	if err := hm.RegisterHandlers(test_handler1.NewHandler(), test_handler_behavior.NewHandler()); err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(NewAPIHandler(hm, cache.NewMapCache(), APIHandlerCallbacks{}).RegisterCodec(MsgpackCodec{}, CBORCodec{}))
+}
+
+func doCodecRequest(t *testing.T, url, contentType, accept string, body []byte) *http.Response {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestCodec_Msgpack(t *testing.T) {
+	server := newCodecTestServer(t)
+	defer server.Close()
+
+	body, _ := msgpack.Marshal(map[string]interface{}{"req_int": 7})
+	resp := doCodecRequest(t, server.URL+"/test/handler1/v1/", "application/msgpack", "", body)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/msgpack", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "Accept", resp.Header.Get("Vary"))
+
+	var res struct {
+		Result string                 `msgpack:"result"`
+		Data   map[string]interface{} `msgpack:"data"`
+	}
+	data, _ := ioutil.ReadAll(resp.Body)
+	if assert.NoError(t, msgpack.Unmarshal(data, &res)) {
+		assert.Equal(t, "OK", res.Result)
+		assert.Equal(t, "Test", res.Data["string"])
+		assert.EqualValues(t, 7, res.Data["int"])
+	}
+}
+
+func TestCodec_CBOR(t *testing.T) {
+	server := newCodecTestServer(t)
+	defer server.Close()
+
+	body, _ := cbor.Marshal(map[string]interface{}{"req_int": 2, "error_id": 1})
+	resp := doCodecRequest(t, server.URL+"/test/handler1/v2/", "application/cbor", "application/cbor", body)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/cbor", resp.Header.Get("Content-Type"))
+
+	var res struct {
+		Result string `cbor:"result"`
+		Data   string `cbor:"data"`
+		Error  string `cbor:"error"`
+	}
+	data, _ := ioutil.ReadAll(resp.Body)
+	if assert.NoError(t, cbor.Unmarshal(data, &res)) {
+		assert.Equal(t, "ERROR", res.Result)
+		assert.Equal(t, "ERROR_TYPE1", res.Error)
+		assert.Equal(t, "Error 1 description", res.Data)
+	}
+}
+
+func TestCodec_Negotiation(t *testing.T) {
+	server := newCodecTestServer(t)
+	defer server.Close()
+
+	body := []byte(`{"req_int": 1}`)
+	for accept, contentType := range map[string]string{
+		"":                                     "application/json; charset=utf-8",
+		"*/*":                                  "application/json; charset=utf-8",
+		"application/cbor":                     "application/cbor",
+		"text/html, application/msgpack;q=0.5": "application/msgpack",
+		"application/json;q=0.2, application/cbor;q=0.8": "application/cbor",
+		"application/*": "application/json; charset=utf-8",
+	} {
+		resp := doCodecRequest(t, server.URL+"/test/handler1/v1/", "application/json", accept, body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, accept)
+		assert.Equal(t, contentType, resp.Header.Get("Content-Type"), accept)
+	}
+
+	// browsers get JSON as before the negotiation
+	resp := doCodecRequest(t, server.URL+"/test/handler1/v1/", "application/json", "text/html", body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
+}
+
+func TestCodec_StrictAccept(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(test_handler1.NewHandler())
+	server := httptest.NewServer(NewAPIHandler(hm, nil, APIHandlerCallbacks{}).SetStrictAccept(true))
+	defer server.Close()
+
+	body := []byte(`{"req_int": 1}`)
+	resp := doCodecRequest(t, server.URL+"/test/handler1/v1/", "application/json", "text/html", body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotAcceptable, resp.StatusCode)
+
+	resp = doCodecRequest(t, server.URL+"/test/handler1/v1/", "application/json", "text/html, application/json;q=0.1", body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestCodec_CacheKeyedPerEncoding(t *testing.T) {
+	server := newCodecTestServer(t)
+	defer server.Close()
+
+	body := []byte(`{"value": 3, "cache": true}`)
+	for _, accept := range []string{"application/json", "application/msgpack", "application/json", "application/msgpack"} {
+		resp := doCodecRequest(t, server.URL+"/test/handler_behavior/v1/", "application/json", accept, body)
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		var res map[string]interface{}
+		if accept == "application/json" {
+			assert.NoError(t, json.Unmarshal(data, &res))
+		} else {
+			assert.NoError(t, msgpack.Unmarshal(data, &res))
+		}
+		assert.Equal(t, "OK", res["result"], accept)
+	}
+}
+
+func TestCodec_ParameterErrors(t *testing.T) {
+	server := newCodecTestServer(t)
+	defer server.Close()
+
+	body, _ := msgpack.Marshal(map[string]interface{}{"req_int": "a"})
+	resp := doCodecRequest(t, server.URL+"/test/handler1/v1/", "application/msgpack", "", body)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	var res struct {
+		Error string              `msgpack:"error"`
+		Data  []map[string]string `msgpack:"data"`
+	}
+	data, _ := ioutil.ReadAll(resp.Body)
+	if assert.NoError(t, msgpack.Unmarshal(data, &res)) {
+		assert.Equal(t, ErrorInvalidParameters, res.Error)
+		if assert.Len(t, res.Data, 1) {
+			assert.Equal(t, "req_int", res.Data[0]["path"])
+			assert.Equal(t, gorpc.ParameterErrorInvalidValue, res.Data[0]["code"])
+		}
+	}
+}
+
+func TestCodec_ToJSONValue(t *testing.T) {
+	v, err := toJSONValue(map[interface{}]interface{}{
+		"i": int8(-1),
+		"u": uint64(18446744073709551615),
+		"f": float32(1.5),
+		"b": []byte("bytes"),
+		"a": []interface{}{true, nil, "s"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"i": json.Number("-1"),
+		"u": json.Number("18446744073709551615"),
+		"f": json.Number("1.5"),
+		"b": "bytes",
+		"a": []interface{}{true, nil, "s"},
+	}, v)
+
+	_, err = toJSONValue(map[interface{}]interface{}{1: "a"})
+	assert.Error(t, err)
+}
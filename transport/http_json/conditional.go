@@ -0,0 +1,113 @@
+package http_json
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sergei-svistunov/gorpc/transport/cache"
+)
+
+// setCacheMetadata copies settings of HTTP caching from the handler's context to the entry, so they are sent with
+// the cached response too
+func setCacheMetadata(ctx context.Context, cacheEntry *cache.CacheEntry) {
+	if ttl := cache.TTL(ctx); ttl > 0 {
+		cacheEntry.Expires = time.Now().Add(ttl)
+	}
+	cacheEntry.CacheControl = cache.GetCacheControl(ctx)
+	cacheEntry.WeakETag = cache.IsWeakETagEnabled(ctx)
+	cacheEntry.LastModified = cache.LastModified(ctx)
+}
+
+// writeCacheHeaders writes ETag, Last-Modified and Cache-Control of the entry, Cache-Control set by the handler isn't
+// replaced
+func writeCacheHeaders(w http.ResponseWriter, cacheEntry *cache.CacheEntry) {
+	if cacheEntry.Hash != "" {
+		w.Header().Set("Etag", formatETag(cacheEntry.Hash, cacheEntry.WeakETag))
+	}
+	if !cacheEntry.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", cacheEntry.LastModified.UTC().Format(http.TimeFormat))
+	}
+	if w.Header().Get("Cache-Control") == "" {
+		if cacheControl := cacheControlHeader(cacheEntry, time.Now()); cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+	}
+}
+
+// cacheControlHeader returns Cache-Control of the entry with max-age equal to the time left until its expiration
+func cacheControlHeader(cacheEntry *cache.CacheEntry, now time.Time) string {
+	if cacheEntry.CacheControl == cache.CacheControlNoStore {
+		return "no-store"
+	}
+
+	var directives []string
+	switch cacheEntry.CacheControl {
+	case cache.CacheControlPublic:
+		directives = append(directives, "public")
+	case cache.CacheControlPrivate:
+		directives = append(directives, "private")
+	case cache.CacheControlDefault:
+		// the response may be personal, so shared caches store it only if the handler allows
+		if !cacheEntry.Expires.IsZero() {
+			directives = append(directives, "private")
+		}
+	}
+	if !cacheEntry.Expires.IsZero() {
+		maxAge := cacheEntry.Expires.Sub(now)
+		if maxAge < 0 {
+			maxAge = 0
+		}
+		directives = append(directives, fmt.Sprintf("max-age=%d", seconds(maxAge)))
+		if cacheEntry.StaleUntil.After(cacheEntry.Expires) {
+			directives = append(directives, fmt.Sprintf("stale-while-revalidate=%d", seconds(cacheEntry.StaleUntil.Sub(cacheEntry.Expires))))
+		}
+		if cacheEntry.StaleIfErrorUntil.After(cacheEntry.Expires) {
+			directives = append(directives, fmt.Sprintf("stale-if-error=%d", seconds(cacheEntry.StaleIfErrorUntil.Sub(cacheEntry.Expires))))
+		}
+	}
+	return strings.Join(directives, ", ")
+}
+
+// seconds returns the duration in seconds rounded to the nearest, so the time spent on the call isn't subtracted
+func seconds(d time.Duration) int64 {
+	return int64(d.Round(time.Second) / time.Second)
+}
+
+// formatETag returns the value of ETag header for the hash
+func formatETag(hash string, weak bool) string {
+	if weak {
+		return `W/"` + hash + `"`
+	}
+	return `"` + hash + `"`
+}
+
+// isNotModified reports whether the client has the entry already. If-Modified-Since is ignored if the request has
+// If-None-Match.
+func isNotModified(req *http.Request, cacheEntry *cache.CacheEntry) bool {
+	if ifNoneMatch := req.Header.Values("If-None-Match"); len(ifNoneMatch) > 0 {
+		return cacheEntry.Hash != "" && etagMatches(strings.Join(ifNoneMatch, ","), cacheEntry.Hash)
+	}
+	if ifModifiedSince := req.Header.Get("If-Modified-Since"); ifModifiedSince != "" && !cacheEntry.LastModified.IsZero() {
+		t, err := http.ParseTime(ifModifiedSince)
+		return err == nil && !cacheEntry.LastModified.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
+// etagMatches reports whether the list of ETags of If-None-Match header contains the hash. ETags are compared weakly,
+// unquoted ones are accepted too.
+func etagMatches(ifNoneMatch, hash string) bool {
+	for _, etag := range strings.Split(ifNoneMatch, ",") {
+		etag = strings.TrimSpace(etag)
+		if etag == "*" {
+			return true
+		}
+		if strings.Trim(strings.TrimPrefix(etag, "W/"), `"`) == hash {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package http_json
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/sergei-svistunov/gorpc/transport/cache"
+)
+
+var conditionalLastModified = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// conditionalHandler sets HTTP caching of its response by parameters, it's declared here because handlers in the test
+// directory can't import the transport
+type conditionalHandler struct{}
+
+func (*conditionalHandler) Caption() string {
+	return "Conditional handler"
+}
+
+func (*conditionalHandler) Description() string {
+	return "Handler with Cache-Control, ETag and Last-Modified"
+}
+
+type conditionalV1Args struct {
+	CacheControl int  `key:"cache_control" description:"Cache-Control policy" default:"0"`
+	Weak         bool `key:"weak" description:"Weak ETag" default:"false"`
+	Cached       bool `key:"cached" description:"Cache the response in the transport cache" default:"true"`
+}
+
+type conditionalV1Res struct {
+	OK bool `json:"ok" description:"Always true"`
+}
+
+func (*conditionalHandler) V1(ctx context.Context, opts *conditionalV1Args) (*conditionalV1Res, error) {
+	if opts.Cached {
+		cache.EnableTransportCache(ctx)
+		cache.EnableETag(ctx)
+	}
+	if opts.Weak {
+		cache.EnableWeakETag(ctx)
+	}
+	cache.SetTTL(ctx, time.Minute)
+	cache.SetCacheControl(ctx, cache.CacheControl(opts.CacheControl))
+	cache.SetLastModified(ctx, conditionalLastModified)
+	return &conditionalV1Res{OK: true}, nil
+}
+
+func TestConditionalRequests(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(&conditionalHandler{})
+	handler := NewAPIHandler(hm, cache.NewMapCache(), APIHandlerCallbacks{})
+
+	call := func(query string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/transport/http_json/v1/?"+query, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := call("", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "private, max-age=60", recorder.Header().Get("Cache-Control"), "Shared caches must not store the response by default")
+	assert.Equal(t, "Thu, 02 Jan 2020 03:04:05 GMT", recorder.Header().Get("Last-Modified"))
+	etag := recorder.Header().Get("Etag")
+	assert.Regexp(t, `^"[0-9a-f]{40}"$`, etag)
+
+	// the cached response is conditional too
+	for _, ifNoneMatch := range []string{etag, `"other", ` + etag, "W/" + etag, etag[1 : len(etag)-1], "*"} {
+		recorder = call("", http.Header{"If-None-Match": {ifNoneMatch}})
+		assert.Equal(t, http.StatusNotModified, recorder.Code, ifNoneMatch)
+		assert.Empty(t, recorder.Body.String(), ifNoneMatch)
+		assert.Equal(t, etag, recorder.Header().Get("Etag"), ifNoneMatch)
+	}
+	recorder = call("", http.Header{"If-None-Match": {`"other"`, `"another"`}})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder = call("", http.Header{"If-None-Match": {etag + ", "}, "If-Modified-Since": {"Thu, 02 Jan 2020 03:04:04 GMT"}})
+	assert.Equal(t, http.StatusNotModified, recorder.Code, "If-Modified-Since must be ignored if the request has If-None-Match")
+
+	recorder = call("weak=true&cache_control=1", nil)
+	assert.Equal(t, "public, max-age=60", recorder.Header().Get("Cache-Control"))
+	assert.Regexp(t, `^W/"[0-9a-f]{40}"$`, recorder.Header().Get("Etag"))
+	assert.Equal(t, http.StatusNotModified, call("weak=true&cache_control=1", http.Header{"If-None-Match": {recorder.Header().Get("Etag")}}).Code)
+
+	// Last-Modified works without ETag and the transport cache
+	for ifModifiedSince, status := range map[string]int{
+		"Thu, 02 Jan 2020 03:04:05 GMT": http.StatusNotModified,
+		"Fri, 03 Jan 2020 00:00:00 GMT": http.StatusNotModified,
+		"Thu, 02 Jan 2020 03:04:04 GMT": http.StatusOK,
+		"invalid":                       http.StatusOK,
+	} {
+		recorder = call("cached=false&cache_control=2", http.Header{"If-Modified-Since": {ifModifiedSince}})
+		assert.Equal(t, status, recorder.Code, ifModifiedSince)
+		assert.Equal(t, "private, max-age=60", recorder.Header().Get("Cache-Control"))
+		assert.Empty(t, recorder.Header().Get("Etag"))
+	}
+
+	assert.Equal(t, "no-store", call("cached=false&cache_control=3", nil).Header().Get("Cache-Control"))
+}
+
+func TestCacheControlHeader(t *testing.T) {
+	now := time.Now()
+	assert.Equal(t, "", cacheControlHeader(&cache.CacheEntry{}, now))
+	assert.Equal(t, "private", cacheControlHeader(&cache.CacheEntry{CacheControl: cache.CacheControlPrivate}, now))
+	assert.Equal(t, "private, max-age=60", cacheControlHeader(&cache.CacheEntry{Expires: now.Add(time.Minute)}, now))
+	assert.Equal(t, "public, max-age=60", cacheControlHeader(&cache.CacheEntry{Expires: now.Add(time.Minute), CacheControl: cache.CacheControlPublic}, now))
+	assert.Equal(t, "private, max-age=0, stale-while-revalidate=60, stale-if-error=3600", cacheControlHeader(&cache.CacheEntry{
+		Expires:           now.Add(-time.Second),
+		StaleUntil:        now.Add(time.Minute - time.Second),
+		StaleIfErrorUntil: now.Add(time.Hour - time.Second),
+	}, now))
+}
@@ -0,0 +1,55 @@
+package http_json
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergei-svistunov/gorpc"
+
+	test_handler_error_status "github.com/sergei-svistunov/gorpc/test/handler_error_status"
+)
+
+func TestAPIHandler_ErrorStatus(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(test_handler_error_status.NewHandler())
+	handler := NewAPIHandler(hm, nil, APIHandlerCallbacks{}).
+		SetErrorStatus("CONFLICT", http.StatusConflict).
+		SetErrorStatus("NOT_FOUND", http.StatusTeapot)
+
+	for query, expected := range map[string]struct {
+		status int
+		body   string
+	}{
+		"error_id=0": {http.StatusOK, `{"result":"OK","data":{"ok":true},"error":""}`},
+		"error_id=1": {http.StatusNotFound, `{"result":"ERROR","data":"Not found","error":"NOT_FOUND"}`},
+		"error_id=2": {http.StatusForbidden, `{"result":"ERROR","data":"Forbidden","error":"FORBIDDEN"}`},
+		"error_id=4": {http.StatusConflict, `{"result":"ERROR","data":"Conflict","error":"CONFLICT"}`},
+	} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/test/handler_error_status/v1/?"+query, nil))
+		assert.Equal(t, expected.status, recorder.Code, query)
+		assert.JSONEq(t, expected.body, recorder.Body.String(), query)
+	}
+}
+
+func TestAPIHandler_ErrorStatusSwagger(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(test_handler_error_status.NewHandler())
+
+	swagger, err := GenerateSwaggerJSON(hm, "", SwaggerJSONCallbacks{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responses := swagger.Paths["/test/handler_error_status/v1/"]["get"].Responses
+	assert.Len(t, responses, 3)
+	if assert.NotNil(t, responses["404"]) {
+		assert.Equal(t, "Business error: NOT_FOUND, GONE", responses["404"].Description)
+	}
+	if assert.NotNil(t, responses["403"]) {
+		assert.Equal(t, "Business error: FORBIDDEN", responses["403"].Description)
+	}
+}
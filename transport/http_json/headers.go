@@ -0,0 +1,83 @@
+package http_json
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+type responseHeadersContextKey struct{}
+
+// responseHeaders are headers set by the handler during the call, the handler may set them from several goroutines
+type responseHeaders struct {
+	mu     sync.Mutex
+	header http.Header
+}
+
+func withResponseHeaders(ctx context.Context) context.Context {
+	return context.WithValue(ctx, responseHeadersContextKey{}, &responseHeaders{header: make(http.Header)})
+}
+
+// SetHeader sets the header of the HTTP response, e.g. Cache-Control or Location. Headers are written with both
+// successful responses and business errors and are cached with the response, so they are replayed for the cached
+// one. It does nothing if the handler isn't called by APIHandler.
+func SetHeader(ctx context.Context, key, value string) {
+	if h, ok := ctx.Value(responseHeadersContextKey{}).(*responseHeaders); ok {
+		h.mu.Lock()
+		h.header.Set(key, value)
+		h.mu.Unlock()
+	}
+}
+
+// AddHeader adds the value to the header of the HTTP response, see SetHeader
+func AddHeader(ctx context.Context, key, value string) {
+	if h, ok := ctx.Value(responseHeadersContextKey{}).(*responseHeaders); ok {
+		h.mu.Lock()
+		h.header.Add(key, value)
+		h.mu.Unlock()
+	}
+}
+
+// AddCookie adds Set-Cookie header to the HTTP response. The cookie is replayed for the cached response, so
+// the transport cache must be disabled for responses with personal cookies.
+func AddCookie(ctx context.Context, cookie *http.Cookie) {
+	if v := cookie.String(); v != "" {
+		AddHeader(ctx, "Set-Cookie", v)
+	}
+}
+
+// responseHeadersFromContext returns the copy of headers set by the handler
+func responseHeadersFromContext(ctx context.Context) http.Header {
+	h, ok := ctx.Value(responseHeadersContextKey{}).(*responseHeaders)
+	if !ok {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.header) == 0 {
+		return nil
+	}
+	return h.header.Clone()
+}
+
+// mergeHeaders returns headers of both a and b, values of b replace values of a with the same key
+func mergeHeaders(a, b http.Header) http.Header {
+	if len(b) == 0 {
+		return a
+	}
+	if len(a) == 0 {
+		return b
+	}
+	header := a.Clone()
+	for k, v := range b {
+		header[k] = v
+	}
+	return header
+}
+
+// writeHeaders copies headers into the response
+func writeHeaders(w http.ResponseWriter, header http.Header) {
+	for k, v := range header {
+		w.Header()[k] = append([]string(nil), v...)
+	}
+}
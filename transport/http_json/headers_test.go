@@ -0,0 +1,125 @@
+package http_json
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/sergei-svistunov/gorpc/transport/cache"
+)
+
+// headersHandler sets response headers, it's declared here because handlers in the test directory can't import
+// the transport
+type headersHandler struct {
+	calls int
+}
+
+func (*headersHandler) Caption() string {
+	return "Headers handler"
+}
+
+func (*headersHandler) Description() string {
+	return "Handler which sets response headers"
+}
+
+type headersV1Args struct {
+	Fail bool `key:"fail" description:"Return an error" default:"false"`
+}
+
+type headersV1Res struct {
+	Calls int `json:"calls" description:"Number of calls"`
+}
+
+type headersV1ErrorTypes struct {
+	FAILED error `text:"Failed"`
+}
+
+var headersV1Errors headersV1ErrorTypes
+
+func (*headersHandler) V1ErrorsVar() *headersV1ErrorTypes {
+	return &headersV1Errors
+}
+
+func (h *headersHandler) V1(ctx context.Context, opts *headersV1Args) (*headersV1Res, error) {
+	h.calls++
+	SetHeader(ctx, "Cache-Control", "max-age=60")
+	AddCookie(ctx, &http.Cookie{Name: "session", Value: "abc"})
+	AddCookie(ctx, &http.Cookie{Name: "lang", Value: "en"})
+	if opts.Fail {
+		SetHeader(ctx, "Location", "/login")
+		return nil, headersV1Errors.FAILED
+	}
+	return &headersV1Res{Calls: h.calls}, nil
+}
+
+func TestResponseHeaders(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(&headersHandler{})
+	server := httptest.NewServer(NewAPIHandler(hm, cache.NewMapCache(), APIHandlerCallbacks{
+		OnInitCtx: func(ctx context.Context, req *http.Request) context.Context {
+			cache.EnableTransportCache(ctx)
+			return ctx
+		},
+	}))
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		resp, body := getRaw(t, server.URL+"/transport/http_json/v1/", nil)
+		assert.Equal(t, "max-age=60", resp.Header.Get("Cache-Control"))
+		assert.Equal(t, []string{"session=abc", "lang=en"}, resp.Header["Set-Cookie"])
+		assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
+		// the second response is cached
+		assert.Equal(t, `{"result":"OK","data":{"calls":1},"error":""}`, body)
+	}
+
+	resp, body := getRaw(t, server.URL+"/transport/http_json/v1/?fail=true", nil)
+	assert.Equal(t, "/login", resp.Header.Get("Location"))
+	assert.Equal(t, []string{"session=abc", "lang=en"}, resp.Header["Set-Cookie"])
+	assert.Equal(t, `{"result":"ERROR","data":"Failed","error":"FAILED"}`, body)
+}
+
+func TestResponseHeaders_WithoutTransport(t *testing.T) {
+	ctx := context.Background()
+	SetHeader(ctx, "Cache-Control", "no-cache")
+	assert.Nil(t, responseHeadersFromContext(ctx))
+
+	ctx = withResponseHeaders(ctx)
+	AddHeader(ctx, "Vary", "Cookie")
+	assert.Equal(t, http.Header{"Vary": {"Cookie"}}, responseHeadersFromContext(ctx))
+	assert.Equal(t, http.Header{"Vary": {"Accept"}, "X-A": {"b"}},
+		mergeHeaders(http.Header{"Vary": {"Cookie"}, "X-A": {"b"}}, http.Header{"Vary": {"Accept"}}))
+}
+
+func TestResponseHeaders_Batch(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(&headersHandler{})
+	server := httptest.NewServer(NewAPIHandler(hm, cache.NewMapCache(), APIHandlerCallbacks{
+		OnInitCtx: func(ctx context.Context, req *http.Request) context.Context {
+			cache.EnableTransportCache(ctx)
+			return ctx
+		},
+	}).SetBatchPath("/batch").SetBatchConcurrency(1))
+	defer server.Close()
+
+	// the first item is cached by the call, its headers are replayed from the cache
+	getRaw(t, server.URL+"/transport/http_json/v1/", nil)
+	resp, err := http.Post(server.URL+"/batch", "application/json", strings.NewReader(`[
+		{"route": "/transport/http_json/v1/"},
+		{"route": "/transport/http_json/v1/", "params": {"fail": true}}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, `[{"result":"OK","data":{"calls":1},"error":""},{"result":"ERROR","data":"Failed","error":"FAILED"}]`, string(body))
+	assert.Equal(t, "max-age=60", resp.Header.Get("Cache-Control"))
+	assert.Equal(t, "/login", resp.Header.Get("Location"))
+	assert.Equal(t, []string{"session=abc", "lang=en", "session=abc", "lang=en"}, resp.Header["Set-Cookie"])
+}
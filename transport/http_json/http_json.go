@@ -10,8 +10,8 @@ import (
 	"io"
 	"net/http"
 	"reflect"
-	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sergei-svistunov/gorpc"
@@ -21,6 +21,9 @@ import (
 
 var PrintDebug = false
 
+// ErrorInvalidParameters is the error code of the response with the list of invalid parameters in the data
+const ErrorInvalidParameters = gorpc.ErrorCodeInvalidParameters
+
 //easyjson:json
 type HttpSessionResponse struct {
 	Result string       `json:"result"`
@@ -45,23 +48,37 @@ type APIHandlerCallbacks struct {
 }
 
 type APIHandler struct {
-	hm        *gorpc.HandlersManager
-	cache     cache.ICache
-	callbacks APIHandlerCallbacks
-	timeout   time.Duration
+	hm               *gorpc.HandlersManager
+	cache            cache.ICache
+	callbacks        APIHandlerCallbacks
+	timeout          time.Duration
+	batchPath        string
+	batchConcurrency int
+	codecs           []Codec
+	strictAccept     bool
+	errorStatuses    map[string]int
+	// refreshing contains keys of stale entries which are refreshed in background
+	refreshing sync.Map
 }
 
 func NewAPIHandler(hm *gorpc.HandlersManager, cache cache.ICache, callbacks APIHandlerCallbacks) *APIHandler {
 	return &APIHandler{
-		hm:        hm,
-		cache:     cache,
-		callbacks: callbacks,
+		hm:               hm,
+		cache:            cache,
+		callbacks:        callbacks,
+		batchConcurrency: defaultBatchConcurrency,
+		codecs:           []Codec{JSONCodec{}},
 	}
 }
 
 func (h *APIHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	startTime := time.Now()
 
+	if h.batchPath != "" && req.URL.Path == h.batchPath {
+		h.serveBatch(w, req, startTime)
+		return
+	}
+
 	var (
 		ctx    context.Context
 		cancel context.CancelFunc
@@ -74,6 +91,7 @@ func (h *APIHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	}
 
 	ctx = cache.NewContext(ctx)
+	ctx = withResponseHeaders(ctx)
 	if h.callbacks.OnInitCtx != nil {
 		ctx = h.callbacks.OnInitCtx(ctx, req)
 	}
@@ -100,13 +118,31 @@ func (h *APIHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// streams and raw responses aren't encoded by codecs
+	codec := h.responseCodec(req)
+	if codec == nil {
+		if h.strictAccept && !h.isUnencodedRoute(req.URL.Path) {
+			h.writeError(ctx, w, "", http.StatusNotAcceptable)
+			return
+		}
+		codec = h.codecs[0]
+	}
+	ctx = withCodec(ctx, codec)
+
 	var resp HttpSessionResponse
 
-	handler, params, err := h.parseRequest(ctx, req)
+	handler, params, closer, err := h.parseRequest(ctx, req)
+	if closer != nil {
+		defer closer.Close()
+	}
 	if err != nil {
 		if h.callbacks.OnError != nil {
 			h.callbacks.OnError(ctx, w, req, resp, err)
 		}
+		if paramErrs := err.ParameterErrors(); paramErrs != nil {
+			h.writeParameterErrors(ctx, w, paramErrs)
+			return
+		}
 		h.writeError(ctx, w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -117,67 +153,108 @@ func (h *APIHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		h.writeError(ctx, w, "", http.StatusNotFound)
 		return
 	}
+	ctx = withRequestInfo(ctx, req, handler)
+	if handler.CacheVary != nil {
+		for _, name := range handler.CacheVary.Headers {
+			w.Header().Add("Vary", http.CanonicalHeaderKey(name))
+		}
+	}
 
-	done := make(chan bool, 1)
-	var cacheEntry *cache.CacheEntry
+	if handler.StreamItem != nil {
+		h.serveStream(ctx, w, req, handler, params, startTime)
+		return
+	}
+
+	cacheEntry, err := h.callWithTimeout(ctx, w, req, &resp, handler, params)
+	if ctx.Err() == context.DeadlineExceeded {
+		h.writeTimeoutError(ctx, req, w)
+		return
+	}
+	if err != nil {
+		h.handleCallError(ctx, w, req, &resp, err, startTime)
+		return
+	}
+	h.writeResponse(ctx, cacheEntry, &resp, w, req, startTime)
+}
+
+// handleCallError writes the error returned by the handler's call, resp contains the business error
+func (h *APIHandler) handleCallError(ctx context.Context, w http.ResponseWriter, req *http.Request, resp *HttpSessionResponse,
+	err *gorpc.CallHandlerError, startTime time.Time) {
+
+	if err.Type != gorpc.ErrorPanic && h.callbacks.OnError != nil {
+		h.callbacks.OnError(ctx, w, req, resp, err)
+	}
+	switch err.Type {
+	case gorpc.ErrorInParameters:
+		if paramErrs := err.ParameterErrors(); paramErrs != nil {
+			h.writeParameterErrors(ctx, w, paramErrs)
+			break
+		}
+		h.writeError(ctx, w, err.UserMessage(), http.StatusBadRequest)
+	case gorpc.ErrorReturnedFromCall:
+		// handle ErrorReturnedFromCall (business error returned from handler) as successful result, the status can be
+		// set for the error
+		h.writeBusinessError(ctx, resp, h.errorStatus(err), w, req, startTime)
+	default:
+		h.writeInternalError(ctx, w, err.Error())
+	}
+}
+
+type callResult struct {
+	cacheEntry *cache.CacheEntry
+	err        *gorpc.CallHandlerError
+}
+
+// callWithTimeout calls the handler in a separate goroutine and waits for it or for the context's end.
+// Caller must check ctx.Err() after the call, results are empty if the context was done first.
+func (h *APIHandler) callWithTimeout(ctx context.Context, w http.ResponseWriter, req *http.Request, resp *HttpSessionResponse,
+	handler gorpc.HandlerVersion, params reflect.Value) (*cache.CacheEntry, *gorpc.CallHandlerError) {
+
+	done := make(chan callResult, 1)
 
 	go func() {
+		var res callResult
 		defer func() {
 			if r := recover(); r != nil {
-				trace := make([]byte, 16*1024)
-				n := runtime.Stack(trace, false)
-				trace = trace[:n]
-
-				if h.callbacks.OnPanic != nil {
-					h.callbacks.OnPanic(ctx, w, r, trace, req)
-				}
-				err = &gorpc.CallHandlerError{
-					Type: gorpc.ErrorPanic,
-					Err:  fmt.Errorf("Panic in handler:\n%#v\n\n%s", r, string(trace)),
-				}
+				res.err = h.recoverPanic(ctx, w, req, r)
 			}
-			done <- true
+			done <- res
 		}()
-		cacheEntry, err = h.callHandlerWithCache(ctx, &resp, req, handler, params)
+		res.cacheEntry, res.err = h.callHandlerWithCache(ctx, resp, req, handler, params)
 	}()
 
 	// Wait handler or ctx timeout
 	select {
 	case <-ctx.Done():
-	case <-done:
+		return nil, nil
+	case res := <-done:
+		return res.cacheEntry, res.err
 	}
+}
 
-	if ctx.Err() == context.DeadlineExceeded {
-		h.writeTimeoutError(ctx, req, w)
-		return
-	}
-	if err != nil {
-		if err.Type != gorpc.ErrorPanic && h.callbacks.OnError != nil {
-			h.callbacks.OnError(ctx, w, req, &resp, err)
-		}
-		switch err.Type {
-		case gorpc.ErrorInParameters:
-			h.writeError(ctx, w, err.UserMessage(), http.StatusBadRequest)
-		case gorpc.ErrorReturnedFromCall:
-			// handle ErrorReturnedFromCall (business error returned from handler) as successful result
-			h.writeBusinessError(ctx, &resp, w, req, startTime)
-		default:
-			h.writeInternalError(ctx, w, err.Error())
+// recoverPanic converts the recovered panic of the handler into the error
+func (h *APIHandler) recoverPanic(ctx context.Context, w http.ResponseWriter, req *http.Request, r interface{}) *gorpc.CallHandlerError {
+	return gorpc.RecoverPanic(r, func(r interface{}, trace []byte) {
+		if h.callbacks.OnPanic != nil {
+			h.callbacks.OnPanic(ctx, w, r, trace, req)
 		}
-		return
-	}
-	h.writeResponse(ctx, cacheEntry, &resp, w, req, startTime)
+	})
 }
 
 func (h *APIHandler) CanServe(req *http.Request) bool {
 	path := req.URL.Path
+	if h.batchPath != "" && path == h.batchPath {
+		return true
+	}
 	handler := h.hm.FindHandlerByRoute(path)
 	return handler != nil
 }
 
-func (h *APIHandler) parseRequest(ctx context.Context, req *http.Request) (gorpc.HandlerVersion, reflect.Value, *gorpc.CallHandlerError) {
+// parseRequest finds the handler and unmarshals its parameters. The returned closer releases uploaded files, it must be
+// closed after the handler's call.
+func (h *APIHandler) parseRequest(ctx context.Context, req *http.Request) (gorpc.HandlerVersion, reflect.Value, io.Closer, *gorpc.CallHandlerError) {
 	if err := req.ParseForm(); err != nil {
-		return nil, reflect.ValueOf(nil), &gorpc.CallHandlerError{
+		return nil, reflect.ValueOf(nil), nil, &gorpc.CallHandlerError{
 			Type: gorpc.ErrorInParameters,
 			Err:  err,
 		}
@@ -185,48 +262,56 @@ func (h *APIHandler) parseRequest(ctx context.Context, req *http.Request) (gorpc
 
 	handler := h.hm.FindHandlerByRoute(req.URL.Path)
 	if handler == nil {
-		return nil, reflect.ValueOf(nil), nil
+		return nil, reflect.ValueOf(nil), nil, nil
 	}
 
 	var paramsGetter gorpc.IHandlerParameters = &ParametersGetter{Req: req}
 
-	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+	if codec := h.requestCodec(req); codec != nil {
 		if req.Method != "POST" {
-			return nil, reflect.ValueOf(nil), &gorpc.CallHandlerError{
+			return nil, reflect.ValueOf(nil), nil, &gorpc.CallHandlerError{
 				Type: gorpc.ErrorInvalidMethod,
 				Err:  errors.New(http.StatusText(http.StatusBadRequest)),
 			}
 		}
 
-		paramsGetter = &JsonParametersGetter{Req: req.Body}
+		paramsGetter = codec.NewParametersGetter(req.Body)
 	}
 
 	if strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data") {
 		if req.Method != "POST" {
-			return nil, reflect.ValueOf(nil), &gorpc.CallHandlerError{
+			return nil, reflect.ValueOf(nil), nil, &gorpc.CallHandlerError{
 				Type: gorpc.ErrorInvalidMethod,
 				Err:  errors.New(http.StatusText(http.StatusBadRequest)),
 			}
 		}
 
-		var err error
-		paramsGetter, err = NewMultipartGetter(req)
+		multipartGetter, err := NewMultipartGetter(req)
 		if err != nil {
-			return nil, reflect.ValueOf(nil), &gorpc.CallHandlerError{
+			return nil, reflect.ValueOf(nil), nil, &gorpc.CallHandlerError{
 				Type: gorpc.ErrorUnknown,
 				Err:  err,
 			}
 		}
+		multipartGetter.MaxSize = handler.MaxUploadSize
+		paramsGetter = multipartGetter
 	}
+	closer, _ := paramsGetter.(io.Closer)
 
 	params, err := h.hm.UnmarshalParameters(ctx, handler, paramsGetter)
 	if err != nil {
-		return nil, reflect.ValueOf(nil), &gorpc.CallHandlerError{
+		if closer != nil {
+			closer.Close()
+		}
+		if callErr, ok := err.(*gorpc.CallHandlerError); ok {
+			return nil, reflect.ValueOf(nil), nil, callErr
+		}
+		return nil, reflect.ValueOf(nil), nil, &gorpc.CallHandlerError{
 			Type: gorpc.ErrorInParameters,
 			Err:  err,
 		}
 	}
-	return handler, params, nil
+	return handler, params, closer, nil
 }
 
 func (h *APIHandler) callHandlerWithCache(ctx context.Context, resp *HttpSessionResponse, req *http.Request, handler gorpc.HandlerVersion, params reflect.Value) (cacheEntry *cache.CacheEntry, err *gorpc.CallHandlerError) {
@@ -235,15 +320,29 @@ func (h *APIHandler) callHandlerWithCache(ctx context.Context, resp *HttpSession
 		return h.callHandler(ctx, cacheKey, resp, req, handler, params)
 	}
 
+	// the stale entry is served without waiting for the lock which is held by its refresh
+	if cacheEntry = h.cache.Get(cacheKey); cacheEntry != nil && cacheEntry.IsStale(time.Now()) {
+		h.refresh(ctx, cacheKey, req, handler, params)
+		if h.callbacks.OnCacheHit != nil {
+			h.callbacks.OnCacheHit(ctx, cacheEntry)
+		}
+		return cacheEntry, nil
+	}
+
 	h.cache.Lock(cacheKey)
 	defer h.cache.Unlock(cacheKey)
 	cacheEntry = h.cache.Get(cacheKey)
+	var staleEntry *cache.CacheEntry
 	if cacheEntry != nil {
-		if h.callbacks.OnCacheHit != nil {
-			h.callbacks.OnCacheHit(ctx, cacheEntry)
+		if cacheEntry.IsFresh(time.Now()) {
+			if h.callbacks.OnCacheHit != nil {
+				h.callbacks.OnCacheHit(ctx, cacheEntry)
+			}
+			return
+		}
+		if cacheEntry.IsStaleIfError(time.Now()) {
+			staleEntry = cacheEntry
 		}
-
-		return
 	}
 	if h.callbacks.OnCacheMiss != nil {
 		h.callbacks.OnCacheMiss(ctx)
@@ -251,21 +350,142 @@ func (h *APIHandler) callHandlerWithCache(ctx context.Context, resp *HttpSession
 
 	cacheEntry, err = h.callHandler(ctx, cacheKey, resp, req, handler, params)
 	if err != nil {
+		// stale-if-error, business errors and invalid parameters are returned as is
+		if staleEntry != nil && err.Type != gorpc.ErrorReturnedFromCall && err.Type != gorpc.ErrorInParameters {
+			return staleEntry, nil
+		}
 		return
 	}
 
-	if cache.IsTransportCacheEnabled(ctx) {
-		if cache.IsETagEnabled(ctx) {
-			cacheEntry.Hash, _ = cache.ETagHash(cacheEntry.Content)
+	h.putCacheEntry(ctx, cacheKey, cacheEntry)
+	return
+}
+
+// putCacheEntry puts the entry if the handler enabled the transport cache. The entry with stale windows is kept
+// in the cache for TTL and the longest window.
+func (h *APIHandler) putCacheEntry(ctx context.Context, cacheKey []byte, cacheEntry *cache.CacheEntry) {
+	if !cache.IsTransportCacheEnabled(ctx) {
+		return
+	}
+
+	if cache.IsETagEnabled(ctx) {
+		cacheEntry.Hash, _ = cache.ETagHash(cacheEntry.Content)
+	}
+	// Expires is set by the call already
+	ttl := cache.TTL(ctx)
+	staleTTL, staleIfErrorTTL := cache.StaleTTL(ctx), cache.StaleIfErrorTTL(ctx)
+	if (staleTTL > 0 || staleIfErrorTTL > 0) && ttl > 0 {
+		cacheEntry.StaleUntil = cacheEntry.Expires.Add(staleTTL)
+		cacheEntry.StaleIfErrorUntil = cacheEntry.Expires.Add(staleIfErrorTTL)
+		if staleIfErrorTTL > staleTTL {
+			staleTTL = staleIfErrorTTL
 		}
-		ttl := cache.TTL(ctx)
-		if p, ok := h.cache.(cache.TTLAwareCachePutter); ok && ttl > 0 {
-			p.PutWithTTL(cacheKey, cacheEntry, ttl)
-		} else {
-			h.cache.Put(cacheKey, cacheEntry)
+		ttl += staleTTL
+	}
+	if p, ok := h.cache.(cache.TagAwareCache); ok && len(cache.Tags(ctx)) > 0 {
+		p.PutWithTags(cacheKey, cacheEntry, ttl, cache.Tags(ctx))
+	} else if p, ok := h.cache.(cache.TTLAwareCachePutter); ok && ttl > 0 {
+		p.PutWithTTL(cacheKey, cacheEntry, ttl)
+	} else {
+		h.cache.Put(cacheKey, cacheEntry)
+	}
+}
+
+// refresh calls the handler of the stale entry in background, only one refresh of the key runs at the same time.
+// The call gets a copy of the request and of the params with the context detached from the request's one, so it
+// isn't canceled when the response is sent. Callbacks receive nil ResponseWriter.
+func (h *APIHandler) refresh(ctx context.Context, cacheKey []byte, req *http.Request, handler gorpc.HandlerVersion, params reflect.Value) {
+	if _, loaded := h.refreshing.LoadOrStore(string(cacheKey), struct{}{}); loaded {
+		return
+	}
+
+	refreshCtx := withResponseHeaders(cache.NewContext(detachedContext{ctx}))
+	req = req.Clone(refreshCtx)
+	req.Body = http.NoBody
+	refreshCtx = withRequestInfo(refreshCtx, req, handler)
+	params = copyValue(params)
+
+	go func() {
+		defer h.refreshing.Delete(string(cacheKey))
+
+		ctx := refreshCtx
+		if h.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, h.timeout)
+			defer cancel()
+		}
+
+		h.cache.Lock(cacheKey)
+		defer h.cache.Unlock(cacheKey)
+
+		var resp HttpSessionResponse
+		cacheEntry, err := func() (cacheEntry *cache.CacheEntry, err *gorpc.CallHandlerError) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = h.recoverPanic(ctx, nil, req, r)
+				}
+			}()
+			return h.callHandler(ctx, cacheKey, &resp, req, handler, params)
+		}()
+		if err != nil {
+			if err.Type != gorpc.ErrorPanic && h.callbacks.OnError != nil {
+				h.callbacks.OnError(ctx, nil, req, &resp, err)
+			}
+			return
+		}
+		h.putCacheEntry(ctx, cacheKey, cacheEntry)
+	}()
+}
+
+// detachedContext keeps values of the parent, but it's never canceled
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// copyValue returns the deep copy of the value, so the handler called in background doesn't share params with
+// the request. Unexported fields are copied shallowly.
+func copyValue(v reflect.Value) reflect.Value {
+	res := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			res.Set(reflect.New(v.Type().Elem()))
+			res.Elem().Set(copyValue(v.Elem()))
 		}
+	case reflect.Struct:
+		res.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if res.Field(i).CanSet() {
+				res.Field(i).Set(copyValue(v.Field(i)))
+			}
+		}
+	case reflect.Slice:
+		if !v.IsNil() {
+			res.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+			for i := 0; i < v.Len(); i++ {
+				res.Index(i).Set(copyValue(v.Index(i)))
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			res.Index(i).Set(copyValue(v.Index(i)))
+		}
+	case reflect.Map:
+		if !v.IsNil() {
+			res.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+			for _, key := range v.MapKeys() {
+				res.SetMapIndex(key, copyValue(v.MapIndex(key)))
+			}
+		}
+	default:
+		res.Set(v)
 	}
-	return
+	return res
 }
 
 func (h *APIHandler) callHandler(ctx context.Context, cacheKey []byte, resp *HttpSessionResponse, req *http.Request, handler gorpc.HandlerVersion, params reflect.Value) (*cache.CacheEntry, *gorpc.CallHandlerError) {
@@ -285,35 +505,89 @@ func (h *APIHandler) callHandler(ctx context.Context, cacheKey []byte, resp *Htt
 
 	resp.Result = "OK"
 	resp.Data = handlerResponse
-	if debugObj, ok := debug.GetDebugFromContext(ctx); ok {
-		resp.Debug = debugObj
+	var cacheEntry *cache.CacheEntry
+	if raw := rawResponse(handlerResponse); raw != nil {
+		resp.Data = raw
+		cacheEntry, err = h.createRawCacheEntry(ctx, raw, cacheKey, req)
+	} else {
+		if debugObj, ok := debug.GetDebugFromContext(ctx); ok {
+			resp.Debug = debugObj
+		}
+		cacheEntry, err = h.createCacheEntry(ctx, resp, cacheKey, req)
 	}
-	return h.createCacheEntry(ctx, resp, cacheKey, req)
+	if err != nil {
+		return nil, err
+	}
+	setCacheMetadata(ctx, cacheEntry)
+	return cacheEntry, nil
 }
 
 func (h *APIHandler) getCacheKey(ctx context.Context, req *http.Request, handler gorpc.HandlerVersion, params reflect.Value) []byte {
-	if h.cache == nil {
+	// content of uploaded files isn't a part of the key
+	if h.cache == nil || handler.Request.Multipart {
 		return nil
 	}
 
 	if h.callbacks.GetCacheKey != nil {
 		if cacheKey := h.callbacks.GetCacheKey(ctx, req, params.Interface()); cacheKey != nil {
-			return cacheKey
+			return withCodecCacheKey(ctx, cacheKey)
 		}
 	}
 
 	buf := bytes.NewBufferString(handler.Route)
 	encoder := json.NewEncoder(buf)
-	err := encoder.Encode(params.Interface())
+	err := encoder.Encode(gorpc.CacheKeyParams(handler, params))
 	if err != nil {
 		// TODO: call callback.onError?
 		return nil
 	}
-	return buf.Bytes()
+	if err := writeCacheVary(ctx, buf, req, handler.CacheVary); err != nil {
+		// the response isn't cached if the key can't be built
+		return nil
+	}
+	return withCodecCacheKey(ctx, buf.Bytes())
+}
+
+// writeCacheVary appends values of headers and of the context which the response varies on to the key, headers are
+// written in the same way by generated clients. Context values are written as strings if they are strings or
+// fmt.Stringer and are encoded into JSON otherwise, so pointers are written by the values they point to.
+func writeCacheVary(ctx context.Context, buf *bytes.Buffer, req *http.Request, vary *gorpc.CacheVary) error {
+	if vary == nil {
+		return nil
+	}
+	for _, name := range vary.Headers {
+		fmt.Fprintf(buf, "\n%s:%s", http.CanonicalHeaderKey(name), strings.Join(req.Header.Values(name), ","))
+	}
+	for i, key := range vary.ContextKeys {
+		fmt.Fprintf(buf, "\nctx%d:", i)
+		switch value := ctx.Value(key).(type) {
+		case string:
+			buf.WriteString(value)
+		case fmt.Stringer:
+			buf.WriteString(value.String())
+		default:
+			data, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			buf.Write(data)
+		}
+	}
+	return nil
+}
+
+// withCodecCacheKey appends the media type of the response's codec to the key, so every encoding is cached
+// separately. Keys of JSON responses aren't changed.
+func withCodecCacheKey(ctx context.Context, key []byte) []byte {
+	codec := codecFromContext(ctx)
+	if _, ok := codec.(JSONCodec); ok {
+		return key
+	}
+	return append(append(key, '\n'), mediaType(codec.ContentType())...)
 }
 
 func (h *APIHandler) createCacheEntry(ctx context.Context, resp *HttpSessionResponse, cacheKey []byte, req *http.Request) (*cache.CacheEntry, *gorpc.CallHandlerError) {
-	content, err := resp.MarshalJSON()
+	content, err := codecFromContext(ctx).Marshal(resp)
 	if err != nil {
 		return nil, &gorpc.CallHandlerError{
 			Type: gorpc.ErrorWriteResponse,
@@ -322,6 +596,7 @@ func (h *APIHandler) createCacheEntry(ctx context.Context, resp *HttpSessionResp
 	}
 	cacheEntry := cache.CacheEntry{
 		Content: content,
+		Header:  responseHeadersFromContext(ctx),
 	}
 	if len(content) > 4096 && cacheKey != nil && strings.Contains(req.Header.Get("Accept-Encoding"), "gzip") {
 		buf := new(bytes.Buffer)
@@ -341,9 +616,13 @@ func (h *APIHandler) writeResponse(ctx context.Context, cacheEntry *cache.CacheE
 		h.callbacks.OnBeforeWriteResponse(ctx, w)
 	}
 
-	if cacheEntry != nil && cacheEntry.Hash != "" {
-		w.Header().Set("Etag", cacheEntry.Hash)
-		if cacheEntry.Hash == req.Header.Get("If-None-Match") {
+	if cacheEntry != nil {
+		writeHeaders(w, cacheEntry.Header)
+		writeCacheHeaders(w, cacheEntry)
+		if isNotModified(req, cacheEntry) {
+			if closer, ok := cacheEntry.Reader.(io.Closer); ok {
+				closer.Close()
+			}
 			w.WriteHeader(http.StatusNotModified)
 			if h.callbacks.On304 != nil {
 				h.callbacks.On304(ctx, req)
@@ -355,12 +634,21 @@ func (h *APIHandler) writeResponse(ctx context.Context, cacheEntry *cache.CacheE
 		}
 	}
 
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if cacheEntry != nil && cacheEntry.ContentType != "" {
+		w.Header().Set("Content-Type", cacheEntry.ContentType)
+	} else {
+		h.setContentType(ctx, w)
+	}
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	var err error
 	if cacheEntry != nil {
-		if cacheEntry.CompressedContent != nil && strings.Contains(req.Header.Get("Accept-Encoding"), "gzip") {
+		if cacheEntry.Reader != nil {
+			_, err = io.Copy(w, cacheEntry.Reader)
+			if closer, ok := cacheEntry.Reader.(io.Closer); ok {
+				closer.Close()
+			}
+		} else if cacheEntry.CompressedContent != nil && strings.Contains(req.Header.Get("Accept-Encoding"), "gzip") {
 			w.Header().Set("Content-Encoding", "gzip")
 			_, err = w.Write(cacheEntry.CompressedContent)
 		} else if cacheEntry.Content == nil && cacheEntry.CompressedContent != nil {
@@ -390,18 +678,20 @@ func (h *APIHandler) writeResponse(ctx context.Context, cacheEntry *cache.CacheE
 	}
 }
 
-func (h *APIHandler) writeBusinessError(ctx context.Context, resp *HttpSessionResponse,
+func (h *APIHandler) writeBusinessError(ctx context.Context, resp *HttpSessionResponse, status int,
 	w http.ResponseWriter, req *http.Request, startTime time.Time) {
 
 	if h.callbacks.OnBeforeWriteResponse != nil {
 		h.callbacks.OnBeforeWriteResponse(ctx, w)
 	}
 
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	writeHeaders(w, responseHeadersFromContext(ctx))
+	h.setContentType(ctx, w)
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	data, err := resp.MarshalJSON()
+	data, err := codecFromContext(ctx).Marshal(resp)
 	if err == nil {
+		w.WriteHeader(status)
 		_, err = w.Write(data)
 	}
 	if err != nil {
@@ -431,6 +721,40 @@ func (h *APIHandler) writeError(ctx context.Context, w http.ResponseWriter, err
 	http.Error(w, err, code)
 }
 
+// writeParameterErrors writes the list of invalid parameters with Bad Request status, e.g.:
+//
+//	{"result": "ERROR", "error": "INVALID_PARAMETERS", "data": [{"path": "items[1].id", "code": "MIN", "message": "..."}]}
+func (h *APIHandler) writeParameterErrors(ctx context.Context, w http.ResponseWriter, errs gorpc.ParameterErrors) {
+	if h.callbacks.OnBeforeWriteResponse != nil {
+		h.callbacks.OnBeforeWriteResponse(ctx, w)
+	}
+
+	resp := HttpSessionResponse{
+		Result: "ERROR",
+		Data:   errs,
+		Error:  ErrorInvalidParameters,
+	}
+	data, err := codecFromContext(ctx).Marshal(&resp)
+	if err != nil {
+		http.Error(w, errs.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.setContentType(ctx, w)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write(data)
+}
+
+// setContentType sets the content type of the negotiated codec, the response varies by Accept header if there are
+// several codecs
+func (h *APIHandler) setContentType(ctx context.Context, w http.ResponseWriter) {
+	w.Header().Set("Content-Type", codecFromContext(ctx).ContentType())
+	if len(h.codecs) > 1 {
+		w.Header().Add("Vary", "Accept")
+	}
+}
+
 func (h *APIHandler) writeInternalError(ctx context.Context, w http.ResponseWriter, err string) {
 	if PrintDebug {
 		h.writeError(ctx, w, http.StatusText(http.StatusInternalServerError)+":\n"+err, http.StatusInternalServerError)
@@ -458,3 +782,31 @@ func (h *APIHandler) SetTimeout(timeout time.Duration) *APIHandler {
 	h.timeout = timeout
 	return h
 }
+
+// InvalidateTags removes cached responses with any of the tags set by cache.AddTags, it returns false if the cache
+// isn't cache.TagAwareCache
+func (h *APIHandler) InvalidateTags(tags ...string) bool {
+	return cache.InvalidateTags(h.cache, tags...)
+}
+
+// SetErrorStatus sets the HTTP status of business errors with the code which don't have "status" tag in ErrorsVar,
+// e.g. SetErrorStatus("NOT_FOUND", http.StatusNotFound). The body of the response is the same as for 200 OK.
+// Swagger lists statuses from tags only.
+func (h *APIHandler) SetErrorStatus(code string, status int) *APIHandler {
+	if h.errorStatuses == nil {
+		h.errorStatuses = make(map[string]int)
+	}
+	h.errorStatuses[code] = status
+	return h
+}
+
+// errorStatus returns the HTTP status of the business error
+func (h *APIHandler) errorStatus(err *gorpc.CallHandlerError) int {
+	if status := err.HTTPStatus(); status != 0 {
+		return status
+	}
+	if status, ok := h.errorStatuses[err.ErrorCode()]; ok {
+		return status
+	}
+	return http.StatusOK
+}
@@ -1,9 +1,11 @@
 package http_json
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/sergei-svistunov/gorpc"
@@ -52,6 +54,33 @@ func (s *HttpJSONSute) TestHttpJSON_CallWithoutRequiredArguments_BadRequest() {
 	s.Equal(400, resp.StatusCode)
 }
 
+func (s *HttpJSONSute) TestHttpJSON_CallWithInvalidArguments_ParameterErrors() {
+	s.server.Start()
+	defer s.server.Close()
+
+	resp, err := http.Post(s.server.URL+"/test/handler1/v6/", "application/json",
+		strings.NewReader(`{"f1": [[{"f11": {"f111": "test"}}, {"f11": {"f111": 1}}], [{"f11": {"f111": false}}]]}`))
+	s.NoError(err)
+	defer resp.Body.Close()
+	s.Equal(400, resp.StatusCode)
+	s.Equal("application/json; charset=utf-8", resp.Header.Get("Content-Type"))
+
+	var body struct {
+		Result string
+		Error  string
+		Data   []gorpc.ParameterError
+	}
+	s.NoError(json.NewDecoder(resp.Body).Decode(&body))
+	s.Equal("ERROR", body.Result)
+	s.Equal(ErrorInvalidParameters, body.Error)
+	if s.Len(body.Data, 2) {
+		s.Equal("f1[0][1].f11.f111", body.Data[0].Path)
+		s.Equal(gorpc.ParameterErrorInvalidValue, body.Data[0].Code)
+		s.Equal("f1[1][0].f11.f111", body.Data[1].Path)
+		s.Equal(gorpc.ParameterErrorInvalidValue, body.Data[1].Code)
+	}
+}
+
 func (s *HttpJSONSute) TestHttpJSON_CallWithOptionalSlice_OneElement_Success() {
 	s.server.Start()
 	defer s.server.Close()
@@ -83,3 +112,26 @@ func BenchmarkHttpJSON_CallWithRequiredArguments_Success(b *testing.B) {
 		handler.ServeHTTP(recorder, request)
 	}
 }
+
+func TestHttpJSON_StrictParameters(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{}).SetStrictParameters(true)
+	if err := hm.RegisterHandler(test_handler1.NewHandler()); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewAPIHandler(hm, nil, APIHandlerCallbacks{})
+
+	for url, status := range map[string]int{
+		"/test/handler1/v1/?req_int=1":             http.StatusOK,
+		"/test/handler1/v1/?req_int=1&debug=true":  http.StatusOK,
+		"/test/handler1/v1/?req_int=1&reqInt=1":    http.StatusBadRequest,
+		"/test/handler1/v1/?req_int=1&int=2&i=3":   http.StatusBadRequest,
+		"/test/handler1/v1/?req_int=1&int=2&int=3": http.StatusOK,
+	} {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest("GET", url, nil)
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != status {
+			t.Errorf("%s: expected status %d, got %d: %s", url, status, recorder.Code, recorder.Body.String())
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	"strconv"
+	"time"
 )
 
 const defaultMaxFormSize = int64(10 << 20) // 10 MB is a lot of text.
@@ -124,6 +125,36 @@ func (p *JsonParametersGetter) GetFloat64(path []string, name string) (float64,
 	return n.Float64()
 }
 
+func (p *JsonParametersGetter) GetTime(path []string, name string) (time.Time, error) {
+	v, _ := p.get(path, name)
+	if s, ok := v.(string); ok {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New(`Wrong value of param "` + name + `". It must be time in RFC3339 format`)
+}
+
+func (p *JsonParametersGetter) GetDuration(path []string, name string) (time.Duration, error) {
+	v, _ := p.get(path, name)
+	switch v := v.(type) {
+	case string:
+		if d, err := time.ParseDuration(v); err == nil {
+			return d, nil
+		}
+	case json.Number:
+		if ns, err := v.Int64(); err == nil {
+			return time.Duration(ns), nil
+		}
+	}
+	return 0, errors.New(`Wrong value of param "` + name + `". It must be duration`)
+}
+
+func (p *JsonParametersGetter) GetRawJSON(path []string, name string) ([]byte, error) {
+	v, _ := p.get(path, name)
+	return json.Marshal(v)
+}
+
 func (p *JsonParametersGetter) getNumber(path []string, name string) (json.Number, error) {
 	v, _ := p.get(path, name)
 	if n, ok := v.(json.Number); ok {
@@ -172,6 +203,19 @@ func (p *JsonParametersGetter) TraverseMap(path []string, name string, h func(k
 	return false, nil
 }
 
+func (p *JsonParametersGetter) Keys(path []string) []string {
+	var v interface{} = p.values
+	if len(path) > 0 {
+		v, _ = p.get(path[:len(path)-1], path[len(path)-1])
+	}
+	m, _ := v.(map[string]interface{})
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
 func (p *JsonParametersGetter) get(path []string, name string) (interface{}, bool) {
 	var m interface{}
 	m = p.values
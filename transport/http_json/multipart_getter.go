@@ -3,16 +3,27 @@ package http_json
 import (
 	"fmt"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
+	"sort"
 	"strings"
+
+	"github.com/sergei-svistunov/gorpc"
 )
 
 const (
-	multipartMaxMemorySize = 1024 * 1024 * 512
+	// multipartMaxMemorySize is the size of the form kept in memory, larger files are stored in temporary files
+	multipartMaxMemorySize = 32 << 20
 )
 
 type MultipartGetter struct {
 	ParametersGetter
+	// MaxSize limits the size of the request body, 0 means no limit
+	MaxSize int64
+
+	// files are uploaded files by keys in brackets notation with the lowercased name of the top level parameter
+	files  map[string][]*multipart.FileHeader
+	opened []multipart.File
 }
 
 func NewMultipartGetter(req *http.Request) (*MultipartGetter, error) {
@@ -27,17 +38,23 @@ func NewMultipartGetter(req *http.Request) (*MultipartGetter, error) {
 	}, nil
 }
 
-// Add upload files to Params like a string
-// params["{inputName}"] = string(file)
-// params["filename_{inputName}"] = string(filename)
+// Parse parses the form, uploaded files are received by parameters of gorpc.File type.
+//
+// For compatibility files can be received as strings with the whole content, their names are passed as
+// "filename_{inputName}" parameters:
 //
-// Example:
-// type MyData struct {
-//     File     string `key:"my_file"`
-//     Filename string `key:"filename_my_file"`
-// }
+//	type MyData struct {
+//		File     string `key:"my_file"`
+//		Filename string `key:"filename_my_file"`
+//	}
 func (g *MultipartGetter) Parse() error {
+	if g.MaxSize > 0 {
+		g.Req.Body = http.MaxBytesReader(nil, g.Req.Body, g.MaxSize)
+	}
 	if err := g.Req.ParseMultipartForm(multipartMaxMemorySize); err != nil {
+		if g.MaxSize > 0 && strings.Contains(err.Error(), "request body too large") {
+			return fmt.Errorf("Request body is larger than %d bytes", g.MaxSize)
+		}
 		return fmt.Errorf("ParseMultipartForm %v", err)
 	}
 
@@ -45,27 +62,149 @@ func (g *MultipartGetter) Parse() error {
 		return fmt.Errorf("parseForm %v", err)
 	}
 
-	for key, files := range g.Req.MultipartForm.File {
+	// keys are sorted to keep the order of files uploaded with "key" and "key[]" the same every time
+	keys := make([]string, 0, len(g.Req.MultipartForm.File))
+	for key := range g.Req.MultipartForm.File {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	g.files = make(map[string][]*multipart.FileHeader, len(keys))
+	for _, key := range keys {
+		files := g.Req.MultipartForm.File[key]
 		if len(files) == 0 {
 			continue
 		}
 
-		file := files[0]
+		fileKey := canonicalKey(splitKey(key))
+		g.files[fileKey] = append(g.files[fileKey], files...)
+		g.set("filename_"+key, files[0].Filename)
+	}
+
+	return nil
+}
+
+func (g *MultipartGetter) IsExists(path []string, name string) bool {
+	return len(g.fileHeaders(path, name)) > 0 || g.ParametersGetter.IsExists(path, name)
+}
+
+// GetString returns the content of the first file uploaded with the key or the value of the parameter
+func (g *MultipartGetter) GetString(path []string, name string) (string, error) {
+	files := g.fileHeaders(path, name)
+	if len(files) == 0 {
+		return g.ParametersGetter.GetString(path, name)
+	}
+
+	f, err := files[0].Open()
+	if err != nil {
+		return "", fmt.Errorf("file open %v", err)
+	}
+	defer f.Close()
+
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return "", fmt.Errorf("file readall %v", err)
+	}
+	return string(b), nil
+}
 
-		f, err := file.Open()
+// GetFiles opens the files uploaded with the key, they are closed by Close
+func (g *MultipartGetter) GetFiles(path []string, name string) ([]*gorpc.File, error) {
+	headers := g.fileHeaders(path, name)
+	files := make([]*gorpc.File, 0, len(headers))
+	for _, header := range headers {
+		f, err := header.Open()
 		if err != nil {
-			return fmt.Errorf("file open %v", err)
+			return nil, fmt.Errorf("file open %v", err)
 		}
+		g.opened = append(g.opened, f)
 
-		b, err := ioutil.ReadAll(f)
-		if err != nil {
-			return fmt.Errorf("file readall %v", err)
+		files = append(files, &gorpc.File{
+			ReadSeeker:  f,
+			Name:        header.Filename,
+			Size:        header.Size,
+			ContentType: header.Header.Get("Content-Type"),
+		})
+	}
+	return files, nil
+}
+
+// Close closes the files opened by GetFiles and removes temporary files of the form
+func (g *MultipartGetter) Close() error {
+	for _, f := range g.opened {
+		f.Close()
+	}
+	g.opened = nil
+
+	if g.Req.MultipartForm == nil {
+		return nil
+	}
+	return g.Req.MultipartForm.RemoveAll()
+}
+
+// Keys returns names of the passed parameters and files, "filename_" keys are added by Parse and aren't returned
+func (g *MultipartGetter) Keys(path []string) []string {
+	keys := g.ParametersGetter.Keys(path)
+	if g.root != nil || g.Req.MultipartForm == nil {
+		return keys
+	}
+
+	filenameKeys := make(map[string]bool, len(g.Req.MultipartForm.File))
+	if len(path) == 0 {
+		for k := range g.Req.MultipartForm.File {
+			filenameKeys[strings.ToLower(splitKey("filename_" + k)[0])] = true
+		}
+	}
+	res := keys[:0]
+	for _, k := range keys {
+		if !filenameKeys[k] {
+			res = append(res, k)
+		}
+	}
+
+	// files of the nested object are keys which are one level deeper than the path
+	prefix := canonicalKey(path)
+	for fileKey := range g.files {
+		var name string
+		if len(path) == 0 {
+			name = strings.SplitN(fileKey, "[", 2)[0]
+		} else if strings.HasPrefix(fileKey, prefix+"[") {
+			name = strings.SplitN(fileKey[len(prefix)+1:], "]", 2)[0]
+		} else {
+			continue
+		}
+		if !containsString(res, name) {
+			res = append(res, name)
 		}
+	}
+	return res
+}
 
-		key = strings.ToLower(key)
-		g.values.Set(key, string(b))
-		g.values.Set("filename_"+key, file.Filename)
+// fileHeaders returns the files uploaded with the key. Files can't be elements of slices or maps of objects.
+func (g *MultipartGetter) fileHeaders(path []string, name string) []*multipart.FileHeader {
+	if g.root != nil {
+		return nil
 	}
+	return g.files[canonicalKey(append(path[:len(path):len(path)], name))]
+}
 
-	return nil
+// canonicalKey writes the path in brackets notation with the lowercased name of the top level parameter
+func canonicalKey(path []string) string {
+	if len(path) == 0 {
+		return ""
+	}
+	key := strings.ToLower(path[0])
+	for _, k := range path[1:] {
+		key += "[" + k + "]"
+	}
+	return key
+}
+
+func containsString(slice []string, s string) bool {
+	for _, v := range slice {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
@@ -3,13 +3,20 @@ package http_json
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/sergei-svistunov/gorpc/transport/cache"
+
+	test_handler_upload "github.com/sergei-svistunov/gorpc/test/handler_upload"
 )
 
 func prepareRequest(t testing.TB, filename string, size int) (*http.Request, error) {
@@ -90,3 +97,127 @@ func bench(b *testing.B, size int) {
 func BenchmarkMultipartGetter_Parse1Mb(b *testing.B)   { bench(b, 1000000) }
 func BenchmarkMultipartGetter_Parse10Mb(b *testing.B)  { bench(b, 10000000) }
 func BenchmarkMultipartGetter_Parse100Mb(b *testing.B) { bench(b, 100000000) }
+
+type uploadPart struct {
+	key, filename, content string
+}
+
+func postMultipart(t *testing.T, url string, parts ...uploadPart) (int, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for _, part := range parts {
+		if part.filename == "" {
+			assert.NoError(t, writer.WriteField(part.key, part.content))
+			continue
+		}
+		w, err := writer.CreateFormFile(part.key, part.filename)
+		if !assert.NoError(t, err) {
+			return 0, ""
+		}
+		w.Write([]byte(part.content))
+	}
+	assert.NoError(t, writer.Close())
+
+	resp, err := http.Post(url, writer.FormDataContentType(), body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(respBody)
+}
+
+func newUploadTestServer(t *testing.T) *httptest.Server {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	if err := hm.RegisterHandler(test_handler_upload.NewHandler()); err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(NewAPIHandler(hm, cache.NewMapCache(), APIHandlerCallbacks{}))
+}
+
+func TestMultipartGetter_Files(t *testing.T) {
+	server := newUploadTestServer(t)
+	defer server.Close()
+
+	status, body := postMultipart(t, server.URL+"/test/handler_upload/v1/",
+		uploadPart{key: "title", content: "Docs"},
+		uploadPart{key: "avatar", filename: "me.png", content: "avatar"},
+		uploadPart{key: "attachments", filename: "a.txt", content: "first"},
+		uploadPart{key: "attachments", filename: "b.txt", content: "second"},
+	)
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"result": "OK", "error": "", "data": {"title": "Docs", "files": [
+		{"name": "me.png", "size": 6, "content_type": "application/octet-stream", "content": "avatar"},
+		{"name": "a.txt", "size": 5, "content_type": "application/octet-stream", "content": "first"},
+		{"name": "b.txt", "size": 6, "content_type": "application/octet-stream", "content": "second"}
+	]}}`, body)
+
+	status, body = postMultipart(t, server.URL+"/test/handler_upload/v1/",
+		uploadPart{key: "title", content: "Docs"},
+		uploadPart{key: "avatar", content: "not a file"},
+	)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body, `Wrong value of param \"avatar\": It must be file`)
+
+	status, body = postMultipart(t, server.URL+"/test/handler_upload/v1/",
+		uploadPart{key: "title", content: "Docs"},
+		uploadPart{key: "avatar", filename: "big.bin", content: strings.Repeat("x", 2<<20)},
+	)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body, "Request body is larger than 1048576 bytes")
+
+	resp, err := http.Post(server.URL+"/test/handler_upload/v1/", "application/json", strings.NewReader(`{"title": "Docs", "avatar": "x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Contains(t, string(respBody), "Files can be uploaded in multipart form only")
+}
+
+func TestMultipartGetter_LegacyStrings(t *testing.T) {
+	request, err := prepareRequest(t, "data.csv", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	getter, _ := NewMultipartGetter(request)
+	if !assert.NoError(t, getter.Parse()) {
+		return
+	}
+	defer getter.Close()
+
+	assert.True(t, getter.IsExists(nil, "file"))
+	content, err := getter.GetString(nil, "file")
+	assert.NoError(t, err)
+	assert.Len(t, content, 100)
+	filename, _ := getter.GetString(nil, "filename_file")
+	assert.Equal(t, "data.csv", filename)
+	assert.ElementsMatch(t, []string{"some field", "file"}, getter.Keys(nil))
+}
+
+func TestMultipartGetter_Swagger(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	if err := hm.RegisterHandler(test_handler_upload.NewHandler()); err != nil {
+		t.Fatal(err)
+	}
+
+	swagger, err := GenerateSwaggerJSON(hm, "", SwaggerJSONCallbacks{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pathItem := swagger.Paths["/test/handler_upload/v1/"]
+	if !assert.Len(t, pathItem, 1) || !assert.NotNil(t, pathItem["post"]) {
+		return
+	}
+	operation := pathItem["post"]
+	assert.Equal(t, []string{"multipart/form-data"}, operation.Consumes)
+
+	types := map[string]string{}
+	for _, p := range operation.Parameters {
+		assert.Equal(t, "formData", p.In)
+		types[p.Name] = p.Type
+	}
+	assert.Equal(t, map[string]string{"title": "string", "avatar": "file", "attachments": "file"}, types)
+}
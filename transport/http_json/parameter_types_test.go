@@ -0,0 +1,98 @@
+package http_json
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sergei-svistunov/gorpc"
+	test_handler_types "github.com/sergei-svistunov/gorpc/test/handler_types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParameterTypes_Json(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	if !assert.NoError(t, hm.RegisterHandler(test_handler_types.NewHandler())) {
+		return
+	}
+
+	pg := &JsonParametersGetter{Req: ioutil.NopCloser(strings.NewReader(`{
+		"since": "2020-01-02T03:04:05Z",
+		"timeout": "1m30s",
+		"color": "red",
+		"point": [1, 2],
+		"times": ["2021-01-01T00:00:00+03:00"],
+		"timeouts": {"read": "1s", "write": 2000000000}
+	}`))}
+
+	params, err := hm.UnmarshalParameters(context.TODO(), hm.FindHandler("/test/handler_types", 1), pg)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	args := params.Interface().(*test_handler_types.V1Args)
+	assert.True(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC).Equal(args.Since))
+	assert.Equal(t, 90*time.Second, *args.Timeout)
+	assert.Equal(t, test_handler_types.Color("RED"), *args.Color)
+	assert.Equal(t, test_handler_types.Point{1, 2}, *args.Point)
+	if assert.Len(t, args.Times, 1) {
+		assert.True(t, time.Date(2020, 12, 31, 21, 0, 0, 0, time.UTC).Equal(args.Times[0]))
+	}
+	assert.Equal(t, map[string]time.Duration{"read": time.Second, "write": 2 * time.Second}, *args.Timeouts)
+}
+
+func TestParameterTypes_Query(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	if !assert.NoError(t, hm.RegisterHandler(test_handler_types.NewHandler())) {
+		return
+	}
+
+	values := url.Values{
+		"since":   {"2020-01-02T03:04:05Z"},
+		"timeout": {"1500000000"},
+		"color":   {"blue"},
+		"point":   {"[3,4]"},
+		"times":   {"2020-01-01T00:00:00Z", "2021-01-01T00:00:00Z"},
+	}
+	req, _ := http.NewRequest("GET", "/test/handler_types/v1/?"+values.Encode(), nil)
+
+	params, err := hm.UnmarshalParameters(context.TODO(), hm.FindHandler("/test/handler_types", 1), &ParametersGetter{Req: req})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	args := params.Interface().(*test_handler_types.V1Args)
+	assert.True(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC).Equal(args.Since))
+	assert.Equal(t, 1500*time.Millisecond, *args.Timeout)
+	assert.Equal(t, test_handler_types.Color("BLUE"), *args.Color)
+	assert.Equal(t, test_handler_types.Point{3, 4}, *args.Point)
+	assert.Len(t, args.Times, 2)
+
+	req, _ = http.NewRequest("GET", "/test/handler_types/v1/?since=yesterday", nil)
+	_, err = hm.UnmarshalParameters(context.TODO(), hm.FindHandler("/test/handler_types", 1), &ParametersGetter{Req: req})
+	assert.Error(t, err)
+}
+
+func TestParameterTypes_Swagger(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	if !assert.NoError(t, hm.RegisterHandler(test_handler_types.NewHandler())) {
+		return
+	}
+
+	swagger, err := GenerateSwaggerJSON(hm, "", SwaggerJSONCallbacks{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	args := swagger.Definitions["github.com/sergei-svistunov/gorpc/test/handler_types/handler_types.V1Args"].(Schema)
+	assert.Equal(t, "string", args.Properties["since"].Type)
+	assert.Equal(t, "date-time", args.Properties["since"].Format)
+	assert.Equal(t, "duration", args.Properties["timeout"].Format)
+	assert.Equal(t, "string", args.Properties["color"].Type)
+	assert.Equal(t, "", args.Properties["point"].Type)
+	assert.Equal(t, "date-time", args.Properties["times"].Items.Format)
+}
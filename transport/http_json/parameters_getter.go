@@ -1,16 +1,29 @@
 package http_json
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
-	"net/url"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/sergei-svistunov/gorpc/internal/params"
 )
 
+// ParametersGetter reads parameters from the query string and the form. Nested parameters are passed in brackets
+// notation, indexes of slices and keys of maps are written the same way:
+//
+//	filter[status]=active&items[0][id]=5&items[1][id]=6&labels[env]=prod
+//
+// Repeated keys and keys with empty brackets ("ids[]=1&ids[]=2") are slices of primitive values. Names of
+// the top level parameters are case insensitive, nested keys are case sensitive like in JSON.
 type ParametersGetter struct {
 	Req    *http.Request
-	values url.Values
+	values map[string]interface{}
+	// root is the element of slice or map which fields are traversed, nil means the top level
+	root map[string]interface{}
 }
 
 func (p *ParametersGetter) Fork(values map[string]interface{}) interface{} {
@@ -26,15 +39,18 @@ func (pg *ParametersGetter) Parse() error {
 }
 
 func (pg *ParametersGetter) IsExists(path []string, name string) bool {
-	return pg.get(name) != ""
+	if m, ok := pg.lookup(path, name).(map[string]interface{}); ok {
+		return len(m) > 0
+	}
+	return pg.get(path, name) != ""
 }
 
 func (pg *ParametersGetter) GetString(path []string, name string) (string, error) {
-	return pg.get(name), nil
+	return pg.get(path, name), nil
 }
 
 func (pg *ParametersGetter) GetBool(path []string, name string) (bool, error) {
-	v, err := strconv.ParseBool(pg.get(name))
+	v, err := strconv.ParseBool(pg.get(path, name))
 	if err != nil {
 		err = errors.New(`Wrong value of param "` + name + `". It should be Bool`)
 	}
@@ -42,7 +58,7 @@ func (pg *ParametersGetter) GetBool(path []string, name string) (bool, error) {
 }
 
 func (pg *ParametersGetter) GetUint(path []string, name string) (uint, error) {
-	v, err := strconv.ParseUint(pg.get(name), 0, 0)
+	v, err := strconv.ParseUint(pg.get(path, name), 0, 0)
 	if err != nil {
 		err = errors.New(`Wrong value of param "` + name + `". It should be Uint`)
 	}
@@ -54,7 +70,7 @@ func (pg *ParametersGetter) GetByte(path []string, name string) (uint8, error) {
 }
 
 func (pg *ParametersGetter) GetUint8(path []string, name string) (uint8, error) {
-	v, err := strconv.ParseUint(pg.get(name), 0, 8)
+	v, err := strconv.ParseUint(pg.get(path, name), 0, 8)
 	if err != nil {
 		err = errors.New(`Wrong value of param "` + name + `". It should be Uint`)
 	}
@@ -62,7 +78,7 @@ func (pg *ParametersGetter) GetUint8(path []string, name string) (uint8, error)
 }
 
 func (pg *ParametersGetter) GetUint16(path []string, name string) (uint16, error) {
-	v, err := strconv.ParseUint(pg.get(name), 0, 16)
+	v, err := strconv.ParseUint(pg.get(path, name), 0, 16)
 	if err != nil {
 		err = errors.New(`Wrong value of param "` + name + `". It should be Uint`)
 	}
@@ -70,7 +86,7 @@ func (pg *ParametersGetter) GetUint16(path []string, name string) (uint16, error
 }
 
 func (pg *ParametersGetter) GetUint32(path []string, name string) (uint32, error) {
-	v, err := strconv.ParseUint(pg.get(name), 0, 32)
+	v, err := strconv.ParseUint(pg.get(path, name), 0, 32)
 	if err != nil {
 		err = errors.New(`Wrong value of param "` + name + `". It should be Uint`)
 	}
@@ -78,7 +94,7 @@ func (pg *ParametersGetter) GetUint32(path []string, name string) (uint32, error
 }
 
 func (pg *ParametersGetter) GetUint64(path []string, name string) (uint64, error) {
-	v, err := strconv.ParseUint(pg.get(name), 0, 64)
+	v, err := strconv.ParseUint(pg.get(path, name), 0, 64)
 	if err != nil {
 		err = errors.New(`Wrong value of param "` + name + `". It should be Uint`)
 	}
@@ -86,7 +102,7 @@ func (pg *ParametersGetter) GetUint64(path []string, name string) (uint64, error
 }
 
 func (pg *ParametersGetter) GetInt(path []string, name string) (int, error) {
-	v, err := strconv.ParseInt(pg.get(name), 0, 0)
+	v, err := strconv.ParseInt(pg.get(path, name), 0, 0)
 	if err != nil {
 		err = errors.New(`Wrong value of param "` + name + `". It should be Int`)
 	}
@@ -94,7 +110,7 @@ func (pg *ParametersGetter) GetInt(path []string, name string) (int, error) {
 }
 
 func (pg *ParametersGetter) GetInt8(path []string, name string) (int8, error) {
-	v, err := strconv.ParseInt(pg.get(name), 0, 8)
+	v, err := strconv.ParseInt(pg.get(path, name), 0, 8)
 	if err != nil {
 		err = errors.New(`Wrong value of param "` + name + `". It should be Int`)
 	}
@@ -102,7 +118,7 @@ func (pg *ParametersGetter) GetInt8(path []string, name string) (int8, error) {
 }
 
 func (pg *ParametersGetter) GetInt16(path []string, name string) (int16, error) {
-	v, err := strconv.ParseInt(pg.get(name), 0, 16)
+	v, err := strconv.ParseInt(pg.get(path, name), 0, 16)
 	if err != nil {
 		err = errors.New(`Wrong value of param "` + name + `". It should be Int`)
 	}
@@ -110,7 +126,7 @@ func (pg *ParametersGetter) GetInt16(path []string, name string) (int16, error)
 }
 
 func (pg *ParametersGetter) GetInt32(path []string, name string) (int32, error) {
-	v, err := strconv.ParseInt(pg.get(name), 0, 32)
+	v, err := strconv.ParseInt(pg.get(path, name), 0, 32)
 	if err != nil {
 		err = errors.New(`Wrong value of param "` + name + `". It should be Int`)
 	}
@@ -118,7 +134,7 @@ func (pg *ParametersGetter) GetInt32(path []string, name string) (int32, error)
 }
 
 func (pg *ParametersGetter) GetInt64(path []string, name string) (int64, error) {
-	v, err := strconv.ParseInt(pg.get(name), 0, 64)
+	v, err := strconv.ParseInt(pg.get(path, name), 0, 64)
 	if err != nil {
 		err = errors.New(`Wrong value of param "` + name + `". It should be Int`)
 	}
@@ -126,7 +142,7 @@ func (pg *ParametersGetter) GetInt64(path []string, name string) (int64, error)
 }
 
 func (pg *ParametersGetter) GetFloat32(path []string, name string) (float32, error) {
-	v, err := strconv.ParseFloat(pg.get(name), 32)
+	v, err := strconv.ParseFloat(pg.get(path, name), 32)
 	if err != nil {
 		err = errors.New(`Wrong value of param "` + name + `". It should be Float`)
 	}
@@ -134,16 +150,61 @@ func (pg *ParametersGetter) GetFloat32(path []string, name string) (float32, err
 }
 
 func (pg *ParametersGetter) GetFloat64(path []string, name string) (float64, error) {
-	v, err := strconv.ParseFloat(pg.get(name), 64)
+	v, err := strconv.ParseFloat(pg.get(path, name), 64)
 	if err != nil {
 		err = errors.New(`Wrong value of param "` + name + `". It should be Float`)
 	}
 	return v, err
 }
 
-func (p *ParametersGetter) TraverseSlice(path []string, name string, h func(i int, v interface{}) error) (bool, error) {
-	strSlice := p.getSlice(name)
-	for i, v := range strSlice {
+func (pg *ParametersGetter) GetTime(path []string, name string) (time.Time, error) {
+	v, err := time.Parse(time.RFC3339, pg.get(path, name))
+	if err != nil {
+		err = errors.New(`Wrong value of param "` + name + `". It should be Time in RFC3339 format`)
+	}
+	return v, err
+}
+
+func (pg *ParametersGetter) GetDuration(path []string, name string) (time.Duration, error) {
+	v, err := params.ParseDuration(pg.get(path, name))
+	if err != nil {
+		err = errors.New(`Wrong value of param "` + name + `". It should be Duration`)
+	}
+	return v, err
+}
+
+func (pg *ParametersGetter) GetRawJSON(path []string, name string) ([]byte, error) {
+	v := []byte(pg.get(path, name))
+	if json.Valid(v) {
+		return v, nil
+	}
+	return json.Marshal(string(v))
+}
+
+func (pg *ParametersGetter) TraverseSlice(path []string, name string, h func(i int, v interface{}) error) (bool, error) {
+	var items []interface{}
+	switch node := pg.lookup(path, name).(type) {
+	case []string:
+		for _, v := range node {
+			items = append(items, v)
+		}
+	case map[string]interface{}:
+		var ok bool
+		if items, ok = sliceItems(node); !ok {
+			return false, errors.New(`Wrong value of param "` + name + `". It should be array`)
+		}
+	}
+
+	origRoot := pg.root
+	defer func() {
+		pg.root = origRoot
+	}()
+	for i, item := range items {
+		v := plainValue(item)
+		pg.root = origRoot
+		if _, ok := v.(map[string]interface{}); ok {
+			pg.root = item.(map[string]interface{})
+		}
 		if err := h(i, v); err != nil {
 			return false, err
 		}
@@ -151,34 +212,220 @@ func (p *ParametersGetter) TraverseSlice(path []string, name string, h func(i in
 	return true, nil
 }
 
-func (p *ParametersGetter) TraverseMap(path []string, name string, h func(k string, v interface{}) error) (bool, error) {
-	panic("maps not supported")
+func (pg *ParametersGetter) TraverseMap(path []string, name string, h func(k string, v interface{}) error) (bool, error) {
+	node, ok := pg.lookup(path, name).(map[string]interface{})
+	if !ok {
+		return false, errors.New(`Wrong value of param "` + name + `". It should be object`)
+	}
+
+	keys := make([]string, 0, len(node))
+	for k := range node {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	origRoot := pg.root
+	defer func() {
+		pg.root = origRoot
+	}()
+	for _, k := range keys {
+		v := plainValue(node[k])
+		pg.root = origRoot
+		if _, ok := v.(map[string]interface{}); ok {
+			pg.root = node[k].(map[string]interface{})
+		}
+		if err := h(k, v); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
 }
 
-func (pg *ParametersGetter) get(name string) string {
-	slice := pg.getSlice(name)
-	if len(slice) == 0 {
-		return ""
+// Keys returns names of the passed parameters except "debug" which is handled by APIHandler
+func (pg *ParametersGetter) Keys(path []string) []string {
+	var node interface{}
+	top := len(path) == 0 && pg.root == nil
+	if len(path) > 0 {
+		node = pg.lookup(path[:len(path)-1], path[len(path)-1])
+	} else if pg.root != nil {
+		node = pg.root
+	} else {
+		node = pg.values
 	}
-	return slice[0]
+
+	m, _ := node.(map[string]interface{})
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		if top && k == "debug" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys
 }
 
-func (pg *ParametersGetter) getSlice(name string) []string {
-	name = strings.ToLower(name)
-	if slice, ok := pg.values[name]; ok {
-		return slice
+func (pg *ParametersGetter) get(path []string, name string) string {
+	if values, ok := pg.lookup(path, name).([]string); ok && len(values) > 0 {
+		return values[0]
 	}
-	return []string{}
+	return ""
 }
 
-func (pg *ParametersGetter) parseForm() error {
-	pg.values = make(url.Values)
-	for k, vs := range pg.Req.Form {
-		name := strings.ToLower(k)
-		for _, v := range vs {
-			pg.values.Add(name, v)
+// lookup returns the node of the parameters tree: []string for values or map[string]interface{} for nested keys
+func (pg *ParametersGetter) lookup(path []string, name string) interface{} {
+	node := pg.root
+	if node == nil {
+		node = pg.values
+	}
+
+	for i, key := range append(path[:len(path):len(path)], name) {
+		if i == 0 && pg.root == nil {
+			key = strings.ToLower(key)
+		}
+		switch v := node[key].(type) {
+		case map[string]interface{}:
+			node = v
+		default:
+			if i == len(path) {
+				return v
+			}
+			return nil
 		}
 	}
+	return node
+}
+
+func (pg *ParametersGetter) parseForm() error {
+	pg.values = make(map[string]interface{})
+
+	// keys are sorted to resolve conflicts of plain and nested keys the same way every time
+	keys := make([]string, 0, len(pg.Req.Form))
+	for k := range pg.Req.Form {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		pg.add(k, pg.Req.Form[k]...)
+	}
 
 	return nil
 }
+
+// add appends values to the parameter with the key in brackets notation
+func (pg *ParametersGetter) add(key string, values ...string) {
+	node, name := pg.parent(key)
+	switch v := node[name].(type) {
+	case map[string]interface{}:
+		// nested keys take precedence over the plain value with the same name
+	case []string:
+		node[name] = append(v, values...)
+	default:
+		node[name] = append([]string(nil), values...)
+	}
+}
+
+// set replaces values of the parameter with the key in brackets notation
+func (pg *ParametersGetter) set(key string, value string) {
+	node, name := pg.parent(key)
+	node[name] = []string{value}
+}
+
+// parent returns the node which contains the parameter with the key, nodes of the path are created if necessary
+func (pg *ParametersGetter) parent(key string) (map[string]interface{}, string) {
+	path := splitKey(key)
+	path[0] = strings.ToLower(path[0])
+
+	node := pg.values
+	for _, k := range path[:len(path)-1] {
+		child, ok := node[k].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			node[k] = child
+		}
+		node = child
+	}
+	return node, path[len(path)-1]
+}
+
+// splitKey splits the key in brackets notation: "items[0][id]" -> ["items", "0", "id"], "ids[]" -> ["ids"].
+// Malformed keys are returned as is.
+func splitKey(key string) []string {
+	start := strings.IndexByte(key, '[')
+	if start <= 0 || !strings.HasSuffix(key, "]") {
+		return []string{key}
+	}
+
+	path := []string{key[:start]}
+	for rest := key[start:]; rest != ""; {
+		end := strings.IndexByte(rest, ']')
+		if rest[0] != '[' || end < 0 {
+			return []string{key}
+		}
+		path = append(path, rest[1:end])
+		rest = rest[end+1:]
+	}
+
+	if path[len(path)-1] == "" {
+		path = path[:len(path)-1]
+	}
+	for _, k := range path[1:] {
+		if k == "" {
+			return []string{key}
+		}
+	}
+	return path
+}
+
+// sliceItems returns the nodes ordered by indexes if all keys are indexes of slice. Indexes may have gaps, only
+// their order matters.
+func sliceItems(node map[string]interface{}) ([]interface{}, bool) {
+	indexes := make([]int, 0, len(node))
+	byIndex := make(map[int]interface{}, len(node))
+	for k, v := range node {
+		i, err := strconv.Atoi(k)
+		if err != nil || i < 0 {
+			return nil, false
+		}
+		indexes = append(indexes, i)
+		byIndex[i] = v
+	}
+	sort.Ints(indexes)
+
+	items := make([]interface{}, len(indexes))
+	for n, i := range indexes {
+		items[n] = byIndex[i]
+	}
+	return items, true
+}
+
+// plainValue converts the node of parameters tree into the value like decoded from JSON: nodes with indexes become
+// []interface{}, other nested nodes become map[string]interface{} and repeated values become []interface{}
+func plainValue(node interface{}) interface{} {
+	switch node := node.(type) {
+	case string:
+		return node
+	case []string:
+		if len(node) == 1 {
+			return node[0]
+		}
+		items := make([]interface{}, len(node))
+		for i, v := range node {
+			items[i] = v
+		}
+		return items
+	case map[string]interface{}:
+		if items, ok := sliceItems(node); ok {
+			for i := range items {
+				items[i] = plainValue(items[i])
+			}
+			return items
+		}
+		m := make(map[string]interface{}, len(node))
+		for k, v := range node {
+			m[k] = plainValue(v)
+		}
+		return m
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,142 @@
+package http_json
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/sergei-svistunov/gorpc/test/handler1"
+	"github.com/sergei-svistunov/gorpc/test/handler_types"
+)
+
+func unmarshalQuery(t *testing.T, hm *gorpc.HandlersManager, path string, version int, query string) (interface{}, error) {
+	handlerVersion := hm.FindHandler(path, version)
+	if handlerVersion == nil {
+		t.Fatal("Handler wasn't found")
+	}
+
+	req := httptest.NewRequest("GET", path+"/?"+query, nil)
+	v, err := hm.UnmarshalParameters(context.TODO(), handlerVersion, &ParametersGetter{Req: req})
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+func TestSplitKey(t *testing.T) {
+	for key, path := range map[string][]string{
+		"a":            {"a"},
+		"items[0][id]": {"items", "0", "id"},
+		"ids[]":        {"ids"},
+		"a[b":          {"a[b"},
+		"a[b]c":        {"a[b]c"},
+		"[a]":          {"[a]"},
+		"a[][b]":       {"a[][b]"},
+	} {
+		assert.Equal(t, path, splitKey(key), key)
+	}
+}
+
+func TestParametersGetter_Nested(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(handler1.NewHandler())
+
+	v, err := unmarshalQuery(t, hm, "/test/handler1", 3,
+		"REQ_INT=1&nested[error_id]=2&optional[foo]=true&strings[Env]=prod&strings[dc]=eu&slices[]=a&slices[]=b"+
+			"&obj_map[x][foo]=true&obj_slice[1][foo]=false&obj_slice[0][foo]=true&recursive[recursive][0][time]=1s")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	req := v.(*handler1.V3Request)
+	assert.Equal(t, 1, req.ReqInt)
+	assert.Equal(t, 2, *req.Nested.ReturnErrorID)
+	assert.Equal(t, true, req.Optional.Foo)
+	assert.Equal(t, map[string]string{"Env": "prod", "dc": "eu"}, req.StringMap)
+	assert.Equal(t, []string{"a", "b"}, req.StringSlice)
+	assert.Equal(t, map[string]handler1.V3Optional{"x": {Foo: true}}, req.ObjMap)
+	assert.Equal(t, []handler1.V3Optional{{Foo: true}, {Foo: false}}, req.ObjSlice)
+	if assert.Len(t, req.Recursive.Recursive, 1) {
+		assert.Equal(t, time.Second, req.Recursive.Recursive[0].Time)
+	}
+}
+
+func TestParametersGetter_SliceInSlice(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(handler1.NewHandler())
+
+	v, err := unmarshalQuery(t, hm, "/test/handler1", 4,
+		"slice_in_slice[0][0][f1]=1&slice_in_slice[0][1][f1]=2&slice_in_slice[0][1][f2]=20&slice_in_slice[1][0][f1]=3")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	req := v.(*handler1.V4Request)
+	if assert.Len(t, req.SliceInSlice, 2) && assert.Len(t, req.SliceInSlice[0], 2) {
+		assert.Equal(t, 2, req.SliceInSlice[0][1].F1)
+		assert.Equal(t, 20, *req.SliceInSlice[0][1].F2)
+		assert.Equal(t, 3, req.SliceInSlice[1][0].F1)
+	}
+}
+
+func TestParametersGetter_Types(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(handler_types.NewHandler())
+
+	v, err := unmarshalQuery(t, hm, "/test/handler_types", 1,
+		"since=2020-01-02T03:04:05Z&times=2020-01-02T03:04:05Z&timeouts[read]=1s&timeouts[write]=2m")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, map[string]time.Duration{"read": time.Second, "write": 2 * time.Minute}, *v.(*handler_types.V1Args).Timeouts)
+}
+
+func TestParametersGetter_Errors(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{}).SetStrictParameters(true)
+	hm.MustRegisterHandler(handler1.NewHandler())
+
+	_, err := unmarshalQuery(t, hm, "/test/handler1", 3,
+		"req_int=1&nested[error_id]=1&strings[a]=b&slices=a&obj_slice[a][foo]=true&obj_map[x][foo]=yes&optional[bar]=1")
+	if assert.IsType(t, &gorpc.CallHandlerError{}, err) {
+		var paths []string
+		for _, e := range err.(*gorpc.CallHandlerError).ParameterErrors() {
+			paths = append(paths, e.Path)
+		}
+		assert.Equal(t, []string{"optional.bar", "optional.foo", "obj_map.x.foo", "obj_slice"}, paths)
+	}
+}
+
+func TestParametersGetter_Swagger(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(handler1.NewHandler())
+
+	swagger, err := GenerateSwaggerJSON(hm, "", SwaggerJSONCallbacks{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pathItem := swagger.Paths["/test/handler1/v3/"]
+	if !assert.NotNil(t, pathItem["post"]) || !assert.NotNil(t, pathItem["get"]) {
+		return
+	}
+
+	required := map[string]bool{}
+	for _, p := range pathItem["get"].Parameters {
+		assert.Equal(t, "query", p.In)
+		required[p.Name] = p.Required
+	}
+	assert.Equal(t, map[string]bool{
+		"req_int":                         true,
+		"nested[error_id]":                false,
+		"optional[foo]":                   false,
+		"strings[{key}]":                  false,
+		"slices":                          true,
+		"obj_map[{key}][foo]":             false,
+		"obj_slice[{i}][foo]":             false,
+		"recursive[recursive][{i}][time]": false,
+	}, required)
+}
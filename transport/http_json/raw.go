@@ -0,0 +1,99 @@
+package http_json
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/sergei-svistunov/gorpc/transport/cache"
+)
+
+// ContentTypeOctetStream is the content type of raw responses without the specified one
+const ContentTypeOctetStream = "application/octet-stream"
+
+// ErrorRawNotSupported is the error code of the call of a handler with gorpc.RawResponse in the batch, the raw content
+// can't be a part of JSON array
+const ErrorRawNotSupported = "RAW_NOT_SUPPORTED"
+
+// rawResponse returns the raw response returned by the handler or nil
+func rawResponse(handlerResponse interface{}) *gorpc.RawResponse {
+	switch r := handlerResponse.(type) {
+	case *gorpc.RawResponse:
+		if r == nil {
+			return &gorpc.RawResponse{}
+		}
+		return r
+	case gorpc.RawResponse:
+		return &r
+	}
+	return nil
+}
+
+// createRawCacheEntry creates the entry written as is. The reader of the response is read into memory only if the
+// response can be cached, otherwise it's copied into the connection by writeResponse.
+func (h *APIHandler) createRawCacheEntry(ctx context.Context, raw *gorpc.RawResponse, cacheKey []byte, req *http.Request) (*cache.CacheEntry, *gorpc.CallHandlerError) {
+	cacheEntry := cache.CacheEntry{
+		ContentType: raw.ContentType,
+		Header:      mergeHeaders(raw.Header, responseHeadersFromContext(ctx)),
+		Content:     raw.Body,
+		Body:        raw,
+	}
+	if cacheEntry.ContentType == "" {
+		cacheEntry.ContentType = ContentTypeOctetStream
+	}
+	if cacheEntry.Content == nil {
+		cacheEntry.Content = []byte{}
+	}
+
+	if raw.Reader != nil {
+		if cacheKey == nil || !cache.IsTransportCacheEnabled(ctx) {
+			cacheEntry.Reader = raw.Reader
+			return &cacheEntry, nil
+		}
+
+		content, err := raw.ReadBody()
+		if err != nil {
+			return nil, &gorpc.CallHandlerError{
+				Type: gorpc.ErrorWriteResponse,
+				Err:  err,
+			}
+		}
+		cacheEntry.Content = content
+	}
+
+	// the handler may encode the content itself
+	if raw.Header.Get("Content-Encoding") != "" {
+		return &cacheEntry, nil
+	}
+	if len(cacheEntry.Content) > 4096 && cacheKey != nil && isCompressible(cacheEntry.ContentType) &&
+		strings.Contains(req.Header.Get("Accept-Encoding"), "gzip") {
+		buf := new(bytes.Buffer)
+		if gzipWriter, err := gzip.NewWriterLevel(buf, gzip.BestSpeed); err == nil {
+			gzipWriter.Write(cacheEntry.Content)
+			gzipWriter.Close()
+			cacheEntry.CompressedContent = buf.Bytes()
+		}
+	}
+	return &cacheEntry, nil
+}
+
+// isCompressible reports whether the content of the type is worth compressing, images, archives and other binary
+// formats are usually compressed already
+func isCompressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "application/x-ndjson":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package http_json
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/sergei-svistunov/gorpc/transport/cache"
+
+	test_handler_raw "github.com/sergei-svistunov/gorpc/test/handler_raw"
+)
+
+func newRawTestServer(t *testing.T, useCache bool) *httptest.Server {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	if err := hm.RegisterHandler(test_handler_raw.NewHandler()); err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(NewAPIHandler(hm, cache.NewMapCache(), APIHandlerCallbacks{
+		OnInitCtx: func(ctx context.Context, req *http.Request) context.Context {
+			if useCache {
+				cache.EnableTransportCache(ctx)
+				cache.EnableETag(ctx)
+			}
+			return ctx
+		},
+	}))
+}
+
+func getRaw(t *testing.T, url string, header http.Header) (*http.Response, string) {
+	req, _ := http.NewRequest("GET", url, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+func TestRawResponse(t *testing.T) {
+	server := newRawTestServer(t, false)
+	defer server.Close()
+
+	for _, query := range []string{"?rows=2", "?rows=2&stream=true"} {
+		resp, body := getRaw(t, server.URL+"/test/handler_raw/v1/"+query, http.Header{"Accept": {"application/pdf"}})
+		assert.Equal(t, http.StatusOK, resp.StatusCode, query)
+		assert.Equal(t, "text/csv", resp.Header.Get("Content-Type"), query)
+		assert.Equal(t, `attachment; filename="rows.csv"`, resp.Header.Get("Content-Disposition"), query)
+		assert.Empty(t, resp.Header.Get("Etag"), query)
+		assert.Equal(t, "id,name\n1,test\n1,test\n", body, query)
+	}
+
+	resp, body := getRaw(t, server.URL+"/test/handler_raw/v1/?rows=0", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `{"result":"ERROR","data":"Rows must be positive","error":"NO_ROWS"}`, strings.TrimSpace(body))
+}
+
+func TestRawResponse_Cache(t *testing.T) {
+	server := newRawTestServer(t, true)
+	defer server.Close()
+
+	url := server.URL + "/test/handler_raw/v1/?rows=1000&stream=true"
+	resp, body := getRaw(t, url, http.Header{"Accept-Encoding": {"gzip"}})
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+	assert.Equal(t, "text/csv", resp.Header.Get("Content-Type"))
+	etag := resp.Header.Get("Etag")
+	assert.NotEmpty(t, etag)
+
+	reader, err := gzip.NewReader(strings.NewReader(body))
+	if assert.NoError(t, err) {
+		content, _ := ioutil.ReadAll(reader)
+		assert.Equal(t, "id,name\n"+strings.Repeat("1,test\n", 1000), string(content))
+	}
+
+	// the cached response
+	resp, body = getRaw(t, url, nil)
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+	assert.Equal(t, `attachment; filename="rows.csv"`, resp.Header.Get("Content-Disposition"))
+	assert.Equal(t, etag, resp.Header.Get("Etag"))
+	assert.Equal(t, "id,name\n"+strings.Repeat("1,test\n", 1000), body)
+
+	resp, body = getRaw(t, url, http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	assert.Empty(t, body)
+}
+
+func TestRawResponse_Swagger(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(test_handler_raw.NewHandler())
+
+	swagger, err := GenerateSwaggerJSON(hm, "", SwaggerJSONCallbacks{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operation := swagger.Paths["/test/handler_raw/v1/"]["get"]
+	if assert.NotNil(t, operation) {
+		assert.Equal(t, []string{"text/csv"}, operation.Produces)
+		assert.Equal(t, "file", operation.Responses["200"].Schema.Type)
+	}
+}
@@ -0,0 +1,20 @@
+package http_json
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/sergei-svistunov/gorpc"
+)
+
+// TransportName marks calls of APIHandler, including batch items
+const TransportName = "http_json"
+
+// withRequestInfo adds the information about the HTTP request of the handler's call into the context
+func withRequestInfo(ctx context.Context, req *http.Request, handler gorpc.HandlerVersion) context.Context {
+	info := gorpc.NewIncomingRequest(TransportName, handler)
+	info.RemoteAddr = req.RemoteAddr
+	info.Header = req.Header
+	info.HTTPRequest = req
+	return gorpc.NewRequestInfoContext(ctx, info)
+}
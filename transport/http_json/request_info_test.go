@@ -0,0 +1,31 @@
+package http_json
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergei-svistunov/gorpc"
+
+	test_handler_request_info "github.com/sergei-svistunov/gorpc/test/handler_request_info"
+)
+
+func TestAPIHandler_RequestInfo(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(test_handler_request_info.NewHandler())
+	handler := NewAPIHandler(hm, nil, APIHandlerCallbacks{}).SetBatchPath("/batch")
+
+	request := httptest.NewRequest("GET", "/test/handler_request_info/v1/", nil)
+	request.Header.Set("User-Agent", "test")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"result":"OK","data":{"transport":"http_json","route":"/test/handler_request_info/v1/",
+		"version":"v1","remote_addr":"192.0.2.1:1234","user_agent":"test","http":true},"error":""}`, recorder.Body.String())
+
+	recorder = callBatch(handler, `[{"route": "/test/handler_request_info/v1/"}]`, http.Header{"User-Agent": {"batch"}})
+	assert.JSONEq(t, `[{"result":"OK","data":{"transport":"http_json","route":"/test/handler_request_info/v1/",
+		"version":"v1","remote_addr":"192.0.2.1:1234","user_agent":"batch","http":true},"error":""}]`, recorder.Body.String())
+}
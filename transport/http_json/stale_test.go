@@ -0,0 +1,144 @@
+package http_json
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/sergei-svistunov/gorpc/transport/cache"
+)
+
+// staleHandler counts its calls and fails on demand, it's declared here because handlers in the test directory
+// can't import the transport
+type staleHandler struct {
+	calls int32
+	fail  int32
+
+	// release blocks calls if it isn't nil, errors of their contexts are sent to ctxErrs then
+	release chan struct{}
+	ctxErrs chan error
+}
+
+func (*staleHandler) Caption() string {
+	return "Stale handler"
+}
+
+func (*staleHandler) Description() string {
+	return "Handler which is cached with stale windows"
+}
+
+type staleV1Args struct{}
+
+type staleV1Res struct {
+	Calls int32 `json:"calls" description:"Number of calls"`
+}
+
+func (h *staleHandler) V1(ctx context.Context, opts *staleV1Args) (*staleV1Res, error) {
+	calls := atomic.AddInt32(&h.calls, 1)
+	if h.release != nil {
+		<-h.release
+		if gorpc.RequestInfo(ctx) == nil {
+			h.ctxErrs <- errors.New("no request info")
+		}
+		h.ctxErrs <- ctx.Err()
+	}
+	if atomic.LoadInt32(&h.fail) != 0 {
+		return nil, errors.New("storage is unavailable")
+	}
+	cache.EnableTransportCache(ctx)
+	cache.SetTTL(ctx, 50*time.Millisecond)
+	cache.SetStaleTTL(ctx, 100*time.Millisecond)
+	cache.SetStaleIfErrorTTL(ctx, time.Hour)
+	return &staleV1Res{Calls: calls}, nil
+}
+
+func TestStaleCache(t *testing.T) {
+	handler := &staleHandler{}
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(handler)
+	server := httptest.NewServer(NewAPIHandler(hm, cache.NewMapCache(), APIHandlerCallbacks{}))
+	defer server.Close()
+
+	get := func() string {
+		resp, body := getRaw(t, server.URL+"/transport/http_json/v1/", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		return body
+	}
+	result := func(calls int32) string {
+		return `{"result":"OK","data":{"calls":` + strconv.Itoa(int(calls)) + `},"error":""}`
+	}
+
+	assert.Equal(t, result(1), get())
+	assert.Equal(t, result(1), get())
+
+	// stale-while-revalidate: the stale response is returned at once and refreshed in background
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, result(1), get())
+	assert.Eventually(t, func() bool {
+		return get() == result(2)
+	}, time.Second, 5*time.Millisecond)
+
+	// stale-if-error: the stale response is returned instead of the internal error
+	atomic.StoreInt32(&handler.fail, 1)
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, result(2), get())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&handler.calls))
+}
+
+func TestStaleCache_RefreshContext(t *testing.T) {
+	handler := &staleHandler{}
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(handler)
+	apiHandler := NewAPIHandler(hm, cache.NewMapCache(), APIHandlerCallbacks{})
+
+	call := func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		recorder := httptest.NewRecorder()
+		apiHandler.ServeHTTP(recorder, httptest.NewRequest("GET", "/transport/http_json/v1/", nil).WithContext(ctx))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	}
+	call()
+	time.Sleep(60 * time.Millisecond)
+
+	// the refresh isn't canceled with the request which has started it
+	handler.release = make(chan struct{})
+	handler.ctxErrs = make(chan error, 2)
+	call()
+	close(handler.release)
+	select {
+	case err := <-handler.ctxErrs:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Handler wasn't refreshed")
+	}
+}
+
+func TestCopyValue(t *testing.T) {
+	type nested struct {
+		Values []int
+	}
+	type params struct {
+		Name   string
+		Nested *nested
+		Map    map[string][]int
+		hidden []int
+	}
+	original := &params{Name: "a", Nested: &nested{Values: []int{1}}, Map: map[string][]int{"a": {1}}, hidden: []int{1}}
+	res := copyValue(reflect.ValueOf(original)).Interface().(*params)
+	assert.Equal(t, original, res)
+
+	res.Nested.Values[0] = 2
+	res.Map["a"][0] = 2
+	assert.Equal(t, 1, original.Nested.Values[0])
+	assert.Equal(t, 1, original.Map["a"][0])
+}
@@ -0,0 +1,181 @@
+package http_json
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/sergei-svistunov/gorpc"
+)
+
+// Content types of streamed responses
+const (
+	ContentTypeNDJSON      = "application/x-ndjson"
+	ContentTypeEventStream = "text/event-stream"
+)
+
+// ErrorStreamNotSupported is the error code of the call of a streaming handler through a transport which can't stream
+const ErrorStreamNotSupported = "STREAM_NOT_SUPPORTED"
+
+// isUnencodedRoute reports whether the route belongs to a handler which streams its response or returns
+// gorpc.RawResponse, their successful responses aren't encoded by codecs
+func (h *APIHandler) isUnencodedRoute(route string) bool {
+	handler := h.hm.FindHandlerByRoute(route)
+	return handler != nil && (handler.StreamItem != nil || handler.Raw)
+}
+
+func acceptsEventStream(req *http.Request) bool {
+	return strings.Contains(req.Header.Get("Accept"), ContentTypeEventStream)
+}
+
+// serveStream writes items of the handler's stream as they come. Every item is HttpSessionResponse written as
+// a line of NDJSON or as a data of Server-Sent Event if the client accepts "text/event-stream":
+//
+//	{"result":"OK","data":{"id":1},"error":""}
+//	{"result":"OK","data":{"id":2},"error":""}
+//	{"result":"ERROR","data":"Request timed out","error":"TIMEOUT"}
+//
+// The response isn't cached, the stream is stopped when the request's context is done. Errors returned by
+// the handler are written the same way as for regular handlers, errors of the stream are written as the last item
+// (with the event "error" for SSE). SSE stream ends with the event "end".
+func (h *APIHandler) serveStream(ctx context.Context, w http.ResponseWriter, req *http.Request, handler gorpc.HandlerVersion,
+	params reflect.Value, startTime time.Time) {
+
+	var resp HttpSessionResponse
+	stream, err := h.callStreamHandler(ctx, w, req, handler, params)
+	if ctx.Err() == context.DeadlineExceeded {
+		h.writeTimeoutError(ctx, req, w)
+		return
+	}
+	if err != nil {
+		if err.Type == gorpc.ErrorReturnedFromCall {
+			resp.Result = "ERROR"
+			resp.Data = err.UserMessage()
+			resp.Error = err.ErrorCode()
+		}
+		h.handleCallError(ctx, w, req, &resp, err, startTime)
+		return
+	}
+
+	if h.callbacks.OnBeforeWriteResponse != nil {
+		h.callbacks.OnBeforeWriteResponse(ctx, w)
+	}
+
+	writeHeaders(w, responseHeadersFromContext(ctx))
+	sse := acceptsEventStream(req)
+	if sse {
+		w.Header().Set("Content-Type", ContentTypeEventStream)
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		w.Header().Set("Content-Type", ContentTypeNDJSON)
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	write := func(resp *HttpSessionResponse, event string) error {
+		data, err := resp.MarshalJSON()
+		if err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		if sse {
+			if event != "" {
+				buf.WriteString("event: " + event + "\n")
+			}
+			buf.WriteString("data: ")
+			buf.Write(data)
+			buf.WriteString("\n\n")
+		} else {
+			buf.Write(data)
+			buf.WriteByte('\n')
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	streamErr := h.readStream(ctx, w, req, stream, func(item interface{}) error {
+		return write(&HttpSessionResponse{Result: "OK", Data: item}, "")
+	})
+	if streamErr == nil {
+		if sse {
+			write(&HttpSessionResponse{Result: "OK"}, "end")
+		}
+		if h.callbacks.OnSuccess != nil {
+			h.callbacks.OnSuccess(ctx, req, nil, startTime)
+		}
+		return
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil && streamErr == ctxErr {
+		if ctxErr == context.DeadlineExceeded {
+			timeoutErr := &gorpc.CallHandlerError{
+				Type: gorpc.ErrorReturnedFromCall,
+				Err:  errors.New("Request timed out"),
+			}
+			if h.callbacks.OnError != nil {
+				h.callbacks.OnError(ctx, w, req, nil, timeoutErr)
+			}
+			write(&HttpSessionResponse{Result: "ERROR", Data: timeoutErr.UserMessage(), Error: ErrorTimeout}, "error")
+		}
+		// otherwise the client has gone
+		return
+	}
+
+	callErr, ok := streamErr.(*gorpc.CallHandlerError)
+	if !ok {
+		callErr = &gorpc.CallHandlerError{
+			Type: gorpc.ErrorWriteResponse,
+			Err:  streamErr,
+		}
+	}
+	if callErr.Type != gorpc.ErrorPanic && h.callbacks.OnError != nil {
+		h.callbacks.OnError(ctx, w, req, nil, callErr)
+	}
+	switch callErr.Type {
+	case gorpc.ErrorWriteResponse:
+	case gorpc.ErrorReturnedFromCall:
+		write(&HttpSessionResponse{Result: "ERROR", Data: callErr.UserMessage(), Error: callErr.ErrorCode()}, "error")
+	default:
+		message := http.StatusText(http.StatusInternalServerError)
+		if PrintDebug {
+			message += ":\n" + callErr.Error()
+		}
+		write(&HttpSessionResponse{Result: "ERROR", Data: message, Error: ErrorInternal}, "error")
+	}
+}
+
+// callStreamHandler calls the handler which returns the stream, there is no need to wait it in a separate goroutine
+// because the stream is read by the caller anyway
+func (h *APIHandler) callStreamHandler(ctx context.Context, w http.ResponseWriter, req *http.Request, handler gorpc.HandlerVersion,
+	params reflect.Value) (stream interface{}, err *gorpc.CallHandlerError) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = h.recoverPanic(ctx, w, req, r)
+		}
+	}()
+	return h.hm.CallHandler(ctx, handler, params)
+}
+
+// readStream reads the stream and converts panics of iterators into errors
+func (h *APIHandler) readStream(ctx context.Context, w http.ResponseWriter, req *http.Request, stream interface{},
+	fn func(item interface{}) error) (err error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = h.recoverPanic(ctx, w, req, r)
+		}
+	}()
+	return gorpc.ReadStream(ctx, stream, fn)
+}
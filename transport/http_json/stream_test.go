@@ -0,0 +1,147 @@
+package http_json
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/sergei-svistunov/gorpc/transport/cache"
+
+	test_handler_stream "github.com/sergei-svistunov/gorpc/test/handler_stream"
+)
+
+func newStreamTestServer(t *testing.T) *httptest.Server {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	if err := hm.RegisterHandler(test_handler_stream.NewHandler()); err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(NewAPIHandler(hm, cache.NewMapCache(), APIHandlerCallbacks{}).SetBatchPath("/batch"))
+}
+
+func getStream(t *testing.T, url, accept string) (*http.Response, string) {
+	req, _ := http.NewRequest("GET", url, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+func TestStream_NDJSON(t *testing.T) {
+	server := newStreamTestServer(t)
+	defer server.Close()
+
+	for _, route := range []string{"/test/handler_stream/v1/", "/test/handler_stream/v2/"} {
+		resp, body := getStream(t, server.URL+route+"?count=3", "")
+		assert.Equal(t, http.StatusOK, resp.StatusCode, route)
+		assert.Equal(t, ContentTypeNDJSON, resp.Header.Get("Content-Type"), route)
+		assert.Equal(t, `{"result":"OK","data":{"n":0},"error":""}
+{"result":"OK","data":{"n":1},"error":""}
+{"result":"OK","data":{"n":2},"error":""}
+`, body, route)
+	}
+
+	_, body := getStream(t, server.URL+"/test/handler_stream/v2/?count=3&fail_at=1", "application/msgpack")
+	assert.Equal(t, `{"result":"OK","data":{"n":0},"error":""}
+{"result":"ERROR","data":"Stream failed","error":"STREAM_FAILED"}
+`, body)
+
+	resp, _ := getStream(t, server.URL+"/test/handler_stream/v2/", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestStream_SSE(t *testing.T) {
+	server := newStreamTestServer(t)
+	defer server.Close()
+
+	resp, body := getStream(t, server.URL+"/test/handler_stream/v1/?count=2", ContentTypeEventStream)
+	assert.Equal(t, ContentTypeEventStream, resp.Header.Get("Content-Type"))
+	assert.Equal(t, `data: {"result":"OK","data":{"n":0},"error":""}
+
+data: {"result":"OK","data":{"n":1},"error":""}
+
+event: end
+data: {"result":"OK","data":null,"error":""}
+
+`, body)
+
+	_, body = getStream(t, server.URL+"/test/handler_stream/v2/?count=2&fail_at=0", ContentTypeEventStream)
+	assert.Equal(t, `event: error
+data: {"result":"ERROR","data":"Stream failed","error":"STREAM_FAILED"}
+
+`, body)
+}
+
+func TestStream_ClientCancel(t *testing.T) {
+	server := newStreamTestServer(t)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequest("GET", server.URL+"/test/handler_stream/v1/?count=1000000000", nil)
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	for i := 0; i < 3; i++ {
+		line, err := reader.ReadString('\n')
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(line, `{"result":"OK"`))
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		ioutil.ReadAll(reader)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Stream must be stopped")
+	}
+}
+
+func TestStream_Batch(t *testing.T) {
+	server := newStreamTestServer(t)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/batch", "application/json", strings.NewReader(`[{"route": "/test/handler_stream/v1/", "params": {"count": 1}}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.JSONEq(t, `[{"result": "ERROR", "data": "Streamed response can't be a part of the batch", "error": "STREAM_NOT_SUPPORTED"}]`, string(body))
+}
+
+func TestStream_Swagger(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	if err := hm.RegisterHandler(test_handler_stream.NewHandler()); err != nil {
+		t.Fatal(err)
+	}
+
+	swagger, err := GenerateSwaggerJSON(hm, "", SwaggerJSONCallbacks{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operation := swagger.Paths["/test/handler_stream/v1/"]["get"]
+	assert.Equal(t, []string{ContentTypeNDJSON, ContentTypeEventStream}, operation.Produces)
+	assert.Equal(t, "#/definitions/github.com/sergei-svistunov/gorpc/test/handler_stream/handler_stream.V1Item", operation.Responses["200"].Schema.Ref)
+}
@@ -2,7 +2,9 @@ package http_json
 
 import (
 	"bytes"
+	"encoding/json"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/sergei-svistunov/gorpc"
@@ -70,13 +72,25 @@ type Response struct {
 }
 
 type Schema struct {
-	Ref                  string     `json:"$ref,omitempty"`
-	Type                 string     `json:"type,omitempty"`
-	Description          string     `json:"description,omitempty"`
-	Required             []string   `json:"required,omitempty"`
-	Items                *Items     `json:"items,omitempty"`
-	Properties           Properties `json:"properties,omitempty"`
-	AdditionalProperties *Schema    `json:"additionalProperties,omitempty"`
+	Ref                  string        `json:"$ref,omitempty"`
+	Type                 string        `json:"type,omitempty"`
+	Format               string        `json:"format,omitempty"`
+	Description          string        `json:"description,omitempty"`
+	Required             []string      `json:"required,omitempty"`
+	Items                *Items        `json:"items,omitempty"`
+	Properties           Properties    `json:"properties,omitempty"`
+	AdditionalProperties *Schema       `json:"additionalProperties,omitempty"`
+	Minimum              *float64      `json:"minimum,omitempty"`
+	Maximum              *float64      `json:"maximum,omitempty"`
+	MinLength            *int          `json:"minLength,omitempty"`
+	MaxLength            *int          `json:"maxLength,omitempty"`
+	MinItems             *int          `json:"minItems,omitempty"`
+	MaxItems             *int          `json:"maxItems,omitempty"`
+	MinProperties        *int          `json:"minProperties,omitempty"`
+	MaxProperties        *int          `json:"maxProperties,omitempty"`
+	Pattern              string        `json:"pattern,omitempty"`
+	Enum                 []interface{} `json:"enum,omitempty"`
+	Default              interface{}   `json:"default,omitempty"`
 }
 
 type Properties map[string]*Schema
@@ -101,9 +115,12 @@ type SecurityScheme struct {
 // SwaggerJSONCallbacks is struct for callbacks describing
 type SwaggerJSONCallbacks struct {
 	OnPrepareBaseInfoJSON func(info *Info)
-	OnPrepareHandlerJSON  func(path string, data *Operation)
-	Process               func(swagger *Swagger)
-	TagName               func(path string) string
+	// OnPrepareHandlerJSON is called once for every version of the handler. Requests which can be passed both as
+	// JSON body and as query parameters are described by POST operation here, GET operation is its copy with query
+	// parameters instead of the body.
+	OnPrepareHandlerJSON func(path string, data *Operation)
+	Process              func(swagger *Swagger)
+	TagName              func(path string) string
 }
 
 func GenerateSwaggerJSON(hm *gorpc.HandlersManager, host string, callbacks SwaggerJSONCallbacks) (*Swagger, error) {
@@ -127,7 +144,7 @@ func GenerateSwaggerJSON(hm *gorpc.HandlersManager, host string, callbacks Swagg
 			<h3>Response compression and caching</h3>
 			<p>API compress a response using gzip if the header "Accept-Encoding" contains "gzip" and a response is bigger or equal 1Kb.
 			If a response is compressed then server sends the header "Content-Encoding: gzip".</p>
-			<p>API supports ETag.</p>`,
+			<p>API supports ETag with "If-None-Match" and "Last-Modified" with "If-Modified-Since" headers, "Cache-Control" header is sent for cacheable responses.</p>`,
 		},
 		BasePath:    "/",
 		Host:        host,
@@ -163,7 +180,15 @@ func GenerateSwaggerJSON(hm *gorpc.HandlersManager, host string, callbacks Swagg
 				ExtraData:   v.ExtraData,
 			}
 
-			if !v.Request.Flat {
+			if v.Request.Multipart {
+				// files can be uploaded in multipart form only, other parameters are its fields
+				operation.Consumes = []string{"multipart/form-data"}
+				operation.Parameters = queryParameters("", v.Request.Fields, true, map[reflect.Type]bool{v.Request.Type: true})
+				for _, param := range operation.Parameters {
+					param.In = "formData"
+				}
+
+			} else if !v.Request.Flat {
 				bodySchema := getOrCreateSchema(swagger.Definitions, v.Request.Type)
 				param := &Parameter{
 					Name:        "body",
@@ -176,26 +201,7 @@ func GenerateSwaggerJSON(hm *gorpc.HandlersManager, host string, callbacks Swagg
 				operation.Parameters = append(operation.Parameters, param)
 
 			} else {
-				for _, p := range v.Request.Fields {
-					paramType := typeName(p.RawType)
-					var arrayType string
-					if paramType == "array" {
-						arrayType = typeName(p.RawType.Elem())
-					}
-
-					param := &Parameter{
-						Name:        p.GetKey(),
-						Description: p.Description,
-						In:          "query",
-						Required:    p.IsRequired,
-						Schema:      Schema{Type: paramType},
-					}
-					if arrayType != "" {
-						param.CollectionFormat = "multi"
-						param.Items = &Items{Schema{Type: arrayType}}
-					}
-					operation.Parameters = append(operation.Parameters, param)
-				}
+				operation.Parameters = queryParameters("", v.Request.Fields, true, map[reflect.Type]bool{})
 			}
 
 			if len(v.Errors) > 0 {
@@ -208,13 +214,36 @@ func GenerateSwaggerJSON(hm *gorpc.HandlersManager, host string, callbacks Swagg
 					errorsDescription.WriteString(e.Code)
 					errorsDescription.WriteString("</code>\", Data: \"<code>")
 					errorsDescription.WriteString(e.UserMessage)
-					errorsDescription.WriteString("</code>\"</li>")
+					errorsDescription.WriteString("</code>\"")
+					if e.HTTPStatus != 0 {
+						errorsDescription.WriteString(", HTTP status: " + strconv.Itoa(e.HTTPStatus))
+					}
+					errorsDescription.WriteString("</li>")
 				}
 				errorsDescription.WriteString("</ul>")
 				operation.Description += errorsDescription.String()
 			}
 
-			if v.Response != nil {
+			if v.StreamItem != nil {
+				operation.Produces = []string{ContentTypeNDJSON, ContentTypeEventStream}
+				operation.Responses = Responses{
+					"200": &Response{
+						Description: "Stream of items, every item is sent as a separate line of NDJSON or event of SSE",
+						Schema:      getOrCreateSchema(swagger.Definitions, v.StreamItem),
+					},
+				}
+			} else if v.Raw {
+				operation.Produces = v.Produces
+				if len(operation.Produces) == 0 {
+					operation.Produces = []string{ContentTypeOctetStream}
+				}
+				operation.Responses = Responses{
+					"200": &Response{
+						Description: "Successful result, the content is returned as is",
+						Schema:      &Schema{Type: "file"},
+					},
+				}
+			} else if v.Response != nil {
 				operation.Responses = Responses{
 					"200": &Response{
 						Description: "Successful result",
@@ -223,18 +252,32 @@ func GenerateSwaggerJSON(hm *gorpc.HandlersManager, host string, callbacks Swagg
 				}
 			}
 
+			operation.Responses = addErrorResponses(operation.Responses, v.Errors)
+
 			if callbacks.OnPrepareHandlerJSON != nil {
 				callbacks.OnPrepareHandlerJSON(path, operation)
 			}
 
-			var method string
-			if v.Request.Flat {
-				method = "get"
-			} else {
-				method = "post"
+			// non-flat requests can be passed as JSON body as well as query parameters in brackets notation
+			var queryOperation *Operation
+			if !v.Request.Multipart && !v.Request.Flat {
+				queryOperation = newQueryOperation(operation,
+					queryParameters("", v.Request.Fields, true, map[reflect.Type]bool{v.Request.Type: true}))
 			}
-			swagger.Paths[v.Route] = PathItem{
-				method: operation,
+
+			if v.Request.Multipart {
+				swagger.Paths[v.Route] = PathItem{
+					"post": operation,
+				}
+			} else if v.Request.Flat {
+				swagger.Paths[v.Route] = PathItem{
+					"get": operation,
+				}
+			} else {
+				swagger.Paths[v.Route] = PathItem{
+					"get":  queryOperation,
+					"post": operation,
+				}
 			}
 		}
 	}
@@ -245,10 +288,153 @@ func GenerateSwaggerJSON(hm *gorpc.HandlersManager, host string, callbacks Swagg
 	return swagger, nil
 }
 
+// newQueryOperation returns GET operation of the non-flat request described by the POST one, the body is replaced
+// with query parameters. Parameters and responses are copied, so changes of one operation don't affect another.
+func newQueryOperation(operation *Operation, params []*Parameter) *Operation {
+	res := *operation
+	res.Consumes = nil
+	res.Tags = append([]string(nil), operation.Tags...)
+	res.Produces = append([]string(nil), operation.Produces...)
+	res.Security = append([]*SecurityRequirement(nil), operation.Security...)
+
+	res.Parameters = params
+	for _, param := range operation.Parameters {
+		if param.In != "body" {
+			paramCopy := *param
+			res.Parameters = append(res.Parameters, &paramCopy)
+		}
+	}
+
+	if operation.Responses != nil {
+		res.Responses = make(Responses, len(operation.Responses))
+		for code, resp := range operation.Responses {
+			respCopy := *resp
+			res.Responses[code] = &respCopy
+		}
+	}
+	return &res
+}
+
+// queryParameters describes fields as query parameters. Fields of nested structures are described in brackets
+// notation, indexes of slices and keys of maps are shown as "{i}" and "{key}" placeholders:
+//
+//	filter[status], items[{i}][id], labels[{key}]
+//
+// Recursive structures are described up to the first repetition, slices of slices and maps of containers can't
+// be described and are omitted.
+func queryParameters(prefix string, fields []gorpc.HandlerParameter, required bool, visited map[reflect.Type]bool) []*Parameter {
+	var params []*Parameter
+	for i := range fields {
+		p := &fields[i]
+		name := p.GetKey()
+		if prefix != "" {
+			name = prefix + "[" + name + "]"
+		}
+
+		t := derefType(p.RawType)
+		var nested string
+		switch {
+		case isQueryObject(t):
+			nested = name
+		case t.Kind() == reflect.Map && isQueryObject(t.Elem()):
+			nested = name + "[{key}]"
+		case t.Kind() == reflect.Map && !isQueryContainer(t.Elem()):
+			params = append(params, queryParameter(name+"[{key}]", p, t.Elem(), false))
+			continue
+		case isQueryContainer(t) && isQueryObject(t.Elem()):
+			nested = name + "[{i}]"
+		case isQueryContainer(t) && isQueryContainer(t.Elem()):
+			continue
+		default:
+			params = append(params, queryParameter(name, p, p.RawType, required && p.IsRequired))
+			continue
+		}
+
+		structType := t
+		for structType.Kind() == reflect.Map || structType.Kind() == reflect.Slice || structType.Kind() == reflect.Array {
+			structType = derefType(structType.Elem())
+		}
+		if visited[structType] {
+			continue
+		}
+		visited[structType] = true
+		params = append(params, queryParameters(nested, p.Fields, required && p.IsRequired && nested == name, visited)...)
+		delete(visited, structType)
+	}
+	return params
+}
+
+// queryParameter describes the value of type t passed in query as a field p
+func queryParameter(name string, p *gorpc.HandlerParameter, t reflect.Type, required bool) *Parameter {
+	paramType := queryTypeName(t)
+	param := &Parameter{
+		Name:        name,
+		Description: p.Description,
+		In:          "query",
+		Required:    required,
+		Schema:      Schema{Type: paramType, Format: typeFormat(t)},
+	}
+	if paramType == "array" && typeName(derefType(t).Elem()) == "file" {
+		// several files are uploaded with the same key, but Swagger has no arrays of files
+		param.Type = "file"
+	} else if paramType == "array" {
+		elem := derefType(t).Elem()
+		param.CollectionFormat = "multi"
+		param.Items = &Items{Schema{Type: queryTypeName(elem), Format: typeFormat(elem)}}
+	}
+	if t == p.RawType {
+		applyConstraints(&param.Schema, p.RawType, p.Constraints)
+		param.Default, _ = p.DefaultValue()
+	}
+	return param
+}
+
+// isQueryObject returns true for structures which fields are passed separately
+func isQueryObject(t reflect.Type) bool {
+	t = derefType(t)
+	return t.Kind() == reflect.Struct && typeName(t) == "object"
+}
+
+// isQueryContainer returns true for maps and slices which elements are passed separately
+func isQueryContainer(t reflect.Type) bool {
+	t = derefType(t)
+	return t.Kind() == reflect.Map || typeName(t) == "array"
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+var fileType = reflect.TypeOf(gorpc.File{})
+
+// typeFormat returns swagger format for types which are passed as strings
+func typeFormat(t reflect.Type) string {
+	_, format, _ := gorpc.ParameterFormat(t)
+	return format
+}
+
+// queryTypeName returns type name of the query parameter, values of any type are passed as strings in query
+func queryTypeName(t reflect.Type) string {
+	if name := typeName(t); name != "" {
+		return name
+	}
+	return "string"
+}
+
 func typeName(t reflect.Type) (name string) {
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
+	if t == fileType {
+		return "file"
+	}
+	if name, _, ok := gorpc.ParameterFormat(t); ok {
+		// the name is empty if the value can be anything
+		return name
+	}
 	switch t.Kind() {
 	case reflect.Array, reflect.Slice:
 		name = "array"
@@ -290,6 +476,7 @@ func getOrCreateSchema(definitions Definitions, t reflect.Type) *Schema {
 	}
 
 	result.Type = typeName(t)
+	result.Format = typeFormat(t)
 	if result.Type == "object" {
 		name := t.PkgPath() + "/" + t.String()
 		if _, ok := definitions[name]; ok {
@@ -313,11 +500,20 @@ func getOrCreateSchema(definitions Definitions, t reflect.Type) *Schema {
 					name = field.Name
 				}
 			}
-			if field.Type.Kind() != reflect.Ptr {
+			defaultValue, hasDefault := field.Tag.Lookup("default")
+			if field.Type.Kind() != reflect.Ptr && !hasDefault {
 				result.Required = append(result.Required, name)
 			}
 			fieldSchema := getOrCreateSchema(definitions, field.Type)
 			fieldSchema.Description = field.Tag.Get("description")
+			if constraints, err := gorpc.ParseParameterConstraints(field); err == nil {
+				applyConstraints(fieldSchema, field.Type, constraints)
+			}
+			if hasDefault {
+				if v, err := gorpc.ParseParameterDefault(field.Type, defaultValue); err == nil {
+					fieldSchema.Default = v.Interface()
+				}
+			}
 			result.Properties[name] = fieldSchema
 		}
 		definitions[name] = result
@@ -329,3 +525,65 @@ func getOrCreateSchema(definitions Definitions, t reflect.Type) *Schema {
 
 	return &result
 }
+
+func applyConstraints(schema *Schema, t reflect.Type, c gorpc.ParameterConstraints) {
+	if c.IsEmpty() {
+		return
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	schema.Minimum = c.Min
+	schema.Maximum = c.Max
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		schema.MinItems, schema.MaxItems = c.MinLength, c.MaxLength
+	case reflect.Map:
+		schema.MinProperties, schema.MaxProperties = c.MinLength, c.MaxLength
+	default:
+		schema.MinLength, schema.MaxLength = c.MinLength, c.MaxLength
+	}
+	if c.Pattern != nil {
+		schema.Pattern = c.Pattern.String()
+	}
+	for _, e := range c.Enum {
+		if t.Kind() == reflect.String {
+			schema.Enum = append(schema.Enum, e)
+		} else {
+			schema.Enum = append(schema.Enum, json.Number(e))
+		}
+	}
+}
+
+// addErrorResponses adds responses for business errors with HTTP statuses, their body is the usual envelope with
+// the code of the error
+func addErrorResponses(responses Responses, errors []gorpc.HandlerError) Responses {
+	for _, e := range errors {
+		if e.HTTPStatus == 0 {
+			continue
+		}
+		if responses == nil {
+			responses = Responses{}
+		}
+
+		status := strconv.Itoa(e.HTTPStatus)
+		if resp, ok := responses[status]; ok {
+			resp.Description += ", " + e.Code
+			continue
+		}
+		responses[status] = &Response{
+			Description: "Business error: " + e.Code,
+			Schema: &Schema{
+				Type:     "object",
+				Required: []string{"result", "data", "error"},
+				Properties: Properties{
+					"result": &Schema{Type: "string", Description: "ERROR"},
+					"data":   &Schema{Type: "string", Description: "Message of the error"},
+					"error":  &Schema{Type: "string", Description: "Code of the error"},
+				},
+			},
+		}
+	}
+	return responses
+}
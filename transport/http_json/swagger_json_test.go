@@ -1,15 +1,19 @@
 package http_json
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/sergei-svistunov/gorpc"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 
 	test_handler1 "github.com/sergei-svistunov/gorpc/test/handler1"
+	test_handler_types "github.com/sergei-svistunov/gorpc/test/handler_types"
+	test_handler_validation "github.com/sergei-svistunov/gorpc/test/handler_validation"
 )
 
 // Suite
@@ -44,3 +48,79 @@ func (s *SwaggerJSONSute) TestSwaggerJSON_Call_Success() {
 	s.NoError(err)
 	s.NotEmpty(body)
 }
+
+func TestSwaggerJSON_ParametersTags(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	if err := hm.RegisterHandler(test_handler_validation.NewHandler()); err != nil {
+		t.Fatal(err)
+	}
+
+	swagger, err := GenerateSwaggerJSON(hm, "", SwaggerJSONCallbacks{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := swagger.Paths["/test/handler_validation/v1/"]["get"].Parameters
+	if assert.Len(t, params, 3) {
+		assert.Equal(t, 1.0, *params[0].Minimum)
+		assert.Equal(t, 100.0, *params[0].Maximum)
+		assert.Equal(t, 3, *params[1].MinLength)
+		assert.Equal(t, 8, *params[1].MaxLength)
+		assert.Equal(t, "^[a-z0-9_]+$", params[1].Pattern)
+		assert.Equal(t, []interface{}{"active", "blocked"}, params[2].Enum)
+	}
+
+	request := swagger.Definitions["github.com/sergei-svistunov/gorpc/test/handler_validation/handler_validation.V2Request"].(Schema)
+	assert.Equal(t, 1, *request.Properties["items"].MinItems)
+	assert.Equal(t, 3, *request.Properties["items"].MaxItems)
+
+	item := swagger.Definitions["github.com/sergei-svistunov/gorpc/test/handler_validation/handler_validation.V2Item"].(Schema)
+	assert.Equal(t, 1.0, *item.Properties["id"].Minimum)
+	assert.Equal(t, []interface{}{json.Number("1"), json.Number("2"), json.Number("3")}, item.Properties["level"].Enum)
+
+	params = swagger.Paths["/test/handler_validation/v3/"]["get"].Parameters
+	if assert.Len(t, params, 4) {
+		assert.False(t, params[0].Required)
+		assert.Equal(t, 20, params[0].Default)
+		assert.Equal(t, "asc", params[2].Default)
+		assert.Equal(t, []uint64{1, 2}, params[3].Default)
+	}
+}
+
+func TestSwaggerJSON_OnPrepareHandlerJSON(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	if err := hm.RegisterHandler(test_handler_types.NewHandler()); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	swagger, err := GenerateSwaggerJSON(hm, "", SwaggerJSONCallbacks{
+		OnPrepareHandlerJSON: func(path string, data *Operation) {
+			calls++
+			data.Responses["401"] = &Response{Description: "Unauthorized"}
+			data.Parameters = append(data.Parameters, &Parameter{Name: "X-Token", In: "header"})
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the callback is called once for the operations of both methods
+	item := swagger.Paths["/test/handler_types/v1/"]
+	if !assert.NotNil(t, item["get"]) || !assert.NotNil(t, item["post"]) {
+		return
+	}
+	assert.Equal(t, 1, calls)
+	for _, operation := range item {
+		assert.Equal(t, "Unauthorized", operation.Responses["401"].Description)
+		assert.Equal(t, "X-Token", operation.Parameters[len(operation.Parameters)-1].Name)
+	}
+
+	// the operations don't share responses and parameters
+	item["get"].Responses["401"].Description = "Changed"
+	delete(item["get"].Responses, "200")
+	item["get"].Parameters[len(item["get"].Parameters)-1].Name = "Changed"
+	assert.Equal(t, "Unauthorized", item["post"].Responses["401"].Description)
+	assert.NotNil(t, item["post"].Responses["200"])
+	assert.Equal(t, "X-Token", item["post"].Parameters[len(item["post"].Parameters)-1].Name)
+}
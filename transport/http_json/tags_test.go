@@ -0,0 +1,67 @@
+package http_json
+
+import (
+	"context"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/sergei-svistunov/gorpc/transport/cache"
+)
+
+// tagsHandler returns the name of the user which is tagged by its id, it's declared here because handlers in the test
+// directory can't import the transport
+type tagsHandler struct {
+	names map[int]string
+}
+
+func (*tagsHandler) Caption() string {
+	return "Tags handler"
+}
+
+func (*tagsHandler) Description() string {
+	return "Handler which tags cached responses"
+}
+
+type tagsV1Args struct {
+	ID int `key:"id" description:"User ID"`
+}
+
+type tagsV1Res struct {
+	Name string `json:"name" description:"User name"`
+}
+
+func (h *tagsHandler) V1(ctx context.Context, opts *tagsV1Args) (*tagsV1Res, error) {
+	cache.EnableTransportCache(ctx)
+	cache.AddTags(ctx, "user:"+strconv.Itoa(opts.ID))
+	return &tagsV1Res{Name: h.names[opts.ID]}, nil
+}
+
+func TestCacheTags(t *testing.T) {
+	handler := &tagsHandler{names: map[int]string{1: "Alice", 2: "Bob"}}
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(handler)
+	memoryCache := cache.NewMemoryCache(cache.MemoryCacheConfig{})
+	defer memoryCache.Close()
+	apiHandler := NewAPIHandler(hm, memoryCache, APIHandlerCallbacks{})
+	server := httptest.NewServer(apiHandler)
+	defer server.Close()
+
+	name := func(id int) string {
+		_, body := getRaw(t, server.URL+"/transport/http_json/v1/?id="+strconv.Itoa(id), nil)
+		return body
+	}
+
+	assert.Equal(t, `{"result":"OK","data":{"name":"Alice"},"error":""}`, name(1))
+	assert.Equal(t, `{"result":"OK","data":{"name":"Bob"},"error":""}`, name(2))
+
+	handler.names = map[int]string{1: "Carol", 2: "Dave"}
+	assert.Equal(t, `{"result":"OK","data":{"name":"Alice"},"error":""}`, name(1))
+
+	assert.True(t, apiHandler.InvalidateTags("user:1"))
+	assert.Equal(t, `{"result":"OK","data":{"name":"Carol"},"error":""}`, name(1))
+	assert.Equal(t, `{"result":"OK","data":{"name":"Bob"},"error":""}`, name(2))
+}
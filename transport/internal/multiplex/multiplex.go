@@ -0,0 +1,219 @@
+// Package multiplex contains the calling of handlers shared by transports which carry many concurrent calls over one
+// connection, e.g. frame_json and ws_json. The transports only read and write frames.
+package multiplex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/sergei-svistunov/gorpc/transport/http_json"
+)
+
+// Error codes of the calls which failed outside of the handler, see also error codes of http_json
+const (
+	ErrorCanceled     = "CANCELED"
+	ErrorInvalidFrame = "INVALID_FRAME"
+)
+
+// Result is the result of the call, its fields are the same as fields of http_json.HttpSessionResponse
+type Result struct {
+	Result string
+	Data   interface{}
+	Error  string
+}
+
+// ErrorResult returns the result of the call which failed with the code
+func ErrorResult(code string, message string) Result {
+	return Result{
+		Result: "ERROR",
+		Data:   message,
+		Error:  code,
+	}
+}
+
+// WriteErrorResult returns the result which replaces the response that can't be encoded, so the client doesn't wait
+// for it forever, and the error to report
+func WriteErrorResult(err error) (Result, *gorpc.CallHandlerError) {
+	callErr := &gorpc.CallHandlerError{Type: gorpc.ErrorWriteResponse, Err: err}
+	code, data := callErr.CodeAndData(http_json.PrintDebug)
+	return Result{Result: "ERROR", Data: data, Error: code}, callErr
+}
+
+// Callbacks are called with the context of the call, the transports add their connection to them
+type Callbacks struct {
+	// OnRequest fills fields of the request's info which depend on the connection
+	OnRequest func(info *gorpc.IncomingRequest)
+	OnError   func(ctx context.Context, route string, err *gorpc.CallHandlerError)
+	OnPanic   func(ctx context.Context, r interface{}, trace []byte)
+	OnSuccess func(ctx context.Context, route string, result interface{}, startTime time.Time)
+}
+
+// Caller calls handlers with JSON parameters
+type Caller struct {
+	HM            *gorpc.HandlersManager
+	TransportName string
+	Timeout       time.Duration
+	Callbacks     Callbacks
+}
+
+// Call calls the handler of the route, params are the JSON object of its parameters
+func (c *Caller) Call(ctx context.Context, route string, params json.RawMessage) Result {
+	startTime := time.Now()
+
+	handler := c.HM.FindHandlerByRoute(route)
+	if handler == nil {
+		c.OnError(ctx, route, &gorpc.CallHandlerError{
+			Type: gorpc.ErrorNotFound,
+			Err:  errors.New("Handler with route " + route + " is not found"),
+		})
+		return ErrorResult(http_json.ErrorNotFound, http.StatusText(http.StatusNotFound))
+	}
+	if handler.StreamItem != nil {
+		return ErrorResult(http_json.ErrorStreamNotSupported, "Streamed responses aren't supported")
+	}
+
+	info := gorpc.NewIncomingRequest(c.TransportName, handler)
+	if c.Callbacks.OnRequest != nil {
+		c.Callbacks.OnRequest(info)
+	}
+	ctx = gorpc.NewRequestInfoContext(ctx, info)
+
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	if len(params) == 0 || string(params) == "null" {
+		params = json.RawMessage("{}")
+	}
+	paramsValue, err := c.HM.UnmarshalParameters(ctx, handler, &http_json.JsonParametersGetter{
+		Req: ioutil.NopCloser(bytes.NewReader(params)),
+	})
+	if err != nil {
+		callErr, ok := err.(*gorpc.CallHandlerError)
+		if !ok {
+			callErr = &gorpc.CallHandlerError{Type: gorpc.ErrorInParameters, Err: err}
+		}
+		return c.handleError(ctx, route, callErr)
+	}
+
+	res, callErr := c.HM.CallHandlerWithContext(ctx, handler, paramsValue, func(r interface{}, trace []byte) {
+		if c.Callbacks.OnPanic != nil {
+			c.Callbacks.OnPanic(ctx, r, trace)
+		}
+	})
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		c.OnError(ctx, route, &gorpc.CallHandlerError{
+			Type: gorpc.ErrorReturnedFromCall,
+			Err:  errors.New("Request timed out"),
+		})
+		return ErrorResult(http_json.ErrorTimeout, "Request timed out")
+	case context.Canceled:
+		return ErrorResult(ErrorCanceled, "Request canceled")
+	}
+	if callErr != nil {
+		return c.handleError(ctx, route, callErr)
+	}
+
+	if c.Callbacks.OnSuccess != nil {
+		c.Callbacks.OnSuccess(ctx, route, res, startTime)
+	}
+	return Result{
+		Result: "OK",
+		Data:   res,
+	}
+}
+
+// OnError reports the error of the call
+func (c *Caller) OnError(ctx context.Context, route string, err *gorpc.CallHandlerError) {
+	if c.Callbacks.OnError != nil {
+		c.Callbacks.OnError(ctx, route, err)
+	}
+}
+
+func (c *Caller) handleError(ctx context.Context, route string, err *gorpc.CallHandlerError) Result {
+	// panics are reported by OnPanic
+	if err.Type != gorpc.ErrorPanic {
+		c.OnError(ctx, route, err)
+	}
+
+	code, data := err.CodeAndData(http_json.PrintDebug)
+	return Result{Result: "ERROR", Data: data, Error: code}
+}
+
+// Dispatcher runs the calls of one connection concurrently, calls over the limit wait for a free slot
+type Dispatcher struct {
+	semaphore chan struct{}
+	wg        sync.WaitGroup
+
+	mu    sync.Mutex
+	calls map[string]context.CancelFunc
+}
+
+func NewDispatcher(maxConcurrency int) *Dispatcher {
+	return &Dispatcher{
+		semaphore: make(chan struct{}, maxConcurrency),
+		calls:     make(map[string]context.CancelFunc),
+	}
+}
+
+// Go runs the call with the id in a separate goroutine and passes its result to reply. The slot is taken in
+// the goroutine, so the connection goes on reading frames and the waiting call can be canceled. It returns false
+// if the call with the same id is in progress.
+func (d *Dispatcher) Go(ctx context.Context, id string, call func(ctx context.Context) Result,
+	reply func(ctx context.Context, res Result)) bool {
+	callCtx, cancelCall := context.WithCancel(ctx)
+	d.mu.Lock()
+	if _, exists := d.calls[id]; exists {
+		d.mu.Unlock()
+		cancelCall()
+		return false
+	}
+	d.calls[id] = cancelCall
+	d.mu.Unlock()
+
+	d.wg.Add(1)
+	go func() {
+		defer func() {
+			d.mu.Lock()
+			delete(d.calls, id)
+			d.mu.Unlock()
+			cancelCall()
+			d.wg.Done()
+		}()
+
+		select {
+		case d.semaphore <- struct{}{}:
+			defer func() { <-d.semaphore }()
+		case <-callCtx.Done():
+			reply(callCtx, ErrorResult(ErrorCanceled, "Request canceled"))
+			return
+		}
+
+		reply(callCtx, call(callCtx))
+	}()
+	return true
+}
+
+// Cancel cancels the call with the id if it's in progress
+func (d *Dispatcher) Cancel(id string) {
+	d.mu.Lock()
+	if cancelCall, ok := d.calls[id]; ok {
+		cancelCall()
+	}
+	d.mu.Unlock()
+}
+
+// Wait waits for the end of all calls
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
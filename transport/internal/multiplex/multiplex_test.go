@@ -0,0 +1,46 @@
+package multiplex
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDispatcher_DuplicateID(t *testing.T) {
+	d := NewDispatcher(1)
+	started := make(chan struct{})
+	results := make(chan Result, 1)
+
+	call := func(ctx context.Context) Result {
+		close(started)
+		<-ctx.Done()
+		return ErrorResult(ErrorCanceled, "Request canceled")
+	}
+	reply := func(ctx context.Context, res Result) {
+		results <- res
+	}
+
+	if !d.Go(context.Background(), "1", call, reply) {
+		t.Fatal("The first call with id 1 is rejected")
+	}
+	<-started
+
+	if d.Go(context.Background(), "1", call, reply) {
+		t.Fatal("The call with id 1 in progress is accepted twice")
+	}
+
+	d.Cancel("1")
+	d.Wait()
+
+	if res := <-results; res.Error != ErrorCanceled {
+		t.Fatalf("Invalid result of the canceled call: %+v", res)
+	}
+
+	// the id is free after the end of the call
+	if !d.Go(context.Background(), "1", func(ctx context.Context) Result { return Result{Result: "OK"} }, reply) {
+		t.Fatal("The call with id 1 is rejected after the end of the previous one")
+	}
+	d.Wait()
+	if res := <-results; res.Result != "OK" {
+		t.Fatalf("Invalid result: %+v", res)
+	}
+}
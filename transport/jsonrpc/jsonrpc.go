@@ -0,0 +1,340 @@
+// Package jsonrpc implements JSON-RPC 2.0 transport over HTTP for HandlersManager.
+// Method name is the route of the handler version, e.g. "/users/v1/" (leading and trailing slashes are optional),
+// params must be an object, they are read by http_json.JsonParametersGetter like "application/json" requests.
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/sergei-svistunov/gorpc/transport/http_json"
+)
+
+const Version = "2.0"
+
+// TransportName marks JSON-RPC calls, including items of batches
+const TransportName = "jsonrpc"
+
+// Standard error codes of JSON-RPC 2.0 and codes of server errors
+const (
+	ErrorCodeParse          = -32700
+	ErrorCodeInvalidRequest = -32600
+	ErrorCodeMethodNotFound = -32601
+	ErrorCodeInvalidParams  = -32602
+	ErrorCodeInternal       = -32603
+	// ErrorCodeHandler is returned for errors from V<N>ErrorsVar, data contains the code of HandlerError
+	ErrorCodeHandler = -32000
+	ErrorCodeTimeout = -32001
+)
+
+const (
+	defaultBatchConcurrency = 8
+	maxRequestSize          = int64(10 << 20)
+)
+
+// Request is a call of JSON-RPC method, a request without ID is a notification
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// Response contains either result or error of the call
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// Error is JSON-RPC error object
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+type Callbacks struct {
+	OnInitCtx func(ctx context.Context, req *http.Request) context.Context
+	OnError   func(ctx context.Context, req *http.Request, method string, err *gorpc.CallHandlerError)
+	OnPanic   func(ctx context.Context, r interface{}, trace []byte, req *http.Request)
+	OnSuccess func(ctx context.Context, req *http.Request, method string, result interface{}, startTime time.Time)
+}
+
+type Handler struct {
+	hm               *gorpc.HandlersManager
+	callbacks        Callbacks
+	timeout          time.Duration
+	batchConcurrency int
+}
+
+func NewHandler(hm *gorpc.HandlersManager, callbacks Callbacks) *Handler {
+	return &Handler{
+		hm:               hm,
+		callbacks:        callbacks,
+		batchConcurrency: defaultBatchConcurrency,
+	}
+}
+
+// SetTimeout sets the timeout of every call, calls of the batch have separate timeouts
+func (h *Handler) SetTimeout(timeout time.Duration) *Handler {
+	h.timeout = timeout
+	return h
+}
+
+// SetBatchConcurrency sets the maximum number of calls of one batch executed concurrently
+func (h *Handler) SetBatchConcurrency(concurrency int) *Handler {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	h.batchConcurrency = concurrency
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := req.Context()
+	if h.callbacks.OnInitCtx != nil {
+		ctx = h.callbacks.OnInitCtx(ctx, req)
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, maxRequestSize))
+	if err != nil {
+		writeResponse(w, errorResponse(nil, &Error{Code: ErrorCodeParse, Message: err.Error()}))
+		return
+	}
+	body = bytes.TrimSpace(body)
+
+	if len(body) == 0 || body[0] != '[' {
+		if resp := h.call(ctx, req, body); resp != nil {
+			writeResponse(w, resp)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		writeResponse(w, errorResponse(nil, &Error{Code: ErrorCodeParse, Message: err.Error()}))
+		return
+	}
+	if len(batch) == 0 {
+		writeResponse(w, errorResponse(nil, &Error{Code: ErrorCodeInvalidRequest, Message: "Empty batch"}))
+		return
+	}
+
+	responses := make([]*Response, len(batch))
+	semaphore := make(chan struct{}, h.batchConcurrency)
+	var wg sync.WaitGroup
+	for i := range batch {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			responses[i] = h.call(ctx, req, batch[i])
+		}(i)
+	}
+	wg.Wait()
+
+	// responses to notifications are not sent
+	res := make([]*Response, 0, len(responses))
+	for _, resp := range responses {
+		if resp != nil {
+			res = append(res, resp)
+		}
+	}
+	if len(res) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeResponse(w, res)
+}
+
+// call executes one request and returns nil for notifications
+func (h *Handler) call(ctx context.Context, req *http.Request, body []byte) *Response {
+	startTime := time.Now()
+
+	var request Request
+	if err := json.Unmarshal(body, &request); err != nil {
+		if _, ok := err.(*json.SyntaxError); ok {
+			return errorResponse(nil, &Error{Code: ErrorCodeParse, Message: err.Error()})
+		}
+		return errorResponse(nil, &Error{Code: ErrorCodeInvalidRequest, Message: err.Error()})
+	}
+	if request.JSONRPC != Version || request.Method == "" || !isValidID(request.ID) {
+		return errorResponse(nil, &Error{Code: ErrorCodeInvalidRequest, Message: "Invalid Request"})
+	}
+
+	result, rpcErr := h.callMethod(ctx, req, &request, startTime)
+	if request.ID == nil {
+		return nil
+	}
+	if rpcErr != nil {
+		return errorResponse(request.ID, rpcErr)
+	}
+	return &Response{
+		JSONRPC: Version,
+		Result:  result,
+		ID:      request.ID,
+	}
+}
+
+func (h *Handler) callMethod(ctx context.Context, req *http.Request, request *Request, startTime time.Time) (json.RawMessage, *Error) {
+	route := request.Method
+	if !strings.HasPrefix(route, "/") {
+		route = "/" + route
+	}
+	handler := h.hm.FindHandlerByRoute(route)
+	if handler == nil {
+		return nil, &Error{Code: ErrorCodeMethodNotFound, Message: "Method not found"}
+	}
+	if handler.StreamItem != nil {
+		return nil, &Error{Code: ErrorCodeHandler, Message: "Streamed responses aren't supported", Data: http_json.ErrorStreamNotSupported}
+	}
+
+	info := gorpc.NewIncomingRequest(TransportName, handler)
+	info.RemoteAddr = req.RemoteAddr
+	info.Header = req.Header
+	info.HTTPRequest = req
+	ctx = gorpc.NewRequestInfoContext(ctx, info)
+
+	params := request.Params
+	switch {
+	case len(params) == 0 || string(params) == "null":
+		params = json.RawMessage("{}")
+	case params[0] != '{':
+		return nil, &Error{Code: ErrorCodeInvalidParams, Message: "Params must be an object"}
+	}
+
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+
+	paramsValue, err := h.hm.UnmarshalParameters(ctx, handler, &http_json.JsonParametersGetter{
+		Req: ioutil.NopCloser(bytes.NewReader(params)),
+	})
+	if err != nil {
+		callErr, ok := err.(*gorpc.CallHandlerError)
+		if !ok {
+			callErr = &gorpc.CallHandlerError{Type: gorpc.ErrorInParameters, Err: err}
+		}
+		return nil, h.handleError(ctx, req, request.Method, callErr)
+	}
+
+	res, callErr := h.hm.CallHandlerWithContext(ctx, handler, paramsValue, func(r interface{}, trace []byte) {
+		if h.callbacks.OnPanic != nil {
+			h.callbacks.OnPanic(ctx, r, trace, req)
+		}
+	})
+	if ctx.Err() == context.DeadlineExceeded {
+		h.onError(ctx, req, request.Method, &gorpc.CallHandlerError{
+			Type: gorpc.ErrorReturnedFromCall,
+			Err:  errors.New("Request timed out"),
+		})
+		return nil, &Error{Code: ErrorCodeTimeout, Message: "Request timed out"}
+	}
+	if callErr != nil {
+		return nil, h.handleError(ctx, req, request.Method, callErr)
+	}
+
+	result, err := json.Marshal(res)
+	if err != nil {
+		return nil, h.handleError(ctx, req, request.Method, &gorpc.CallHandlerError{Type: gorpc.ErrorWriteResponse, Err: err})
+	}
+
+	if h.callbacks.OnSuccess != nil {
+		h.callbacks.OnSuccess(ctx, req, request.Method, res, startTime)
+	}
+	return result, nil
+}
+
+// handleError converts CallHandlerError into JSON-RPC error
+func (h *Handler) handleError(ctx context.Context, req *http.Request, method string, err *gorpc.CallHandlerError) *Error {
+	if err.Type != gorpc.ErrorPanic {
+		h.onError(ctx, req, method, err)
+	}
+
+	switch err.Type {
+	case gorpc.ErrorInParameters:
+		rpcErr := &Error{Code: ErrorCodeInvalidParams, Message: "Invalid params"}
+		if paramErrs := err.ParameterErrors(); paramErrs != nil {
+			rpcErr.Data = paramErrs
+		} else {
+			rpcErr.Data = err.Error()
+		}
+		return rpcErr
+	case gorpc.ErrorReturnedFromCall:
+		return &Error{Code: ErrorCodeHandler, Message: err.UserMessage(), Data: err.ErrorCode()}
+	case gorpc.ErrorNotFound:
+		return &Error{Code: ErrorCodeMethodNotFound, Message: "Method not found"}
+	default:
+		rpcErr := &Error{Code: ErrorCodeInternal, Message: "Internal error"}
+		if http_json.PrintDebug {
+			rpcErr.Data = err.Error()
+		}
+		return rpcErr
+	}
+}
+
+func (h *Handler) onError(ctx context.Context, req *http.Request, method string, err *gorpc.CallHandlerError) {
+	if h.callbacks.OnError != nil {
+		h.callbacks.OnError(ctx, req, method, err)
+	}
+}
+
+func errorResponse(id json.RawMessage, err *Error) *Response {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &Response{
+		JSONRPC: Version,
+		Error:   err,
+		ID:      id,
+	}
+}
+
+// isValidID checks that ID is a string, a number or null
+func isValidID(id json.RawMessage) bool {
+	if id == nil {
+		return true
+	}
+	var v interface{}
+	if err := json.Unmarshal(id, &v); err != nil {
+		return false
+	}
+	switch v.(type) {
+	case nil, string, float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func writeResponse(w http.ResponseWriter, resp interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,132 @@
+package jsonrpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/stretchr/testify/assert"
+
+	test_handler1 "github.com/sergei-svistunov/gorpc/test/handler1"
+	test_handler_behavior "github.com/sergei-svistunov/gorpc/test/handler_behavior"
+)
+
+func newTestHandler(t *testing.T) *Handler {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	if err := hm.RegisterHandlers(test_handler1.NewHandler(), test_handler_behavior.NewHandler()); err != nil {
+		t.Fatal(err)
+	}
+	return NewHandler(hm, Callbacks{}).SetTimeout(200 * time.Millisecond)
+}
+
+func call(handler http.Handler, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/rpc", strings.NewReader(body)))
+	return recorder
+}
+
+func TestJSONRPC_Call(t *testing.T) {
+	handler := newTestHandler(t)
+
+	for _, testCase := range []struct {
+		request  string
+		response string
+	}{
+		{
+			`{"jsonrpc": "2.0", "method": "/test/handler1/v1/", "params": {"req_int": 5}, "id": 1}`,
+			`{"jsonrpc": "2.0", "result": {"string": "Test", "int": 5}, "id": 1}`,
+		},
+		{
+			`{"jsonrpc": "2.0", "method": "test/handler1/v1", "params": {"req_int": 5}, "id": "a"}`,
+			`{"jsonrpc": "2.0", "result": {"string": "Test", "int": 5}, "id": "a"}`,
+		},
+		{
+			`{"jsonrpc": "2.0", "method": "/test/handler1/v2/", "params": {"req_int": 5, "error_id": 1}, "id": null}`,
+			`{"jsonrpc": "2.0", "error": {"code": -32000, "message": "Error 1 description", "data": "ERROR_TYPE1"}, "id": null}`,
+		},
+		{
+			`{"jsonrpc": "2.0", "method": "/test/handler1/v1/", "id": 2}`,
+			`{"jsonrpc": "2.0", "error": {"code": -32602, "message": "Invalid params", "data": [{"path": "req_int", "code": "REQUIRED", "message": "Missed required field 'req_int'"}]}, "id": 2}`,
+		},
+		{
+			`{"jsonrpc": "2.0", "method": "/test/handler1/v1/", "params": [5], "id": 3}`,
+			`{"jsonrpc": "2.0", "error": {"code": -32602, "message": "Params must be an object"}, "id": 3}`,
+		},
+		{
+			`{"jsonrpc": "2.0", "method": "/test/unknown/v1/", "id": 4}`,
+			`{"jsonrpc": "2.0", "error": {"code": -32601, "message": "Method not found"}, "id": 4}`,
+		},
+		{
+			`{"jsonrpc": "2.0", "method": "/test/handler_behavior/v1/", "params": {"value": 1, "panic": true}, "id": 5}`,
+			`{"jsonrpc": "2.0", "error": {"code": -32603, "message": "Internal error"}, "id": 5}`,
+		},
+		{
+			`{"jsonrpc": "2.0", "method": "/test/handler_behavior/v1/", "params": {"value": 1, "sleep": "1s"}, "id": 6}`,
+			`{"jsonrpc": "2.0", "error": {"code": -32001, "message": "Request timed out"}, "id": 6}`,
+		},
+		{
+			`{"jsonrpc": "1.0", "method": "/test/handler1/v1/", "id": 7}`,
+			`{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": null}`,
+		},
+		{
+			`{"jsonrpc": "2.0", "method": "/test/handler1/v1/", "id": {}}`,
+			`{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Invalid Request"}, "id": null}`,
+		},
+		{
+			`{"jsonrpc": "2.0", "method": 1, "id": 8}`,
+			`{"jsonrpc": "2.0", "error": {"code": -32600, "message": "json: cannot unmarshal number into Go struct field Request.method of type string"}, "id": null}`,
+		},
+		{
+			`{"jsonrpc": "2.0", "method"`,
+			`{"jsonrpc": "2.0", "error": {"code": -32700, "message": "unexpected end of JSON input"}, "id": null}`,
+		},
+	} {
+		recorder := call(handler, testCase.request)
+		assert.Equal(t, http.StatusOK, recorder.Code, testCase.request)
+		assert.JSONEq(t, testCase.response, recorder.Body.String(), testCase.request)
+	}
+}
+
+func TestJSONRPC_Batch(t *testing.T) {
+	handler := newTestHandler(t)
+
+	recorder := call(handler, `[
+		{"jsonrpc": "2.0", "method": "/test/handler1/v1/", "params": {"req_int": 1}, "id": 1},
+		{"jsonrpc": "2.0", "method": "/test/handler1/v1/", "params": {"req_int": 2}},
+		1,
+		{"jsonrpc": "2.0", "method": "/test/handler_behavior/v1/", "params": {"value": 3}, "id": 3}
+	]`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `[
+		{"jsonrpc": "2.0", "result": {"string": "Test", "int": 1}, "id": 1},
+		{"jsonrpc": "2.0", "error": {"code": -32600, "message": "json: cannot unmarshal number into Go value of type jsonrpc.Request"}, "id": null},
+		{"jsonrpc": "2.0", "result": {"value": 3}, "id": 3}
+	]`, recorder.Body.String())
+
+	recorder = call(handler, `[]`)
+	assert.JSONEq(t, `{"jsonrpc": "2.0", "error": {"code": -32600, "message": "Empty batch"}, "id": null}`, recorder.Body.String())
+
+	recorder = call(handler, `[{"jsonrpc": "2.0", "method": "/test/handler1/v1/", "params": {"req_int": 1}}]`)
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Empty(t, recorder.Body.String())
+}
+
+func TestJSONRPC_Notification(t *testing.T) {
+	var calls int
+	handler := newTestHandler(t)
+	handler.callbacks.OnSuccess = func(ctx context.Context, req *http.Request, method string, result interface{}, startTime time.Time) {
+		calls++
+	}
+
+	recorder := call(handler, `{"jsonrpc": "2.0", "method": "/test/handler1/v1/", "params": {"req_int": 1}}`)
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Equal(t, 1, calls)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/rpc", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
@@ -0,0 +1,272 @@
+// Package ws_json implements transport over WebSocket with JSON frames. One connection carries many concurrent
+// calls, every call has an id chosen by the client and the response has the same id:
+//
+//	-> {"id": 1, "route": "/users/v1/", "params": {"id": 10}}
+//	-> {"id": 2, "route": "/orders/v1/", "params": {"user_id": 10}}
+//	<- {"id": 2, "result": "OK", "data": [...], "error": ""}
+//	<- {"id": 1, "result": "OK", "data": {...}, "error": ""}
+//
+// A call can be canceled by the frame {"id": 1, "type": "cancel"}, all calls are canceled when the connection
+// is closed.
+package ws_json
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/sergei-svistunov/gorpc/transport/internal/multiplex"
+)
+
+// TransportName marks calls received over WebSocket connections
+const TransportName = "ws_json"
+
+// Types of the client's frames
+const (
+	FrameCall   = "call"
+	FrameCancel = "cancel"
+)
+
+// Error codes of the calls which failed outside of the handler, see also error codes of http_json
+const (
+	ErrorCanceled     = multiplex.ErrorCanceled
+	ErrorInvalidFrame = multiplex.ErrorInvalidFrame
+)
+
+const (
+	defaultMaxConcurrency = 16
+	maxFrameSize          = 10 << 20
+)
+
+// Request is the client's frame. Type is FrameCall if it's empty.
+type Request struct {
+	ID     json.RawMessage `json:"id"`
+	Type   string          `json:"type,omitempty"`
+	Route  string          `json:"route,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is the server's frame with the result of the call, it has the same fields as http_json.HttpSessionResponse
+type Response struct {
+	ID     json.RawMessage `json:"id"`
+	Result string          `json:"result"`
+	Data   interface{}     `json:"data"`
+	Error  string          `json:"error"`
+}
+
+type Callbacks struct {
+	// Handshake checks the request before the connection is established, e.g. its Origin. Only requests with
+	// the Origin of the same host are accepted if it's nil.
+	Handshake    func(config *websocket.Config, req *http.Request) error
+	OnInitCtx    func(ctx context.Context, req *http.Request) context.Context
+	OnConnect    func(ctx context.Context, req *http.Request)
+	OnDisconnect func(ctx context.Context, req *http.Request, startTime time.Time)
+	OnError      func(ctx context.Context, req *http.Request, route string, err *gorpc.CallHandlerError)
+	OnPanic      func(ctx context.Context, r interface{}, trace []byte, req *http.Request)
+	OnSuccess    func(ctx context.Context, req *http.Request, route string, result interface{}, startTime time.Time)
+}
+
+type Handler struct {
+	hm             *gorpc.HandlersManager
+	callbacks      Callbacks
+	timeout        time.Duration
+	maxConcurrency int
+}
+
+func NewHandler(hm *gorpc.HandlersManager, callbacks Callbacks) *Handler {
+	return &Handler{
+		hm:             hm,
+		callbacks:      callbacks,
+		maxConcurrency: defaultMaxConcurrency,
+	}
+}
+
+// SetTimeout sets the timeout of every call
+func (h *Handler) SetTimeout(timeout time.Duration) *Handler {
+	h.timeout = timeout
+	return h
+}
+
+// SetMaxConcurrency sets the maximum number of concurrent calls of one connection. Calls over the limit wait for
+// a free slot and can be canceled while waiting.
+func (h *Handler) SetMaxConcurrency(concurrency int) *Handler {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	h.maxConcurrency = concurrency
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	handshake := h.callbacks.Handshake
+	if handshake == nil {
+		handshake = checkSameOrigin
+	}
+	server := websocket.Server{
+		Handshake: handshake,
+		Handler: func(conn *websocket.Conn) {
+			h.serveConn(conn, req)
+		},
+	}
+	server.ServeHTTP(w, req)
+}
+
+// connection contains the state of one client's connection
+type connection struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+}
+
+// send writes the response. The response which can't be encoded, e.g. it has NaN value, is replaced with the internal
+// error, and its error is returned. The connection is closed on errors of writing.
+func (c *connection) send(id json.RawMessage, res multiplex.Result) error {
+	data, err := json.Marshal(&Response{ID: id, Result: res.Result, Data: res.Data, Error: res.Error})
+	if err != nil {
+		errRes, callErr := multiplex.WriteErrorResult(err)
+		if data, err = json.Marshal(&Response{ID: id, Result: errRes.Result, Data: errRes.Data, Error: errRes.Error}); err != nil {
+			c.conn.Close()
+			return err
+		}
+		err = callErr
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if writeErr := websocket.Message.Send(c.conn, string(data)); writeErr != nil {
+		c.conn.Close()
+		return writeErr
+	}
+	return err
+}
+
+// checkSameOrigin accepts requests from the pages of the same host, so other sites can't call handlers with
+// the cookies of the user
+func checkSameOrigin(config *websocket.Config, req *http.Request) error {
+	origin, err := websocket.Origin(config, req)
+	if err != nil {
+		return err
+	}
+	if origin == nil {
+		return errors.New("null origin")
+	}
+	if !strings.EqualFold(origin.Host, req.Host) {
+		return fmt.Errorf("origin %s doesn't match host %s", origin, req.Host)
+	}
+	config.Origin = origin
+	return nil
+}
+
+func (h *Handler) serveConn(conn *websocket.Conn, req *http.Request) {
+	startTime := time.Now()
+	conn.MaxPayloadBytes = maxFrameSize
+
+	// the request's context isn't canceled when hijacked connection is closed, so it's done explicitly
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+	if h.callbacks.OnInitCtx != nil {
+		ctx = h.callbacks.OnInitCtx(ctx, req)
+	}
+
+	if h.callbacks.OnConnect != nil {
+		h.callbacks.OnConnect(ctx, req)
+	}
+	defer func() {
+		if h.callbacks.OnDisconnect != nil {
+			h.callbacks.OnDisconnect(ctx, req, startTime)
+		}
+	}()
+
+	c := &connection{conn: conn}
+	caller := h.newCaller(req)
+	dispatcher := multiplex.NewDispatcher(h.maxConcurrency)
+
+	for {
+		var data []byte
+		if err := websocket.Message.Receive(conn, &data); err != nil {
+			break
+		}
+
+		var request Request
+		if err := json.Unmarshal(data, &request); err != nil || len(request.ID) == 0 {
+			message := "Frame must have id"
+			if err != nil {
+				message = err.Error()
+			}
+			c.send(request.ID, multiplex.ErrorResult(ErrorInvalidFrame, message))
+			continue
+		}
+		id := string(request.ID)
+
+		switch request.Type {
+		case FrameCancel:
+			dispatcher.Cancel(id)
+			continue
+		case "", FrameCall:
+		default:
+			c.send(request.ID, multiplex.ErrorResult(ErrorInvalidFrame, "Unknown frame type "+request.Type))
+			continue
+		}
+
+		call := func(ctx context.Context) multiplex.Result {
+			return caller.Call(ctx, request.Route, request.Params)
+		}
+		reply := func(ctx context.Context, res multiplex.Result) {
+			if err := c.send(request.ID, res); err != nil {
+				callErr, ok := err.(*gorpc.CallHandlerError)
+				if !ok {
+					callErr = &gorpc.CallHandlerError{Type: gorpc.ErrorWriteResponse, Err: err}
+				}
+				caller.OnError(ctx, request.Route, callErr)
+			}
+		}
+		if !dispatcher.Go(ctx, id, call, reply) {
+			c.send(request.ID, multiplex.ErrorResult(ErrorInvalidFrame, "Call with id "+id+" is in progress"))
+		}
+	}
+
+	// the client has gone, so nobody waits for results
+	cancel()
+	dispatcher.Wait()
+}
+
+// newCaller returns the caller of handlers which passes the handshake's request to the callbacks
+func (h *Handler) newCaller(req *http.Request) *multiplex.Caller {
+	callbacks := multiplex.Callbacks{
+		// the request is the handshake of the connection
+		OnRequest: func(info *gorpc.IncomingRequest) {
+			info.RemoteAddr = req.RemoteAddr
+			info.Header = req.Header
+			info.HTTPRequest = req
+		},
+	}
+	if h.callbacks.OnError != nil {
+		callbacks.OnError = func(ctx context.Context, route string, err *gorpc.CallHandlerError) {
+			h.callbacks.OnError(ctx, req, route, err)
+		}
+	}
+	if h.callbacks.OnPanic != nil {
+		callbacks.OnPanic = func(ctx context.Context, r interface{}, trace []byte) {
+			h.callbacks.OnPanic(ctx, r, trace, req)
+		}
+	}
+	if h.callbacks.OnSuccess != nil {
+		callbacks.OnSuccess = func(ctx context.Context, route string, result interface{}, startTime time.Time) {
+			h.callbacks.OnSuccess(ctx, req, route, result, startTime)
+		}
+	}
+
+	return &multiplex.Caller{
+		HM:            h.hm,
+		TransportName: TransportName,
+		Timeout:       h.timeout,
+		Callbacks:     callbacks,
+	}
+}
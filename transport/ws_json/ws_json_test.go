@@ -0,0 +1,230 @@
+package ws_json
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/sergei-svistunov/gorpc"
+	"github.com/sergei-svistunov/gorpc/transport/http_json"
+	"github.com/stretchr/testify/assert"
+
+	test_handler1 "github.com/sergei-svistunov/gorpc/test/handler1"
+	test_handler_behavior "github.com/sergei-svistunov/gorpc/test/handler_behavior"
+)
+
+func newTestServer(t *testing.T, interceptors ...gorpc.Interceptor) (*httptest.Server, *websocket.Conn) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.Use(interceptors...)
+	if err := hm.RegisterHandlers(test_handler1.NewHandler(), test_handler_behavior.NewHandler()); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(NewHandler(hm, Callbacks{}).SetTimeout(500 * time.Millisecond))
+
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http"), "", server.URL)
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	return server, conn
+}
+
+func send(t *testing.T, conn *websocket.Conn, frame string) {
+	if err := websocket.Message.Send(conn, frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func receive(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var data []byte
+	if err := websocket.Message.Receive(conn, &data); err != nil {
+		t.Fatal(err)
+	}
+	var resp map[string]interface{}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestWSJSON_MultiplexedCalls(t *testing.T) {
+	server, conn := newTestServer(t)
+	defer server.Close()
+	defer conn.Close()
+
+	send(t, conn, `{"id": 1, "route": "/test/handler_behavior/v1/", "params": {"value": 1, "sleep": "100ms"}}`)
+	send(t, conn, `{"id": "b", "route": "/test/handler1/v1/", "params": {"req_int": 2}}`)
+	send(t, conn, `{"id": 3, "route": "/test/handler1/v2/", "params": {"req_int": 2, "error_id": 1}}`)
+	send(t, conn, `{"id": 4, "route": "/test/handler1/v1/"}`)
+	send(t, conn, `{"id": 5, "route": "/test/unknown/v1/"}`)
+	send(t, conn, `{"route": "/test/handler1/v1/"}`)
+
+	responses := map[string]map[string]interface{}{}
+	var order []string
+	for i := 0; i < 6; i++ {
+		resp := receive(t, conn)
+		id, _ := json.Marshal(resp["id"])
+		responses[string(id)] = resp
+		order = append(order, string(id))
+	}
+
+	assert.Equal(t, "1", order[len(order)-1], "slow call must be the last")
+	assert.Equal(t, map[string]interface{}{"value": float64(1)}, responses["1"]["data"])
+	assert.Equal(t, map[string]interface{}{"string": "Test", "int": float64(2)}, responses[`"b"`]["data"])
+	assert.Equal(t, "ERROR_TYPE1", responses["3"]["error"])
+	assert.Equal(t, "INVALID_PARAMETERS", responses["4"]["error"])
+	assert.Equal(t, "NOT_FOUND", responses["5"]["error"])
+	assert.Equal(t, ErrorInvalidFrame, responses["null"]["error"])
+}
+
+func TestWSJSON_Cancel(t *testing.T) {
+	server, conn := newTestServer(t)
+	defer server.Close()
+	defer conn.Close()
+
+	send(t, conn, `{"id": 1, "route": "/test/handler_behavior/v1/", "params": {"value": 1, "sleep": "10s"}}`)
+	send(t, conn, `{"id": 2, "route": "/test/handler_behavior/v1/", "params": {"value": 1, "sleep": "10s"}}`)
+	send(t, conn, `{"id": 1, "type": "cancel"}`)
+
+	resp := receive(t, conn)
+	assert.Equal(t, float64(1), resp["id"])
+	assert.Equal(t, ErrorCanceled, resp["error"])
+
+	resp = receive(t, conn)
+	assert.Equal(t, float64(2), resp["id"])
+	assert.Equal(t, "TIMEOUT", resp["error"])
+}
+
+func TestWSJSON_Disconnect(t *testing.T) {
+	started := make(chan struct{})
+	canceled := make(chan error, 1)
+	server, conn := newTestServer(t, func(ctx context.Context, handler gorpc.HandlerVersion, params reflect.Value, next gorpc.InterceptorNext) (interface{}, *gorpc.CallHandlerError) {
+		close(started)
+		<-ctx.Done()
+		canceled <- ctx.Err()
+		return next(ctx, params)
+	})
+	defer server.Close()
+
+	send(t, conn, `{"id": 1, "route": "/test/handler1/v1/", "params": {"req_int": 1}}`)
+	<-started
+	conn.Close()
+
+	select {
+	case err := <-canceled:
+		assert.Equal(t, context.Canceled, err)
+	case <-time.After(time.Second):
+		t.Fatal("Context of the call wasn't canceled after disconnect")
+	}
+}
+
+func TestWSJSON_Origin(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(test_handler1.NewHandler())
+	url := func(server *httptest.Server) string {
+		return "ws" + strings.TrimPrefix(server.URL, "http")
+	}
+
+	// pages of other sites are rejected by default
+	server := httptest.NewServer(NewHandler(hm, Callbacks{}))
+	defer server.Close()
+	_, err := websocket.Dial(url(server), "", "http://example.com")
+	assert.Error(t, err)
+
+	// Handshake replaces the default check
+	server = httptest.NewServer(NewHandler(hm, Callbacks{
+		Handshake: func(config *websocket.Config, req *http.Request) error {
+			return nil
+		},
+	}))
+	defer server.Close()
+	conn, err := websocket.Dial(url(server), "", "http://example.com")
+	if assert.NoError(t, err) {
+		conn.Close()
+	}
+}
+
+func TestWSJSON_CancelWaitingCall(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(test_handler_behavior.NewHandler())
+	server := httptest.NewServer(NewHandler(hm, Callbacks{}).SetTimeout(500 * time.Millisecond).SetMaxConcurrency(1))
+	defer server.Close()
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http"), "", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// the second call waits for the slot of the first one, but its cancel frame is read
+	send(t, conn, `{"id": 1, "route": "/test/handler_behavior/v1/", "params": {"value": 1, "sleep": "10s"}}`)
+	send(t, conn, `{"id": 2, "route": "/test/handler_behavior/v1/", "params": {"value": 2}}`)
+	send(t, conn, `{"id": 2, "type": "cancel"}`)
+
+	resp := receive(t, conn)
+	assert.Equal(t, float64(2), resp["id"])
+	assert.Equal(t, ErrorCanceled, resp["error"])
+
+	resp = receive(t, conn)
+	assert.Equal(t, float64(1), resp["id"])
+	assert.Equal(t, "TIMEOUT", resp["error"])
+}
+
+// nanHandler returns the response which can't be encoded into JSON, it's declared here because handlers in the test
+// directory can't import the transport
+type nanHandler struct{}
+
+func (*nanHandler) Caption() string {
+	return "NaN handler"
+}
+
+func (*nanHandler) Description() string {
+	return "Handler which returns NaN"
+}
+
+type nanV1Args struct{}
+
+type nanV1Res struct {
+	Value float64 `json:"value" description:"NaN"`
+}
+
+func (*nanHandler) V1(ctx context.Context, opts *nanV1Args) (*nanV1Res, error) {
+	return &nanV1Res{Value: math.NaN()}, nil
+}
+
+func TestWSJSON_UnencodableResponse(t *testing.T) {
+	hm := gorpc.NewHandlersManager("github.com/sergei-svistunov/gorpc", gorpc.HandlersManagerCallbacks{})
+	hm.MustRegisterHandler(&nanHandler{})
+	errs := make(chan *gorpc.CallHandlerError, 1)
+	server := httptest.NewServer(NewHandler(hm, Callbacks{
+		OnError: func(ctx context.Context, req *http.Request, route string, err *gorpc.CallHandlerError) {
+			errs <- err
+		},
+	}))
+	defer server.Close()
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http"), "", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// the client gets the error instead of the response
+	send(t, conn, `{"id": 1, "route": "/transport/ws_json/v1/"}`)
+	resp := receive(t, conn)
+	assert.Equal(t, float64(1), resp["id"])
+	assert.Equal(t, http_json.ErrorInternal, resp["error"])
+	select {
+	case err := <-errs:
+		assert.Equal(t, gorpc.ErrorWriteResponse, err.Type)
+	case <-time.After(time.Second):
+		t.Fatal("OnError wasn't called")
+	}
+}